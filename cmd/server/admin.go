@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/pprof"
+
+	"go-grafana/internal/config"
+	"go-grafana/internal/domain/models"
+	"go-grafana/internal/handler"
+	"go-grafana/internal/middleware"
+	"go-grafana/internal/problem"
+	"go-grafana/internal/requestctx"
+	"go-grafana/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// adminServer is the HTTP server of the admin listener, kept apart from the public server so that
+// its port is never exposed through the public service
+type adminServer struct {
+	*http.Server
+}
+
+// newAdminServer creates the admin listener, which requires an API key with the server:admin scope on every route.
+// It has no write timeout so that CPU profiles and traces can run for as long as requested.
+func newAdminServer(
+	cfg *config.Config,
+	requestIDMiddleware middleware.RequestIDMiddleware,
+	loggingMiddleware middleware.LoggingMiddleware,
+	rateLimitMiddleware *middleware.RateLimitMiddleware,
+	adminHandler *handler.AdminHandler,
+	apiKeyService service.APIKeyService,
+	usageTracker service.APIKeyUsageTracker,
+	logger *zap.Logger,
+) (*adminServer, error) {
+	engine := gin.New()
+	if err := engine.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	engine.Use(requestIDMiddleware.Handle())
+	engine.Use(loggingMiddleware.Handle())
+	engine.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		requestctx.Logger(c.Request.Context(), logger).Error("Recovered from panic", zap.Any("panic", recovered), zap.String("path", c.Request.URL.Path))
+		problem.Respond(c, problem.New(problem.Internal, "An unexpected error occurred"))
+	}))
+	engine.NoRoute(func(c *gin.Context) {
+		problem.Respond(c, problem.New(problem.NotFound, "No route matches "+c.Request.URL.Path))
+	})
+
+	admin := engine.Group("/admin",
+		rateLimitMiddleware.ThrottleFailedAuth(),
+		middleware.APIKeyAuthMiddleware(apiKeyService, usageTracker, logger),
+		middleware.RequireScopes(logger, models.ScopeServerAdmin),
+	)
+	{
+		admin.GET("/loglevel", adminHandler.GetLogLevel)
+		admin.PUT("/loglevel", adminHandler.SetLogLevel)
+		admin.GET("/config", adminHandler.GetConfig)
+
+		// Profiles; the index links to them relatively, so named profiles are served from the same group
+		profiles := admin.Group("/debug/pprof")
+		profiles.GET("/", gin.WrapF(pprof.Index))
+		profiles.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		profiles.GET("/profile", gin.WrapF(pprof.Profile))
+		profiles.GET("/symbol", gin.WrapF(pprof.Symbol))
+		profiles.POST("/symbol", gin.WrapF(pprof.Symbol))
+		profiles.GET("/trace", gin.WrapF(pprof.Trace))
+		profiles.GET("/:profile", adminHandler.Profile)
+	}
+
+	return &adminServer{
+		Server: &http.Server{
+			Addr:              net.JoinHostPort(cfg.Admin.Address, cfg.Admin.Port),
+			Handler:           engine,
+			ReadTimeout:       cfg.Server.ReadTimeout,
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+			IdleTimeout:       cfg.Server.IdleTimeout,
+			MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+		},
+	}, nil
+}
+
+// startAdminServer serves the admin listener for the lifetime of the application, unless it is disabled.
+// It is not drained: it only serves operators, and stops once in-flight requests complete.
+func startAdminServer(lifecycle fx.Lifecycle, shutdowner fx.Shutdowner, server *adminServer, cfg *config.Config, logger *zap.Logger) {
+	if !cfg.Admin.Enabled {
+		logger.Info("Admin listener disabled")
+		return
+	}
+
+	lifecycle.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			listener, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return fmt.Errorf("failed to listen on %s: %w", server.Addr, err)
+			}
+
+			logger.Info("Starting admin server", zap.String("addr", server.Addr))
+			go func() {
+				if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					logger.Error("Admin server failed", zap.Error(err))
+					_ = shutdowner.Shutdown(fx.ExitCode(1))
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if err := server.Shutdown(ctx); err != nil {
+				_ = server.Close()
+				return err
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// usage describes the administrative subcommands
+const usage = `Usage: server [flags]
+       server command [arguments]
+
+Without a command the HTTP server is started. The configuration is layered as defaults < config file
+(-config or $CONFIG_FILE, YAML or TOML) < environment < -set flags. The config, keys and migrate
+commands accept the same -config and -set flags, e.g. server keys list -config config.yaml.
+
+Commands:
+  config print  Print the effective configuration as YAML, secrets masked unless -redacted=false
+
+  keys create   Create a new API key and print its secret
+  keys list     List API keys
+  keys revoke   Deactivate an API key
+  keys rotate   Replace the secret of an API key and print it
+
+  migrate up      Apply all pending database migrations
+  migrate down    Roll back the latest migration (-steps n for more)
+  migrate status  List migrations and when they were applied
+  migrate create  Create empty up and down files for a new migration
+`
+
+// runCommand dispatches an administrative subcommand and returns the process exit code
+func runCommand(args []string) int {
+	var err error
+	switch args[0] {
+	case "keys":
+		err = runKeysCommand(args[1:])
+	case "migrate":
+		err = runMigrateCommand(args[1:])
+	case "config":
+		err = runConfigCommand(args[1:])
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return 0
+	default:
+		err = fmt.Errorf("unknown command %q", args[0])
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n\n%s", err, usage)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"go-grafana/internal/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runConfigCommand inspects the effective configuration
+func runConfigCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("config: missing subcommand")
+	}
+
+	switch args[0] {
+	case "print":
+		return printConfig(args[1:])
+	default:
+		return fmt.Errorf("config: unknown subcommand %q", args[0])
+	}
+}
+
+// printConfig handles "config print". The output is YAML that can be used as a config file.
+func printConfig(args []string) error {
+	var sources config.Sources
+	flags := flag.NewFlagSet("config print", flag.ContinueOnError)
+	redacted := flags.Bool("redacted", true, "mask secrets such as the database password; -redacted=false prints them")
+	sources.RegisterFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(sources)
+	if err != nil {
+		return err
+	}
+	if *redacted {
+		cfg = cfg.Redacted()
+	}
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-grafana/internal/config"
+	"go-grafana/internal/health"
+	"go-grafana/pkg/database"
+	"go-grafana/pkg/database/migrations"
+
+	sentrysdk "github.com/getsentry/sentry-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// newHealthRegistry creates the health check registry with the dependency checks of the server
+func newHealthRegistry(reg prometheus.Registerer, db *gorm.DB, cfg *config.Config, logger *zap.Logger) (*health.Registry, error) {
+	registry := health.NewRegistry(reg, cfg.Health.CheckTimeout)
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get underlying SQL database: %w", err)
+	}
+	registry.AddReadinessCheck(health.Check{
+		Name: "database",
+		Run:  sqlDB.PingContext,
+	})
+
+	// SQLite and auto migrated databases do not record versioned migrations
+	if cfg.Database.Driver == config.DriverPostgres && !cfg.Database.AutoMigrate {
+		migrator, err := database.NewMigrator(sqlDB, migrations.FS, logger)
+		if err != nil {
+			return nil, err
+		}
+		registry.AddReadinessCheck(health.Check{
+			Name: "migrations",
+			Run: func(ctx context.Context) error {
+				pending, err := migrator.Pending(ctx)
+				if err != nil {
+					return err
+				}
+				if len(pending) > 0 {
+					return fmt.Errorf("%d pending migrations, first %d_%s", len(pending), pending[0].Version, pending[0].Name)
+				}
+				return nil
+			},
+		})
+	}
+
+	// Error reporting is not needed to serve traffic
+	if cfg.Sentry.DSN != "" {
+		registry.AddReadinessCheck(health.Check{
+			Name:     "sentry",
+			Optional: true,
+			Run: func(context.Context) error {
+				if sentrysdk.CurrentHub().Client() == nil {
+					return errors.New("sentry client is not initialized")
+				}
+				return nil
+			},
+		})
+	}
+
+	return registry, nil
+}
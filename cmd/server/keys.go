@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go-grafana/internal/config"
+	"go-grafana/internal/domain/models"
+	"go-grafana/internal/service"
+
+	"go.uber.org/fx"
+)
+
+// runKeysCommand manages API keys directly against the database, bypassing the HTTP API.
+// This is how the first key is created on a fresh database.
+func runKeysCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("keys: missing subcommand")
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "create":
+		return createKey(ctx, args[1:])
+	case "list":
+		return listKeys(ctx, args[1:])
+	case "revoke":
+		return revokeKey(ctx, args[1:])
+	case "rotate":
+		return rotateKey(ctx, args[1:])
+	default:
+		return fmt.Errorf("keys: unknown subcommand %q", args[0])
+	}
+}
+
+// newKeysFlagSet creates the flag set of a keys subcommand, which accepts the -config and -set flags of the server
+func newKeysFlagSet(name string, sources *config.Sources) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	sources.RegisterFlags(flags)
+	return flags
+}
+
+// newAPIKeyService loads the configuration and builds the API key service on top of the database
+func newAPIKeyService(sources config.Sources) (service.APIKeyService, error) {
+	cfg, err := loadConfig(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiKeyService service.APIKeyService
+	app := fx.New(
+		fx.Supply(cfg),
+		coreModule,
+		fx.NopLogger,
+		fx.Populate(&apiKeyService),
+	)
+	if err := app.Err(); err != nil {
+		return nil, err
+	}
+	return apiKeyService, nil
+}
+
+// createKey handles "keys create"
+func createKey(ctx context.Context, args []string) error {
+	var sources config.Sources
+	flags := newKeysFlagSet("keys create", &sources)
+	name := flags.String("name", "", "name of the API key (required)")
+	description := flags.String("description", "", "description of the API key")
+	scopes := flags.String("scopes", "", "comma separated scopes, e.g. users:write,api-keys:admin")
+	expiresIn := flags.Duration("expires-in", 0, "lifetime of the key, e.g. 720h (default: never expires)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	apiKeyService, err := newAPIKeyService(sources)
+	if err != nil {
+		return err
+	}
+
+	req := &models.CreateAPIKeyRequest{
+		Name:        *name,
+		Description: *description,
+		Scopes:      splitScopes(*scopes),
+	}
+	if *expiresIn > 0 {
+		expiresAt := time.Now().Add(*expiresIn).UTC()
+		req.ExpiresAt = &expiresAt
+	}
+
+	apiKey, err := apiKeyService.CreateAPIKey(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created API key %d (%s)\n", apiKey.ID, apiKey.Name)
+	fmt.Printf("Key: %s\n", apiKey.Key)
+	fmt.Println("Store the key now, it cannot be retrieved again.")
+	return nil
+}
+
+// listKeys handles "keys list"
+func listKeys(ctx context.Context, args []string) error {
+	var sources config.Sources
+	if err := newKeysFlagSet("keys list", &sources).Parse(args); err != nil {
+		return err
+	}
+
+	apiKeyService, err := newAPIKeyService(sources)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tNAME\tSCOPES\tACTIVE\tEXPIRES AT\tLAST USED AT\tUSES\tCREATED AT")
+
+	req := &models.ListAPIKeysRequest{Limit: models.MaxPageSize}
+	for {
+		page, err := apiKeyService.ListAPIKeys(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		for _, apiKey := range page.Data {
+			expiresAt := "never"
+			if apiKey.ExpiresAt != nil {
+				expiresAt = apiKey.ExpiresAt.Format(time.RFC3339)
+			}
+			lastUsedAt := "never"
+			if apiKey.LastUsedAt != nil {
+				lastUsedAt = apiKey.LastUsedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(writer, "%d\t%s\t%s\t%t\t%s\t%s\t%d\t%s\n",
+				apiKey.ID, apiKey.Name, strings.Join(apiKey.Scopes, ","), apiKey.Active,
+				expiresAt, lastUsedAt, apiKey.UsageCount, apiKey.CreatedAt.Format(time.RFC3339))
+		}
+
+		if page.Pagination.NextCursor == "" {
+			break
+		}
+		req.Cursor = page.Pagination.NextCursor
+	}
+
+	return writer.Flush()
+}
+
+// revokeKey handles "keys revoke <id>"
+func revokeKey(ctx context.Context, args []string) error {
+	var sources config.Sources
+	flags := newKeysFlagSet("keys revoke", &sources)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	id, err := parseKeyID(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	apiKeyService, err := newAPIKeyService(sources)
+	if err != nil {
+		return err
+	}
+
+	apiKey, err := apiKeyService.RevokeAPIKey(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Revoked API key %d (%s)\n", apiKey.ID, apiKey.Name)
+	return nil
+}
+
+// rotateKey handles "keys rotate [-grace duration] <id>"
+func rotateKey(ctx context.Context, args []string) error {
+	var sources config.Sources
+	flags := newKeysFlagSet("keys rotate", &sources)
+	grace := flags.Duration("grace", 0, "how long the old key keeps working, 0 for a hard cutover (default: API_KEY_ROTATION_GRACE_PERIOD)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *grace < 0 {
+		return errors.New("-grace must not be negative")
+	}
+
+	id, err := parseKeyID(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	apiKeyService, err := newAPIKeyService(sources)
+	if err != nil {
+		return err
+	}
+
+	req := &models.RotateAPIKeyRequest{}
+	// An explicit -grace 0 ends the previous key now instead of falling back to the configured default
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name != "grace" {
+			return
+		}
+		if *grace == 0 {
+			req.HardCutover = true
+			return
+		}
+		graceUntil := time.Now().Add(*grace).UTC()
+		req.GraceUntil = &graceUntil
+	})
+
+	apiKey, err := apiKeyService.RotateAPIKey(ctx, id, req)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Rotated API key %d (%s)\n", apiKey.ID, apiKey.Name)
+	fmt.Printf("Key: %s\n", apiKey.Key)
+	if apiKey.GraceUntil != nil {
+		fmt.Printf("The previous key keeps working until %s.\n", apiKey.GraceUntil.Format(time.RFC3339))
+	}
+	fmt.Println("Store the key now, it cannot be retrieved again.")
+	return nil
+}
+
+// parseKeyID parses the single API key ID argument
+func parseKeyID(args []string) (uint, error) {
+	if len(args) != 1 {
+		return 0, errors.New("expected exactly one API key ID")
+	}
+
+	id, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("invalid API key ID %q", args[0])
+	}
+	return uint(id), nil
+}
+
+// splitScopes splits a comma separated scope list
+func splitScopes(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
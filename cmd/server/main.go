@@ -2,28 +2,41 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+	"strings"
 	"time"
 
 	"go-grafana/docs"
 	"go-grafana/internal/config"
+	"go-grafana/internal/domain/models"
 	"go-grafana/internal/domain/repository"
 	"go-grafana/internal/handler"
+	"go-grafana/internal/health"
 	"go-grafana/internal/middleware"
+	"go-grafana/internal/problem"
+	"go-grafana/internal/redact"
+	"go-grafana/internal/reload"
+	"go-grafana/internal/requestctx"
 	"go-grafana/internal/service"
+	"go-grafana/internal/util"
 	"go-grafana/pkg/database"
 	"go-grafana/pkg/metrics"
 	"go-grafana/pkg/sentry"
+	"go-grafana/pkg/tracing"
 
 	sentrygin "github.com/getsentry/sentry-go/gin"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/fx"
 	"go.uber.org/fx/fxevent"
 	"go.uber.org/zap"
@@ -52,6 +65,25 @@ import (
 // @in header
 // @name X-API-Key
 func main() {
+	// Dispatch administrative subcommands such as "keys"; flags configure the server itself
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
+	var sources config.Sources
+	flags := flag.NewFlagSet("server", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	sources.RegisterFlags(flags)
+	_ = flags.Parse(os.Args[1:])
+
+	cfg, err := loadConfig(sources)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Initialize Swagger info
 	docs.SwaggerInfo.Title = "Go Grafana Web API"
 	docs.SwaggerInfo.Description = "A Go web application with Grafana monitoring"
@@ -61,40 +93,90 @@ func main() {
 	docs.SwaggerInfo.Schemes = []string{"http"}
 
 	app := fx.New(
-		// Provide all dependencies
+		// Provide the dependencies shared with the CLI subcommands
+		fx.Supply(cfg, sources),
+		coreModule,
+		// Provide the HTTP dependencies
 		fx.Provide(
-			config.NewConfig,
-			newLogger,
-			database.NewPostgresDB,
-			func() prometheus.Registerer { return prometheus.DefaultRegisterer },
-			metrics.NewPrometheusMetrics,
-			repository.NewUserRepository,
-			repository.NewAPIKeyRepository,
-			service.NewUserService,
-			service.NewAPIKeyService,
+			middleware.NewTracingMiddleware,
+			middleware.NewRequestIDMiddleware,
 			middleware.NewLoggingMiddleware,
 			middleware.NewMetricsMiddleware,
 			middleware.NewCORSMiddleware,
+			middleware.NewRateLimitMiddleware,
 			handler.NewUserHandler,
 			handler.NewAPIKeyHandler,
+			handler.NewHealthHandler,
+			handler.NewAdminHandler,
+			newHealthRegistry,
+			newAPIKeyUsageTracker,
 			newGinEngine,
 			newHTTPServer,
+			newConfigReloader,
+			newAdminServer,
 		),
+		// Cache API key validation in the server only; CLI changes must hit the database directly.
+		// Service calls are traced in the server only as well.
+		fx.Decorate(newServerAPIKeyService, service.NewTracedUserService),
+		// Seed the bootstrap API key before accepting traffic
+		fx.Invoke(seedBootstrapAPIKey),
 		// Invoke the server startup
 		fx.Invoke(startServer),
+		fx.Invoke(startAdminServer),
 		fx.Invoke(sentry.InitSentry),
+		// Apply runtime settings on SIGHUP and when the config file changes
+		fx.Invoke(watchConfig),
 		// Configure logging
 		fx.WithLogger(func() fxevent.Logger {
 			return fxevent.NopLogger
 		}),
 	)
 
-	// Start the application
-	app.Run()
+	// Start the application and stop it on SIGINT or SIGTERM
+	startCtx, cancelStart := context.WithTimeout(context.Background(), app.StartTimeout())
+	err = app.Start(startCtx)
+	cancelStart()
+	if err != nil {
+		log.Fatalf("failed to start: %v", err)
+	}
+	done := <-app.Wait()
+
+	// The stop context covers the readiness drain and in-flight requests, plus fx's default for the other hooks
+	stopCtx, cancelStop := context.WithTimeout(context.Background(), cfg.Server.DrainPeriod+cfg.Server.ShutdownTimeout+fx.DefaultTimeout)
+	err = app.Stop(stopCtx)
+	cancelStop()
+	if err != nil {
+		log.Fatalf("failed to stop: %v", err)
+	}
+	os.Exit(done.ExitCode)
 }
 
-// newLogger creates a new Zap logger
-func newLogger(cfg *config.Config) *zap.Logger {
+// coreModule provides logging, storage and services without any HTTP wiring; the configuration is supplied by the caller
+var coreModule = fx.Provide(
+	newLogger,
+	tracing.NewTracerProvider,
+	database.NewDB,
+	func() prometheus.Registerer { return prometheus.DefaultRegisterer },
+	metrics.NewPrometheusMetrics,
+	newCursorCodec,
+	repository.NewUserRepository,
+	repository.NewAPIKeyRepository,
+	repository.NewTransactor,
+	service.NewUserService,
+	service.NewAPIKeyService,
+)
+
+// loadConfig loads the layered configuration and rejects invalid settings before anything starts
+func loadConfig(sources config.Sources) (*config.Config, error) {
+	cfg, err := config.Load(sources)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// newLogger creates a new Zap logger, along with its level so that it can be changed at runtime
+func newLogger(cfg *config.Config) (*zap.Logger, zap.AtomicLevel) {
 	var logger *zap.Logger
 	var err error
 
@@ -102,21 +184,16 @@ func newLogger(cfg *config.Config) *zap.Logger {
 	zapConfig := zap.NewProductionConfig()
 
 	// Set log level
-	switch cfg.Logging.Level {
-	case "debug":
-		zapConfig.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
-	case "info":
-		zapConfig.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
-	case "warn":
-		zapConfig.Level = zap.NewAtomicLevelAt(zap.WarnLevel)
-	case "error":
-		zapConfig.Level = zap.NewAtomicLevelAt(zap.ErrorLevel)
-	default:
+	zapConfig.Level, err = zap.ParseAtomicLevel(cfg.Logging.Level)
+	if err != nil {
 		zapConfig.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
 	}
 
-	// Build the logger
-	logger, err = zapConfig.Build()
+	// Build the logger, masking secrets before entries are written
+	redactor := redact.New(cfg.Logging.RedactFields)
+	logger, err = zapConfig.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return redact.NewCore(core, redactor)
+	}))
 	if err != nil {
 		log.Fatal("Failed to create logger:", err)
 	}
@@ -132,51 +209,124 @@ func newLogger(cfg *config.Config) *zap.Logger {
 		if err != nil {
 			logger.Error("Failed to create Sentry core for Zap", zap.Error(err))
 		} else {
-			logger = zapsentry.AttachCoreToLogger(sentryCore, logger)
+			logger = zapsentry.AttachCoreToLogger(redact.NewCore(sentryCore, redactor), logger)
 		}
 	}
 
-	return logger
+	return logger, zapConfig.Level
+}
+
+// newCursorCodec creates the codec used to sign keyset pagination cursors
+func newCursorCodec(cfg *config.Config, logger *zap.Logger) (*util.CursorCodec, error) {
+	if cfg.Pagination.CursorSecret != "" {
+		return util.NewCursorCodec([]byte(cfg.Pagination.CursorSecret.Reveal())), nil
+	}
+
+	logger.Warn("PAGINATION_CURSOR_SECRET not set, cursors will not be valid across restarts or replicas")
+	return util.NewRandomCursorCodec()
+}
+
+// seedBootstrapAPIKey idempotently creates the admin key configured through BOOTSTRAP_API_KEY
+func seedBootstrapAPIKey(cfg *config.Config, apiKeyService service.APIKeyService, logger *zap.Logger) error {
+	if cfg.APIKeys.BootstrapKey == "" {
+		return nil
+	}
+
+	created, err := apiKeyService.EnsureAPIKey(context.Background(), cfg.APIKeys.BootstrapKey.Reveal(), "bootstrap", []string{models.ScopeAPIKeysAdmin})
+	if err != nil {
+		return fmt.Errorf("failed to seed bootstrap API key: %w", err)
+	}
+
+	if created {
+		logger.Info("Bootstrap API key created")
+	} else {
+		logger.Debug("Bootstrap API key already present")
+	}
+	return nil
+}
+
+// newServerAPIKeyService wraps the API key service with the validation cache unless it is disabled,
+// and traces every call including those answered from the cache
+func newServerAPIKeyService(apiKeyService service.APIKeyService, cfg *config.Config, prometheusMetrics *metrics.PrometheusMetrics, tracerProvider trace.TracerProvider) service.APIKeyService {
+	if cfg.APIKeys.CacheTTL > 0 {
+		apiKeyService = service.NewCachedAPIKeyService(apiKeyService, cfg.APIKeys.CacheTTL, prometheusMetrics)
+	}
+	return service.NewTracedAPIKeyService(apiKeyService, tracerProvider)
+}
+
+// newAPIKeyUsageTracker creates the API key usage tracker and ties its flush loop to the application lifecycle.
+// Its stop hook runs after the HTTP server has shut down, so usage from in-flight requests is still written.
+func newAPIKeyUsageTracker(lifecycle fx.Lifecycle, apiKeyRepo repository.APIKeyRepository, cfg *config.Config, logger *zap.Logger) service.APIKeyUsageTracker {
+	tracker := service.NewAPIKeyUsageTracker(apiKeyRepo, cfg.APIKeys.UsageFlushInterval, logger)
+	lifecycle.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			tracker.Start()
+			return nil
+		},
+		OnStop: tracker.Stop,
+	})
+	return tracker
 }
 
 // newGinEngine creates a new Gin engine with middleware
 func newGinEngine(
+	cfg *config.Config,
+	tracingMiddleware middleware.TracingMiddleware,
+	requestIDMiddleware middleware.RequestIDMiddleware,
 	loggingMiddleware middleware.LoggingMiddleware,
 	metricsMiddleware middleware.MetricsMiddleware,
 	corsMiddleware middleware.CORSMiddleware,
+	rateLimitMiddleware *middleware.RateLimitMiddleware,
 	userHandler *handler.UserHandler,
 	apiKeyHandler *handler.APIKeyHandler,
+	healthHandler *handler.HealthHandler,
 	apiKeyService service.APIKeyService,
+	usageTracker service.APIKeyUsageTracker,
 	logger *zap.Logger,
-) *gin.Engine {
+) (*gin.Engine, error) {
 	// Set Gin mode
 	gin.SetMode(gin.ReleaseMode)
 
-	// Create Gin engine
+	// Create Gin engine; forwarded client IPs are only believed from the configured proxies, since the
+	// client IP picks the rate limit buckets
 	engine := gin.New()
+	if err := engine.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
 
-	// Add middleware
+	// Add middleware; tracing and request IDs come first so logs, metrics, error responses and
+	// Sentry events can refer to the request
+	engine.Use(tracingMiddleware.Handle())
+	engine.Use(requestIDMiddleware.Handle())
 	engine.Use(loggingMiddleware.Handle())
 	engine.Use(metricsMiddleware.Handle())
 	engine.Use(corsMiddleware.Handle())
+	// Recover after Sentry has reported the panic, so clients still get a problem response
+	engine.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		requestctx.Logger(c.Request.Context(), logger).Error("Recovered from panic", zap.Any("panic", recovered), zap.String("path", c.Request.URL.Path))
+		problem.Respond(c, problem.New(problem.Internal, "An unexpected error occurred"))
+	}))
 	engine.Use(sentrygin.New(sentrygin.Options{
 		Repanic: true,
 	}))
+	engine.NoRoute(func(c *gin.Context) {
+		problem.Respond(c, problem.New(problem.NotFound, "No route matches "+c.Request.URL.Path))
+	})
+
+	// Kubernetes probes
+	engine.GET("/livez", healthHandler.Livez)
+	engine.GET("/readyz", healthHandler.Readyz)
 
-	// Create API key authentication middleware
-	apiKeyAuthMiddleware := middleware.APIKeyAuthMiddleware(apiKeyService, logger)
+	// Create API key authentication and rate limiting middleware
+	apiKeyAuthMiddleware := middleware.APIKeyAuthMiddleware(apiKeyService, usageTracker, logger)
+	throttleFailedAuth := rateLimitMiddleware.ThrottleFailedAuth()
+	rateLimit := rateLimitMiddleware.Handle()
 
 	// API routes
 	api := engine.Group("/api/v1")
 	{
-		// Health check
-		api.GET("/health", func(c *gin.Context) {
-			c.JSON(http.StatusOK, gin.H{
-				"status":  "ok",
-				"message": "Service is healthy",
-				"time":    time.Now().UTC(),
-			})
-		})
+		// Health check, kept for clients that predate /readyz
+		api.GET("/health", healthHandler.Readyz)
 
 		// Metrics endpoint for Prometheus
 		api.GET("/metrics", metricsMiddleware.MetricsHandler())
@@ -187,66 +337,121 @@ func newGinEngine(
 		// User routes
 		users := api.Group("/users")
 		{
-			// Public endpoints (no API key required)
-			users.GET("/", userHandler.GetUsers)
-			users.GET("/:id", userHandler.GetUserByID)
-
-			// Protected endpoints (API key required)
-			users.POST("/", apiKeyAuthMiddleware, userHandler.CreateUser)
-			users.PUT("/:id", apiKeyAuthMiddleware, userHandler.UpdateUser)
-			users.DELETE("/:id", apiKeyAuthMiddleware, userHandler.DeleteUser)
+			// Public endpoints (no API key required, rate limited per client IP)
+			usersRead := users.Group("", rateLimit)
+			usersRead.GET("/", userHandler.GetUsers)
+			usersRead.GET("/:id", userHandler.GetUserByID)
+
+			// Protected endpoints (API key with users:write scope required, rate limited per API key and
+			// per client IP on failed authentication)
+			usersWrite := users.Group("", throttleFailedAuth, apiKeyAuthMiddleware, rateLimit, middleware.RequireScopes(logger, models.ScopeUsersWrite))
+			usersWrite.POST("/", userHandler.CreateUser)
+			usersWrite.PUT("/:id", userHandler.UpdateUser)
+			usersWrite.DELETE("/:id", userHandler.DeleteUser)
 		}
 
-		// API Key management routes (protected by API key)
-		apiKeys := api.Group("/api-keys")
+		// API Key management routes (API key with api-keys:admin scope required, rate limited per API key and
+		// per client IP on failed authentication)
+		apiKeys := api.Group("/api-keys", throttleFailedAuth, apiKeyAuthMiddleware, rateLimit, middleware.RequireScopes(logger, models.ScopeAPIKeysAdmin))
 		{
-			apiKeys.POST("/", apiKeyAuthMiddleware, apiKeyHandler.CreateAPIKey)
-			apiKeys.GET("/", apiKeyAuthMiddleware, apiKeyHandler.GetAPIKeys)
-			apiKeys.GET("/:id", apiKeyAuthMiddleware, apiKeyHandler.GetAPIKeyByID)
-			apiKeys.PUT("/:id", apiKeyAuthMiddleware, apiKeyHandler.UpdateAPIKey)
-			apiKeys.DELETE("/:id", apiKeyAuthMiddleware, apiKeyHandler.DeleteAPIKey)
+			apiKeys.POST("/", apiKeyHandler.CreateAPIKey)
+			apiKeys.GET("/", apiKeyHandler.GetAPIKeys)
+			apiKeys.GET("/:id", apiKeyHandler.GetAPIKeyByID)
+			apiKeys.PUT("/:id", apiKeyHandler.UpdateAPIKey)
+			apiKeys.POST("/:id/rotate", apiKeyHandler.RotateAPIKey)
+			apiKeys.DELETE("/:id", apiKeyHandler.DeleteAPIKey)
 		}
 	}
 
 	// Swagger documentation
 	engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	return engine
+	return engine, nil
 }
 
-// newHTTPServer creates a new HTTP server
+// newConfigReloader creates the configuration reloader and applies the runtime settings of every reload:
+// the log level, the CORS policies, the rate limits and the Sentry sample rate
+func newConfigReloader(
+	sources config.Sources,
+	cfg *config.Config,
+	level zap.AtomicLevel,
+	corsMiddleware middleware.CORSMiddleware,
+	rateLimitMiddleware *middleware.RateLimitMiddleware,
+	reg prometheus.Registerer,
+	logger *zap.Logger,
+) *reload.Reloader {
+	reloader := reload.NewReloader(sources, cfg, reg, logger)
+	reloader.OnReload(func(cfg *config.Config) {
+		// The level has passed config validation
+		_ = level.UnmarshalText([]byte(cfg.Logging.Level))
+		corsMiddleware.Update(cfg.CORS)
+		rateLimitMiddleware.SetLimits(cfg.RateLimit)
+		sentry.SetSampleRate(cfg.Sentry.SampleRate)
+	})
+	return reloader
+}
+
+// watchConfig ties the configuration reloader to the application lifecycle
+func watchConfig(lifecycle fx.Lifecycle, reloader *reload.Reloader) {
+	lifecycle.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			reloader.Start()
+			return nil
+		},
+		OnStop: reloader.Stop,
+	})
+}
+
+// newHTTPServer creates the HTTP server with the configured timeouts and header limit
 func newHTTPServer(engine *gin.Engine, cfg *config.Config) *http.Server {
 	return &http.Server{
-		Addr:    ":" + cfg.Server.Port,
-		Handler: engine,
+		Addr:              ":" + cfg.Server.Port,
+		Handler:           engine,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
 	}
 }
 
-// startServer starts the HTTP server with graceful shutdown
-func startServer(lifecycle fx.Lifecycle, server *http.Server, logger *zap.Logger) {
+// startServer serves HTTP for the lifetime of the application. On stop the readiness probe fails for
+// the drain period while requests are still served, then in-flight requests are given until the
+// shutdown timeout or the end of the stop context to complete.
+func startServer(lifecycle fx.Lifecycle, shutdowner fx.Shutdowner, server *http.Server, registry *health.Registry, cfg *config.Config, logger *zap.Logger) {
 	lifecycle.Append(fx.Hook{
 		OnStart: func(context.Context) error {
+			// Listen before returning so that a port in use fails startup
+			listener, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return fmt.Errorf("failed to listen on %s: %w", server.Addr, err)
+			}
+
 			logger.Info("Starting HTTP server", zap.String("addr", server.Addr))
-			// Start server in a goroutine
 			go func() {
-				if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-					logger.Error("Failed to start server", zap.Error(err))
-					log.Fatal(err)
+				if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					logger.Error("HTTP server failed", zap.Error(err))
+					_ = shutdowner.Shutdown(fx.ExitCode(1))
 				}
 			}()
 			return nil
 		},
 		OnStop: func(ctx context.Context) error {
+			logger.Info("Draining HTTP server", zap.Duration("drain_period", cfg.Server.DrainPeriod))
+			registry.Drain()
+			// Clients reconnect, and get routed elsewhere, instead of reusing their connections
+			server.SetKeepAlivesEnabled(false)
+			select {
+			case <-time.After(cfg.Server.DrainPeriod):
+			case <-ctx.Done():
+			}
+
 			logger.Info("Shutting down HTTP server")
-			// Create a channel to listen for interrupt signals
-			quit := make(chan os.Signal, 1)
-			signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-			<-quit
-			// Create a context with timeout for graceful shutdown
-			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			ctx, cancel := context.WithTimeout(ctx, cfg.Server.ShutdownTimeout)
 			defer cancel()
 			if err := server.Shutdown(ctx); err != nil {
 				logger.Error("Server forced to shutdown", zap.Error(err))
+				_ = server.Close()
 				return err
 			}
 			logger.Info("Server exited")
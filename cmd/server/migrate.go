@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"go-grafana/internal/config"
+	"go-grafana/pkg/database"
+	"go-grafana/pkg/database/migrations"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// migrationsDir is where "migrate create" writes new migration files, relative to the repository root
+const migrationsDir = "pkg/database/migrations"
+
+// runMigrateCommand applies, rolls back and inspects the versioned database migrations
+func runMigrateCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("migrate: missing subcommand")
+	}
+
+	// Creating a migration only touches the source tree
+	if args[0] == "create" {
+		return createMigration(args[1:])
+	}
+
+	var sources config.Sources
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	sources.RegisterFlags(flags)
+	var steps *int
+	switch args[0] {
+	case "up", "status":
+	case "down":
+		steps = flags.Int("steps", 1, "number of migrations to roll back")
+	default:
+		return fmt.Errorf("migrate: unknown subcommand %q", args[0])
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if steps != nil && *steps < 1 {
+		return errors.New("migrate down: -steps must be at least 1")
+	}
+
+	cfg, err := loadConfig(sources)
+	if err != nil {
+		return err
+	}
+
+	// The migrations are written for Postgres; SQLite databases create their schema on startup
+	if cfg.Database.Driver != config.DriverPostgres {
+		return fmt.Errorf("migrate: not supported for the %s driver, its schema is created on startup", cfg.Database.Driver)
+	}
+
+	var db *gorm.DB
+	var logger *zap.Logger
+	app := fx.New(
+		fx.Supply(cfg),
+		coreModule,
+		fx.NopLogger,
+		fx.Populate(&db, &logger),
+	)
+	if err := app.Err(); err != nil {
+		return err
+	}
+	defer database.CloseDB(db, logger)
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get underlying SQL database: %w", err)
+	}
+	migrator, err := database.NewMigrator(sqlDB, migrations.FS, logger)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "down":
+		return migrateDown(ctx, migrator, *steps)
+	case "status":
+		return migrationStatus(ctx, migrator)
+	default:
+		return migrateUp(ctx, migrator)
+	}
+}
+
+// migrateUp handles "migrate up"
+func migrateUp(ctx context.Context, migrator *database.Migrator) error {
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Applied %d migration(s)\n", applied)
+	return nil
+}
+
+// migrateDown handles "migrate down"
+func migrateDown(ctx context.Context, migrator *database.Migrator, steps int) error {
+	rolledBack, err := migrator.Down(ctx, steps)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Rolled back %d migration(s)\n", rolledBack)
+	return nil
+}
+
+// migrationStatus handles "migrate status"
+func migrationStatus(ctx context.Context, migrator *database.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		name := status.Name
+		if status.Unknown {
+			name = "(no migration file)"
+		}
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, name, appliedAt)
+	}
+	return w.Flush()
+}
+
+// createMigration handles "migrate create"
+func createMigration(args []string) error {
+	flags := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+	dir := flags.String("dir", migrationsDir, "directory holding the migration files")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("migrate create: expected a single migration name, e.g. add_users_phone")
+	}
+
+	paths, err := database.CreateMigration(*dir, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		fmt.Printf("Created %s\n", path)
+	}
+	return nil
+}
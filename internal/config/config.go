@@ -1,8 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
@@ -10,66 +13,320 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig   `json:"server"`
-	Database DatabaseConfig `json:"database"`
-	Logging  LoggingConfig  `json:"logging"`
-	Sentry   SentryConfig   `json:"sentry"`
+	Server     ServerConfig     `json:"server" yaml:"server"`
+	Database   DatabaseConfig   `json:"database" yaml:"database"`
+	Logging    LoggingConfig    `json:"logging" yaml:"logging"`
+	Sentry     SentryConfig     `json:"sentry" yaml:"sentry"`
+	Pagination PaginationConfig `json:"pagination" yaml:"pagination"`
+	APIKeys    APIKeyConfig     `json:"api_keys" yaml:"api_keys"`
+	RateLimit  RateLimitConfig  `json:"rate_limit" yaml:"rate_limit"`
+	Health     HealthConfig     `json:"health" yaml:"health"`
+	Tracing    TracingConfig    `json:"tracing" yaml:"tracing"`
+	CORS       CORSConfig       `json:"cors" yaml:"cors"`
+	Admin      AdminConfig      `json:"admin" yaml:"admin"`
+
+	// loadErrors holds the values that could not be parsed, reported by Validate
+	loadErrors []error
 }
 
 // ServerConfig holds server-specific configuration
 type ServerConfig struct {
-	Port         string        `json:"port"`
-	ReadTimeout  time.Duration `json:"read_timeout"`
-	WriteTimeout time.Duration `json:"write_timeout"`
-	IdleTimeout  time.Duration `json:"idle_timeout"`
+	Port         string        `json:"port" yaml:"port"`
+	ReadTimeout  time.Duration `json:"read_timeout" yaml:"read_timeout"`
+	WriteTimeout time.Duration `json:"write_timeout" yaml:"write_timeout"`
+	IdleTimeout  time.Duration `json:"idle_timeout" yaml:"idle_timeout"`
+	// ReadHeaderTimeout bounds reading the request headers, so slow clients cannot hold connections open
+	ReadHeaderTimeout time.Duration `json:"read_header_timeout" yaml:"read_header_timeout"`
+	MaxHeaderBytes    int           `json:"max_header_bytes" yaml:"max_header_bytes"`
+	// DrainPeriod is how long /readyz fails at shutdown before the server stops accepting connections,
+	// giving load balancers time to stop routing requests to it
+	DrainPeriod time.Duration `json:"drain_period" yaml:"drain_period"`
+	// ShutdownTimeout bounds waiting for in-flight requests once the drain period is over
+	ShutdownTimeout time.Duration `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+	// TrustedProxies are the IPs and CIDRs allowed to set the client IP with X-Forwarded-For and X-Real-IP.
+	// Empty means the client IP is always the peer address, so clients cannot pick their rate limit bucket.
+	TrustedProxies []string `json:"trusted_proxies" yaml:"trusted_proxies"`
 }
 
+// Supported database drivers
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 // DatabaseConfig holds database-specific configuration
 type DatabaseConfig struct {
-	Host     string `json:"host"`
-	Port     string `json:"port"`
-	User     string `json:"user"`
-	Password string `json:"password"`
-	DBName   string `json:"db_name"`
-	SSLMode  string `json:"ssl_mode"`
+	// Driver selects the database engine, either DriverPostgres or DriverSQLite
+	Driver   string `json:"driver" yaml:"driver"`
+	Host     string `json:"host" yaml:"host"`
+	Port     string `json:"port" yaml:"port"`
+	User     string `json:"user" yaml:"user"`
+	Password Secret `json:"password" yaml:"password"`
+	DBName   string `json:"db_name" yaml:"db_name"`
+	SSLMode  string `json:"ssl_mode" yaml:"ssl_mode"`
+	// SQLitePath is the database file used by the sqlite driver; ":memory:" keeps the data in memory
+	SQLitePath string `json:"sqlite_path" yaml:"sqlite_path"`
+	// AutoMigrate runs GORM AutoMigrate at startup. It is meant for local development only;
+	// deployments apply the versioned migrations with "server migrate up".
+	AutoMigrate bool `json:"auto_migrate" yaml:"auto_migrate"`
+	// StatementTimeout bounds every statement issued on behalf of a request; zero disables it
+	StatementTimeout time.Duration `json:"statement_timeout" yaml:"statement_timeout"`
+	// Connection pool settings, applied by the postgres driver. SQLite always uses a single connection.
+	MaxOpenConns int `json:"max_open_conns" yaml:"max_open_conns"`
+	MaxIdleConns int `json:"max_idle_conns" yaml:"max_idle_conns"`
+	// ConnMaxLifetime and ConnMaxIdleTime close connections older or idle for longer; zero keeps them open
+	ConnMaxLifetime time.Duration `json:"conn_max_lifetime" yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `json:"conn_max_idle_time" yaml:"conn_max_idle_time"`
 }
 
 // LoggingConfig holds logging-specific configuration
 type LoggingConfig struct {
-	Level string `json:"level"`
+	Level string `json:"level" yaml:"level"`
+	// RedactFields names the log fields, Sentry data keys and request headers whose values are always masked
+	RedactFields []string `json:"redact_fields" yaml:"redact_fields"`
 }
 
 // SentryConfig holds Sentry-specific configuration
 type SentryConfig struct {
-	DSN string `json:"dsn"`
+	DSN Secret `json:"dsn" yaml:"dsn"`
+	// SampleRate is the fraction of error events sent to Sentry, between 0 and 1
+	SampleRate float64 `json:"sample_rate" yaml:"sample_rate"`
 }
 
-// NewConfig creates a new configuration instance with environment-based values
-func NewConfig() *Config {
+// PaginationConfig holds list pagination configuration
+type PaginationConfig struct {
+	// CursorSecret signs keyset pagination cursors. It must be shared by every
+	// replica; when empty a random per-process secret is used.
+	CursorSecret Secret `json:"cursor_secret" yaml:"cursor_secret"`
+}
+
+// APIKeyConfig holds API key management configuration
+type APIKeyConfig struct {
+	// BootstrapKey is an optional plaintext admin key seeded idempotently at startup
+	BootstrapKey Secret `json:"bootstrap_key" yaml:"bootstrap_key"`
+	// RotationGracePeriod is how long a rotated key keeps working when the request sets no grace_until
+	RotationGracePeriod time.Duration `json:"rotation_grace_period" yaml:"rotation_grace_period"`
+	// UsageFlushInterval is how often buffered usage statistics are written to the database
+	UsageFlushInterval time.Duration `json:"usage_flush_interval" yaml:"usage_flush_interval"`
+	// CacheTTL is how long validation results are cached in process; zero disables the cache
+	CacheTTL time.Duration `json:"cache_ttl" yaml:"cache_ttl"`
+}
+
+// RateLimitConfig holds the default token bucket applied per API key, or per client IP on public routes,
+// and the per client IP budget of failed authentications on protected routes
+type RateLimitConfig struct {
+	Enabled               bool    `json:"enabled" yaml:"enabled"`
+	RequestsPerSecond     float64 `json:"requests_per_second" yaml:"requests_per_second"`
+	Burst                 int     `json:"burst" yaml:"burst"`
+	AuthFailuresPerMinute float64 `json:"auth_failures_per_minute" yaml:"auth_failures_per_minute"`
+	AuthFailureBurst      int     `json:"auth_failure_burst" yaml:"auth_failure_burst"`
+}
+
+// HealthConfig holds liveness and readiness probe configuration
+type HealthConfig struct {
+	// CheckTimeout bounds each dependency check run by a probe
+	CheckTimeout time.Duration `json:"check_timeout" yaml:"check_timeout"`
+}
+
+// Supported trace exporters
+const (
+	TraceExporterNone   = "none"
+	TraceExporterOTLP   = "otlp"
+	TraceExporterStdout = "stdout"
+)
+
+// TracingConfig holds OpenTelemetry tracing configuration
+type TracingConfig struct {
+	// Exporter selects where spans are sent, one of TraceExporterNone, TraceExporterOTLP or TraceExporterStdout
+	Exporter    string `json:"exporter" yaml:"exporter"`
+	ServiceName string `json:"service_name" yaml:"service_name"`
+	// OTLPEndpoint is the URL of the OTLP/HTTP collector, e.g. http://localhost:4318
+	OTLPEndpoint string `json:"otlp_endpoint" yaml:"otlp_endpoint"`
+	// File is where the stdout exporter writes spans; empty writes to standard output
+	File string `json:"file" yaml:"file"`
+	// SampleRatio is the fraction of new traces that are sampled; incoming sampling decisions are respected
+	SampleRatio float64 `json:"sample_ratio" yaml:"sample_ratio"`
+}
+
+// CORSPolicy is the cross-origin policy applied to browser clients
+type CORSPolicy struct {
+	// AllowedOrigins lists origins such as https://app.example.com. An origin may contain one wildcard,
+	// as in https://*.example.com for every subdomain, and "*" alone allows every origin.
+	AllowedOrigins []string `json:"allowed_origins" yaml:"allowed_origins"`
+	AllowedMethods []string `json:"allowed_methods" yaml:"allowed_methods"`
+	AllowedHeaders []string `json:"allowed_headers" yaml:"allowed_headers"`
+	ExposedHeaders []string `json:"exposed_headers" yaml:"exposed_headers"`
+	// AllowCredentials lets browsers send cookies and HTTP authentication; it cannot be combined with "*"
+	AllowCredentials bool `json:"allow_credentials" yaml:"allow_credentials"`
+	// MaxAge is how long browsers may cache the result of a preflight request
+	MaxAge time.Duration `json:"max_age" yaml:"max_age"`
+}
+
+// CORSConfig holds the default CORS policy and its overrides for route groups
+type CORSConfig struct {
+	CORSPolicy `yaml:",inline"`
+	// Routes overrides the policy for the routes under a path prefix such as /api/v1/api-keys.
+	// The longest matching prefix wins, and fields left out of an override keep the default policy.
+	Routes map[string]CORSPolicy `json:"routes" yaml:"routes"`
+}
+
+// AdminConfig holds the admin listener configuration. The admin port serves runtime debugging endpoints
+// and must never be exposed through the public service, so it only listens on the loopback interface by default.
+type AdminConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Address string `json:"address" yaml:"address"`
+	Port    string `json:"port" yaml:"port"`
+}
+
+// Default returns the built-in configuration, the bottom layer under the config file, environment and flags
+func Default() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port:         getEnv("SERVER_PORT", "8080"),
-			ReadTimeout:  getDurationEnv("SERVER_READ_TIMEOUT", 30*time.Second),
-			WriteTimeout: getDurationEnv("SERVER_WRITE_TIMEOUT", 30*time.Second),
-			IdleTimeout:  getDurationEnv("SERVER_IDLE_TIMEOUT", 60*time.Second),
+			Port:              "8080",
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ReadHeaderTimeout: 10 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+			DrainPeriod:       5 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
 		},
 		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "password"),
-			DBName:   getEnv("DB_NAME", "go_grafana"),
-			SSLMode:  getEnv("DB_SSL_MODE", "disable"),
+			Driver:           DriverPostgres,
+			Host:             "localhost",
+			Port:             "5432",
+			User:             "postgres",
+			Password:         "password",
+			DBName:           "go_grafana",
+			SSLMode:          "disable",
+			SQLitePath:       "go_grafana.db",
+			StatementTimeout: 5 * time.Second,
+			MaxOpenConns:     100,
+			MaxIdleConns:     10,
+			ConnMaxLifetime:  time.Hour,
 		},
 		Logging: LoggingConfig{
-			Level: getEnv("LOG_LEVEL", "info"),
+			Level:        "info",
+			RedactFields: []string{"password", "secret", "token", "dsn", "sentry_dsn", "cursor_secret", "bootstrap_key", "authorization", "cookie", "x-api-key"},
 		},
 		Sentry: SentryConfig{
-			DSN: getEnv("SENTRY_DSN", ""),
+			SampleRate: 1,
+		},
+		APIKeys: APIKeyConfig{
+			RotationGracePeriod: 24 * time.Hour,
+			UsageFlushInterval:  10 * time.Second,
+			CacheTTL:            30 * time.Second,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:               true,
+			RequestsPerSecond:     10,
+			Burst:                 20,
+			AuthFailuresPerMinute: 10,
+			AuthFailureBurst:      10,
+		},
+		Health: HealthConfig{
+			CheckTimeout: 2 * time.Second,
+		},
+		Tracing: TracingConfig{
+			Exporter:     TraceExporterNone,
+			ServiceName:  "go-grafana",
+			OTLPEndpoint: "http://localhost:4318",
+			SampleRatio:  1,
+		},
+		CORS: CORSConfig{
+			CORSPolicy: CORSPolicy{
+				AllowedOrigins: []string{"*"},
+				AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+				AllowedHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", "X-API-Key", "X-Request-ID"},
+				ExposedHeaders: []string{"Content-Length", "Content-Type", "X-Request-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"},
+				MaxAge:         12 * time.Hour,
+			},
+		},
+		Admin: AdminConfig{
+			Enabled: true,
+			Address: "127.0.0.1",
+			Port:    "8081",
 		},
 	}
 }
 
+// NewConfig creates a new configuration instance with the defaults overridden by the environment
+func NewConfig() *Config {
+	cfg := Default()
+	cfg.applyEnv()
+	return cfg
+}
+
+// applyEnv overrides the configuration with the environment variables that are set
+func (c *Config) applyEnv() {
+	c.Server.Port = getEnv("SERVER_PORT", c.Server.Port)
+	c.Server.ReadTimeout = c.getDurationEnv("SERVER_READ_TIMEOUT", c.Server.ReadTimeout)
+	c.Server.WriteTimeout = c.getDurationEnv("SERVER_WRITE_TIMEOUT", c.Server.WriteTimeout)
+	c.Server.IdleTimeout = c.getDurationEnv("SERVER_IDLE_TIMEOUT", c.Server.IdleTimeout)
+	c.Server.ReadHeaderTimeout = c.getDurationEnv("SERVER_READ_HEADER_TIMEOUT", c.Server.ReadHeaderTimeout)
+	c.Server.MaxHeaderBytes = c.getIntEnv("SERVER_MAX_HEADER_BYTES", c.Server.MaxHeaderBytes)
+	c.Server.DrainPeriod = c.getDurationEnv("SERVER_DRAIN_PERIOD", c.Server.DrainPeriod)
+	c.Server.ShutdownTimeout = c.getDurationEnv("SERVER_SHUTDOWN_TIMEOUT", c.Server.ShutdownTimeout)
+	c.Server.TrustedProxies = getListEnv("SERVER_TRUSTED_PROXIES", c.Server.TrustedProxies)
+
+	c.Database.Driver = getEnv("DB_DRIVER", c.Database.Driver)
+	c.Database.Host = getEnv("DB_HOST", c.Database.Host)
+	c.Database.Port = getEnv("DB_PORT", c.Database.Port)
+	c.Database.User = getEnv("DB_USER", c.Database.User)
+	c.Database.Password = c.getSecretEnv("DB_PASSWORD", c.Database.Password)
+	c.Database.DBName = getEnv("DB_NAME", c.Database.DBName)
+	c.Database.SSLMode = getEnv("DB_SSL_MODE", c.Database.SSLMode)
+	c.Database.SQLitePath = getEnv("DB_SQLITE_PATH", c.Database.SQLitePath)
+	c.Database.AutoMigrate = c.getBoolEnv("DB_AUTO_MIGRATE", c.Database.AutoMigrate)
+	c.Database.StatementTimeout = c.getDurationEnv("DB_STATEMENT_TIMEOUT", c.Database.StatementTimeout)
+	c.Database.MaxOpenConns = c.getIntEnv("DB_MAX_OPEN_CONNS", c.Database.MaxOpenConns)
+	c.Database.MaxIdleConns = c.getIntEnv("DB_MAX_IDLE_CONNS", c.Database.MaxIdleConns)
+	c.Database.ConnMaxLifetime = c.getDurationEnv("DB_CONN_MAX_LIFETIME", c.Database.ConnMaxLifetime)
+	c.Database.ConnMaxIdleTime = c.getDurationEnv("DB_CONN_MAX_IDLE_TIME", c.Database.ConnMaxIdleTime)
+
+	c.Logging.Level = getEnv("LOG_LEVEL", c.Logging.Level)
+	c.Logging.RedactFields = getListEnv("LOG_REDACT_FIELDS", c.Logging.RedactFields)
+	c.Sentry.DSN = c.getSecretEnv("SENTRY_DSN", c.Sentry.DSN)
+	c.Sentry.SampleRate = c.getFloatEnv("SENTRY_SAMPLE_RATE", c.Sentry.SampleRate)
+	c.Pagination.CursorSecret = c.getSecretEnv("PAGINATION_CURSOR_SECRET", c.Pagination.CursorSecret)
+
+	c.APIKeys.BootstrapKey = c.getSecretEnv("BOOTSTRAP_API_KEY", c.APIKeys.BootstrapKey)
+	c.APIKeys.RotationGracePeriod = c.getDurationEnv("API_KEY_ROTATION_GRACE_PERIOD", c.APIKeys.RotationGracePeriod)
+	c.APIKeys.UsageFlushInterval = c.getDurationEnv("API_KEY_USAGE_FLUSH_INTERVAL", c.APIKeys.UsageFlushInterval)
+	c.APIKeys.CacheTTL = c.getDurationEnv("API_KEY_CACHE_TTL", c.APIKeys.CacheTTL)
+
+	c.RateLimit.Enabled = c.getBoolEnv("RATE_LIMIT_ENABLED", c.RateLimit.Enabled)
+	c.RateLimit.RequestsPerSecond = c.getFloatEnv("RATE_LIMIT_RPS", c.RateLimit.RequestsPerSecond)
+	c.RateLimit.Burst = c.getIntEnv("RATE_LIMIT_BURST", c.RateLimit.Burst)
+	c.RateLimit.AuthFailuresPerMinute = c.getFloatEnv("RATE_LIMIT_AUTH_FAILURES_PER_MINUTE", c.RateLimit.AuthFailuresPerMinute)
+	c.RateLimit.AuthFailureBurst = c.getIntEnv("RATE_LIMIT_AUTH_FAILURE_BURST", c.RateLimit.AuthFailureBurst)
+
+	c.Health.CheckTimeout = c.getDurationEnv("HEALTH_CHECK_TIMEOUT", c.Health.CheckTimeout)
+
+	c.Tracing.Exporter = getEnv("TRACING_EXPORTER", c.Tracing.Exporter)
+	c.Tracing.ServiceName = getEnv("TRACING_SERVICE_NAME", c.Tracing.ServiceName)
+	c.Tracing.OTLPEndpoint = getEnv("TRACING_OTLP_ENDPOINT", c.Tracing.OTLPEndpoint)
+	c.Tracing.File = getEnv("TRACING_FILE", c.Tracing.File)
+	c.Tracing.SampleRatio = c.getFloatEnv("TRACING_SAMPLE_RATIO", c.Tracing.SampleRatio)
+
+	c.CORS.AllowedOrigins = getListEnv("CORS_ALLOWED_ORIGINS", c.CORS.AllowedOrigins)
+	c.CORS.AllowedMethods = getListEnv("CORS_ALLOWED_METHODS", c.CORS.AllowedMethods)
+	c.CORS.AllowedHeaders = getListEnv("CORS_ALLOWED_HEADERS", c.CORS.AllowedHeaders)
+	c.CORS.ExposedHeaders = getListEnv("CORS_EXPOSED_HEADERS", c.CORS.ExposedHeaders)
+	c.CORS.AllowCredentials = c.getBoolEnv("CORS_ALLOW_CREDENTIALS", c.CORS.AllowCredentials)
+	c.CORS.MaxAge = c.getDurationEnv("CORS_MAX_AGE", c.CORS.MaxAge)
+
+	c.Admin.Enabled = c.getBoolEnv("ADMIN_ENABLED", c.Admin.Enabled)
+	c.Admin.Address = getEnv("ADMIN_ADDRESS", c.Admin.Address)
+	c.Admin.Port = getEnv("ADMIN_PORT", c.Admin.Port)
+
+	if value := os.Getenv("CORS_ROUTES"); value != "" {
+		if err := c.mergeCORSRoutes([]byte(value)); err != nil {
+			c.loadErrors = append(c.loadErrors, fmt.Errorf("CORS_ROUTES: %w", err))
+		}
+	}
+}
+
 // getEnv retrieves an environment variable with a fallback default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -78,44 +335,134 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-// getDurationEnv retrieves an environment variable as a duration with a fallback default value
-func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
-	if value := os.Getenv(key); value != "" {
-		if duration, err := time.ParseDuration(value); err == nil {
-			return duration
-		}
-		// Try parsing as seconds if it's just a number
-		if seconds, err := strconv.Atoi(value); err == nil {
-			return time.Duration(seconds) * time.Second
-		}
+// getSecretEnv retrieves a secret from an environment variable, or from the file named by the same variable with
+// a _FILE suffix, as mounted from Kubernetes secrets. Setting both is reported by Validate.
+func (c *Config) getSecretEnv(key string, defaultValue Secret) Secret {
+	file := os.Getenv(key + "_FILE")
+	if file == "" {
+		return Secret(getEnv(key, defaultValue.Reveal()))
+	}
+	if os.Getenv(key) != "" {
+		c.loadErrors = append(c.loadErrors, fmt.Errorf("%s and %s_FILE are both set", key, key))
+		return defaultValue
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		c.loadErrors = append(c.loadErrors, fmt.Errorf("%s_FILE: %w", key, err))
+		return defaultValue
+	}
+	// Secret files usually end with a newline
+	return Secret(strings.TrimRight(string(data), "\r\n"))
+}
+
+// getDurationEnv retrieves an environment variable as a duration, or as a number of seconds, with a fallback
+// default value. A malformed value is reported by Validate.
+func (c *Config) getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return duration
+	}
+	// Try parsing as seconds if it's just a number
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
 	}
+	c.loadErrors = append(c.loadErrors, fmt.Errorf("%s: %q is not a duration", key, value))
 	return defaultValue
 }
 
-// GetDSN returns the database connection string
+// getIntEnv retrieves an environment variable as an integer with a fallback default value.
+// A malformed value is reported by Validate.
+func (c *Config) getIntEnv(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		c.loadErrors = append(c.loadErrors, fmt.Errorf("%s: %q is not an integer", key, value))
+		return defaultValue
+	}
+	return parsed
+}
+
+// getFloatEnv retrieves an environment variable as a float with a fallback default value.
+// A malformed value is reported by Validate.
+func (c *Config) getFloatEnv(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		c.loadErrors = append(c.loadErrors, fmt.Errorf("%s: %q is not a number", key, value))
+		return defaultValue
+	}
+	return parsed
+}
+
+// getBoolEnv retrieves an environment variable as a boolean with a fallback default value.
+// A malformed value is reported by Validate.
+func (c *Config) getBoolEnv(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		c.loadErrors = append(c.loadErrors, fmt.Errorf("%s: %q is not a boolean", key, value))
+		return defaultValue
+	}
+	return parsed
+}
+
+// getListEnv retrieves a comma-separated environment variable as a list with a fallback default value
+func getListEnv(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// GetDSN returns the Postgres connection string without the password, so that it can safely appear in
+// error messages; the password is set separately on the parsed connection config
 func (c *Config) GetDSN() string {
 	return "host=" + c.Database.Host +
 		" port=" + c.Database.Port +
 		" user=" + c.Database.User +
-		" password=" + c.Database.Password +
 		" dbname=" + c.Database.DBName +
 		" sslmode=" + c.Database.SSLMode
 }
 
-// Validate validates the configuration
-func (c *Config) Validate() error {
-	// Add validation logic here if needed
-	return nil
+// Redacted returns a copy of the configuration with every Secret masked
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redactSecrets(reflect.ValueOf(&redacted).Elem())
+	return &redacted
 }
 
 // LogConfig logs the configuration (without sensitive data)
 func (c *Config) LogConfig(logger *zap.Logger) {
 	logger.Info("Configuration loaded",
 		zap.String("server_port", c.Server.Port),
+		zap.String("db_driver", c.Database.Driver),
 		zap.String("db_host", c.Database.Host),
 		zap.String("db_port", c.Database.Port),
 		zap.String("db_name", c.Database.DBName),
 		zap.String("log_level", c.Logging.Level),
-		zap.String("sentry_dsn", c.Sentry.DSN),
+		zap.Stringer("sentry_dsn", c.Sentry.DSN),
+		zap.String("tracing_exporter", c.Tracing.Exporter),
+		zap.Strings("cors_allowed_origins", c.CORS.AllowedOrigins),
 	)
 }
@@ -3,6 +3,9 @@ package config
 import (
 	"bytes"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -22,6 +25,27 @@ func TestNewConfig(t *testing.T) {
 		if cfg.Logging.Level != "info" {
 			t.Errorf("expected log level info, got %s", cfg.Logging.Level)
 		}
+		if cfg.Database.Driver != DriverPostgres {
+			t.Errorf("expected db driver %s, got %s", DriverPostgres, cfg.Database.Driver)
+		}
+		if cfg.Database.AutoMigrate {
+			t.Error("expected auto migrate to be disabled by default")
+		}
+		if cfg.Database.StatementTimeout != 5*time.Second {
+			t.Errorf("expected statement timeout 5s, got %s", cfg.Database.StatementTimeout)
+		}
+		if cfg.Database.MaxOpenConns != 100 || cfg.Database.MaxIdleConns != 10 || cfg.Database.ConnMaxLifetime != time.Hour {
+			t.Errorf("expected pool defaults 100/10/1h, got %d/%d/%s", cfg.Database.MaxOpenConns, cfg.Database.MaxIdleConns, cfg.Database.ConnMaxLifetime)
+		}
+		if cfg.Server.ReadHeaderTimeout != 10*time.Second || cfg.Server.MaxHeaderBytes != 1<<20 {
+			t.Errorf("expected header limits 10s/1MiB, got %s/%d", cfg.Server.ReadHeaderTimeout, cfg.Server.MaxHeaderBytes)
+		}
+		if cfg.Server.DrainPeriod != 5*time.Second || cfg.Server.ShutdownTimeout != 30*time.Second {
+			t.Errorf("expected drain period 5s and shutdown timeout 30s, got %s and %s", cfg.Server.DrainPeriod, cfg.Server.ShutdownTimeout)
+		}
+		if cfg.Health.CheckTimeout != 2*time.Second {
+			t.Errorf("expected health check timeout 2s, got %s", cfg.Health.CheckTimeout)
+		}
 	})
 
 	t.Run("with env variables", func(t *testing.T) {
@@ -29,11 +53,13 @@ func TestNewConfig(t *testing.T) {
 		os.Setenv("DB_HOST", "testdb")
 		os.Setenv("LOG_LEVEL", "debug")
 		os.Setenv("SERVER_READ_TIMEOUT", "10s")
+		os.Setenv("BOOTSTRAP_API_KEY", "bootstrap-key")
 
 		defer os.Unsetenv("SERVER_PORT")
 		defer os.Unsetenv("DB_HOST")
 		defer os.Unsetenv("LOG_LEVEL")
 		defer os.Unsetenv("SERVER_READ_TIMEOUT")
+		defer os.Unsetenv("BOOTSTRAP_API_KEY")
 
 		cfg := NewConfig()
 		if cfg.Server.Port != "9090" {
@@ -48,6 +74,9 @@ func TestNewConfig(t *testing.T) {
 		if cfg.Server.ReadTimeout != 10*time.Second {
 			t.Errorf("expected read timeout 10s, got %s", cfg.Server.ReadTimeout)
 		}
+		if cfg.APIKeys.BootstrapKey != "bootstrap-key" {
+			t.Errorf("expected bootstrap key to be read, got %q", cfg.APIKeys.BootstrapKey)
+		}
 	})
 }
 
@@ -69,36 +98,117 @@ func Test_getEnv(t *testing.T) {
 
 func Test_getDurationEnv(t *testing.T) {
 	t.Run("env not set", func(t *testing.T) {
-		if val := getDurationEnv("NON_EXISTENT_VAR", 5*time.Second); val != 5*time.Second {
+		if val := (&Config{}).getDurationEnv("NON_EXISTENT_VAR", 5*time.Second); val != 5*time.Second {
 			t.Errorf("expected 5s, got %s", val)
 		}
 	})
 
 	t.Run("env set with duration string", func(t *testing.T) {
-		os.Setenv("DURATION_VAR", "15s")
-		defer os.Unsetenv("DURATION_VAR")
-		if val := getDurationEnv("DURATION_VAR", 5*time.Second); val != 15*time.Second {
+		t.Setenv("DURATION_VAR", "15s")
+		if val := (&Config{}).getDurationEnv("DURATION_VAR", 5*time.Second); val != 15*time.Second {
 			t.Errorf("expected 15s, got %s", val)
 		}
 	})
 
 	t.Run("env set with number string", func(t *testing.T) {
-		os.Setenv("DURATION_VAR", "20")
-		defer os.Unsetenv("DURATION_VAR")
-		if val := getDurationEnv("DURATION_VAR", 5*time.Second); val != 20*time.Second {
+		t.Setenv("DURATION_VAR", "20")
+		if val := (&Config{}).getDurationEnv("DURATION_VAR", 5*time.Second); val != 20*time.Second {
 			t.Errorf("expected 20s, got %s", val)
 		}
 	})
 
 	t.Run("env set with invalid string", func(t *testing.T) {
-		os.Setenv("DURATION_VAR", "invalid")
-		defer os.Unsetenv("DURATION_VAR")
-		if val := getDurationEnv("DURATION_VAR", 5*time.Second); val != 5*time.Second {
+		t.Setenv("DURATION_VAR", "invalid")
+		cfg := &Config{}
+		if val := cfg.getDurationEnv("DURATION_VAR", 5*time.Second); val != 5*time.Second {
 			t.Errorf("expected 5s, got %s", val)
 		}
+		if len(cfg.loadErrors) != 1 || !strings.Contains(cfg.loadErrors[0].Error(), `DURATION_VAR: "invalid" is not a duration`) {
+			t.Errorf("expected the malformed value to be reported, got %v", cfg.loadErrors)
+		}
 	})
 }
 
+func Test_getIntEnv(t *testing.T) {
+	t.Run("env not set", func(t *testing.T) {
+		if val := (&Config{}).getIntEnv("NON_EXISTENT_VAR", 5); val != 5 {
+			t.Errorf("expected 5, got %d", val)
+		}
+	})
+
+	t.Run("env set", func(t *testing.T) {
+		t.Setenv("INT_VAR", "42")
+		if val := (&Config{}).getIntEnv("INT_VAR", 5); val != 42 {
+			t.Errorf("expected 42, got %d", val)
+		}
+	})
+
+	t.Run("env set with invalid string", func(t *testing.T) {
+		t.Setenv("INT_VAR", "invalid")
+		cfg := &Config{}
+		if val := cfg.getIntEnv("INT_VAR", 5); val != 5 {
+			t.Errorf("expected 5, got %d", val)
+		}
+		if len(cfg.loadErrors) != 1 || !strings.Contains(cfg.loadErrors[0].Error(), `INT_VAR: "invalid" is not an integer`) {
+			t.Errorf("expected the malformed value to be reported, got %v", cfg.loadErrors)
+		}
+	})
+}
+
+func Test_getFloatEnv(t *testing.T) {
+	t.Setenv("FLOAT_VAR", "0.5")
+	cfg := &Config{}
+	if val := cfg.getFloatEnv("FLOAT_VAR", 1); val != 0.5 {
+		t.Errorf("expected 0.5, got %v", val)
+	}
+	if val := cfg.getFloatEnv("NON_EXISTENT_VAR", 1); val != 1 {
+		t.Errorf("expected 1, got %v", val)
+	}
+
+	t.Setenv("FLOAT_VAR", "half")
+	if val := cfg.getFloatEnv("FLOAT_VAR", 1); val != 1 {
+		t.Errorf("expected 1, got %v", val)
+	}
+	if len(cfg.loadErrors) != 1 || !strings.Contains(cfg.loadErrors[0].Error(), `FLOAT_VAR: "half" is not a number`) {
+		t.Errorf("expected the malformed value to be reported, got %v", cfg.loadErrors)
+	}
+}
+
+func Test_getBoolEnv(t *testing.T) {
+	t.Setenv("BOOL_VAR", "false")
+	cfg := &Config{}
+	if val := cfg.getBoolEnv("BOOL_VAR", true); val {
+		t.Error("expected false, got true")
+	}
+	if val := cfg.getBoolEnv("NON_EXISTENT_VAR", true); !val {
+		t.Error("expected true, got false")
+	}
+
+	t.Setenv("BOOL_VAR", "nope")
+	if val := cfg.getBoolEnv("BOOL_VAR", true); !val {
+		t.Error("expected true, got false")
+	}
+	if len(cfg.loadErrors) != 1 || !strings.Contains(cfg.loadErrors[0].Error(), `BOOL_VAR: "nope" is not a boolean`) {
+		t.Errorf("expected the malformed value to be reported, got %v", cfg.loadErrors)
+	}
+}
+
+func TestNewConfig_MalformedEnv(t *testing.T) {
+	t.Setenv("SERVER_READ_TIMEOUT", "soon")
+	t.Setenv("RATE_LIMIT_BURST", "lots")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "yes please")
+
+	err := NewConfig().Validate()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"SERVER_READ_TIMEOUT", "RATE_LIMIT_BURST", "CORS_ALLOW_CREDENTIALS"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %s to be reported, got %v", want, err)
+		}
+	}
+}
+
 func TestConfig_GetDSN(t *testing.T) {
 	cfg := &Config{
 		Database: DatabaseConfig{
@@ -110,16 +220,97 @@ func TestConfig_GetDSN(t *testing.T) {
 			SSLMode:  "disable",
 		},
 	}
-	expectedDSN := "host=host port=port user=user password=password dbname=dbname sslmode=disable"
+	// The password is set on the parsed connection config, never in the DSN
+	expectedDSN := "host=host port=port user=user dbname=dbname sslmode=disable"
 	if dsn := cfg.GetDSN(); dsn != expectedDSN {
 		t.Errorf("expected DSN '%s', got '%s'", expectedDSN, dsn)
 	}
 }
 
-func TestConfig_Validate(t *testing.T) {
-	cfg := &Config{}
-	if err := cfg.Validate(); err != nil {
-		t.Errorf("expected nil error, got %v", err)
+func TestNewConfig_CORSRoutes(t *testing.T) {
+	t.Run("overrides inherit the default policy", func(t *testing.T) {
+		t.Setenv("CORS_ALLOWED_METHODS", "GET,POST")
+		t.Setenv("CORS_ROUTES", `{"/api/v1/api-keys": {"allowed_origins": ["https://admin.example.com"], "allow_credentials": true, "max_age": "10m"}}`)
+		cfg := NewConfig()
+
+		policy := cfg.CORS.Routes["/api/v1/api-keys"]
+		if len(policy.AllowedOrigins) != 1 || policy.AllowedOrigins[0] != "https://admin.example.com" || !policy.AllowCredentials {
+			t.Errorf("expected the override to apply, got %+v", policy)
+		}
+		if policy.MaxAge != 10*time.Minute || len(policy.AllowedMethods) != 2 {
+			t.Errorf("expected the max age string to be parsed and the methods inherited, got %+v", policy)
+		}
+		if cfg.CORS.AllowedOrigins[0] != "*" {
+			t.Error("expected the default policy to be left unchanged")
+		}
+	})
+
+	t.Run("invalid values are reported by Validate", func(t *testing.T) {
+		t.Setenv("CORS_ROUTES", `{"/api/v1/users": {"max_age": "forever"}}`)
+		if err := NewConfig().Validate(); err == nil || !strings.Contains(err.Error(), "CORS_ROUTES") {
+			t.Errorf("expected an error for CORS_ROUTES, got %v", err)
+		}
+	})
+}
+
+func Test_getSecretEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db_password")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("from file", func(t *testing.T) {
+		t.Setenv("DB_PASSWORD_FILE", path)
+		cfg := NewConfig()
+		if cfg.Database.Password != "from-file" {
+			t.Errorf("expected the password to be read from the file, got %q", cfg.Database.Password)
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+	})
+
+	t.Run("both set", func(t *testing.T) {
+		t.Setenv("DB_PASSWORD", "from-env")
+		t.Setenv("DB_PASSWORD_FILE", path)
+		if err := NewConfig().Validate(); err == nil || !strings.Contains(err.Error(), "DB_PASSWORD and DB_PASSWORD_FILE are both set") {
+			t.Errorf("expected an error for the conflicting variables, got %v", err)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		t.Setenv("DB_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+		if err := NewConfig().Validate(); err == nil || !strings.Contains(err.Error(), "DB_PASSWORD_FILE") {
+			t.Errorf("expected an error for the missing file, got %v", err)
+		}
+	})
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Sentry.DSN = "https://key@sentry.example.com/1"
+	cfg.APIKeys.BootstrapKey = "sk-bootstrap"
+
+	redacted := cfg.Redacted()
+	if redacted.Database.Password != redactedValue || redacted.Sentry.DSN != redactedValue || redacted.APIKeys.BootstrapKey != redactedValue {
+		t.Errorf("expected the secrets to be masked, got %+v", redacted)
+	}
+	if redacted.Pagination.CursorSecret != "" {
+		t.Error("expected unset secrets to stay empty")
+	}
+	if cfg.Database.Password != "password" || redacted.Database.Host != cfg.Database.Host {
+		t.Error("expected the original configuration to be left unchanged")
+	}
+}
+
+func Test_getListEnv(t *testing.T) {
+	t.Setenv("TEST_LIST", " https://a.example.com, ,https://b.example.com ")
+	list := getListEnv("TEST_LIST", nil)
+	if len(list) != 2 || list[0] != "https://a.example.com" || list[1] != "https://b.example.com" {
+		t.Errorf("unexpected list %q", list)
+	}
+	if list := getListEnv("NON_EXISTENT_LIST", []string{"*"}); len(list) != 1 || list[0] != "*" {
+		t.Errorf("expected the default, got %q", list)
 	}
 }
 
@@ -130,6 +321,7 @@ func TestConfig_LogConfig(t *testing.T) {
 	logger := zap.New(core)
 
 	cfg := NewConfig()
+	cfg.Sentry.DSN = "https://key@sentry.example.com/1"
 	cfg.LogConfig(logger)
 
 	logOutput := buffer.String()
@@ -144,4 +336,37 @@ func TestConfig_LogConfig(t *testing.T) {
 	if !bytes.Contains(buffer.Bytes(), []byte(`"db_host":"localhost"`)) {
 		t.Error("log output should contain db host")
 	}
+	if bytes.Contains(buffer.Bytes(), []byte("key@sentry")) || !bytes.Contains(buffer.Bytes(), []byte(`"sentry_dsn":"REDACTED"`)) {
+		t.Error("log output should mask the Sentry DSN")
+	}
+}
+
+func TestConfig_RestartRequired(t *testing.T) {
+	cfg := NewConfig()
+
+	next := NewConfig()
+	next.Logging.Level = "debug"
+	next.CORS.AllowedOrigins = []string{"https://app.example.com"}
+	next.RateLimit.Burst = 50
+	next.Sentry.SampleRate = 0.5
+	if paths := cfg.RestartRequired(next); len(paths) != 0 {
+		t.Errorf("expected runtime settings to be reloadable, got %q", paths)
+	}
+
+	next.Server.Port = "9090"
+	next.Database.Host = "db.example.com"
+	next.Sentry.DSN = "https://key@sentry.example.com/1"
+	paths := cfg.RestartRequired(next)
+	want := []string{"server.port", "database.host", "sentry.dsn"}
+	if !slices.Equal(paths, want) {
+		t.Errorf("expected %q, got %q", want, paths)
+	}
+
+	merged := cfg.WithReloadable(next)
+	if merged.Logging.Level != "debug" || merged.RateLimit.Burst != 50 || merged.Sentry.SampleRate != 0.5 {
+		t.Errorf("expected the runtime settings of next, got %+v", merged)
+	}
+	if merged.Server.Port != cfg.Server.Port || merged.Sentry.DSN != cfg.Sentry.DSN {
+		t.Errorf("expected the restart-only settings to be kept, got %+v", merged)
+	}
 }
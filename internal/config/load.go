@@ -0,0 +1,188 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Sources selects the config file and the command-line overrides layered over the defaults and the environment
+type Sources struct {
+	// File is a YAML (.yaml, .yml) or TOML (.toml) config file; it defaults to the CONFIG_FILE environment variable
+	File string
+	// Overrides set single values by their path in the config file, as in server.port=9090
+	Overrides []string
+}
+
+// RegisterFlags registers the -config and -set flags that fill s
+func (s *Sources) RegisterFlags(flags *flag.FlagSet) {
+	flags.StringVar(&s.File, "config", "", "YAML or TOML config `file` (default $CONFIG_FILE)")
+	flags.Func("set", "override a setting by its `path=value` in the config file, e.g. server.port=9090; may be repeated", func(value string) error {
+		s.Overrides = append(s.Overrides, value)
+		return nil
+	})
+}
+
+// ConfigFile returns the config file to load, if any
+func (s Sources) ConfigFile() string {
+	if s.File != "" {
+		return s.File
+	}
+	return os.Getenv("CONFIG_FILE")
+}
+
+// Load builds the configuration from its layers, each overriding the previous one: the defaults,
+// the config file, the environment and the command-line overrides. The result is validated.
+func Load(sources Sources) (*Config, error) {
+	cfg := Default()
+
+	if file := sources.ConfigFile(); file != "" {
+		if err := cfg.loadFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg.applyEnv()
+
+	for _, override := range sources.Overrides {
+		if err := cfg.set(override); err != nil {
+			return nil, fmt.Errorf("-set %s: %w", override, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile merges a YAML or TOML config file, chosen by its extension
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values := make(map[string]any)
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("config file %s: unsupported format %q, use .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	if err := c.merge(values); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// set merges a single path=value override. The value is parsed as YAML, so lists are written as [a, b].
+func (c *Config) set(override string) error {
+	path, value, ok := strings.Cut(override, "=")
+	if !ok || path == "" {
+		return errors.New("expected path=value")
+	}
+
+	var parsed any
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil {
+		return err
+	}
+
+	// Nest the value under its path, as it would appear in a config file
+	keys := strings.Split(path, ".")
+	values := map[string]any{keys[len(keys)-1]: parsed}
+	for i := len(keys) - 2; i >= 0; i-- {
+		values = map[string]any{keys[i]: values}
+	}
+	return c.merge(values)
+}
+
+// merge decodes a config document over the configuration, rejecting unknown keys
+func (c *Config) merge(values map[string]any) error {
+	// Route overrides are decoded separately to inherit from the default policy
+	var routes any
+	if cors, ok := values["cors"].(map[string]any); ok {
+		routes = cors["routes"]
+		delete(cors, "routes")
+	}
+
+	// Sections are decoded one at a time so that errors name the section they are in
+	sections := make([]string, 0, len(values))
+	for section := range values {
+		sections = append(sections, section)
+	}
+	slices.Sort(sections)
+	for _, section := range sections {
+		if err := decodeStrict(map[string]any{section: values[section]}, c); err != nil {
+			return fmt.Errorf("%s: %w", section, err)
+		}
+	}
+
+	if routes != nil {
+		data, err := yaml.Marshal(routes)
+		if err != nil {
+			return err
+		}
+		if err := c.mergeCORSRoutes(data); err != nil {
+			return fmt.Errorf("cors.routes: %w", err)
+		}
+	}
+	return nil
+}
+
+// mergeCORSRoutes decodes CORS route overrides keyed by path prefix, written in YAML or JSON. Each override
+// is decoded over the current policy of its route, or over the default policy for a new route.
+func (c *Config) mergeCORSRoutes(data []byte) error {
+	var routes map[string]any
+	if err := yaml.Unmarshal(data, &routes); err != nil {
+		return err
+	}
+
+	for prefix, override := range routes {
+		policy, ok := c.CORS.Routes[prefix]
+		if !ok {
+			policy = c.CORS.CORSPolicy
+		}
+		if override != nil {
+			if err := decodeStrict(override, &policy); err != nil {
+				return fmt.Errorf("route %s: %w", prefix, err)
+			}
+		}
+
+		if c.CORS.Routes == nil {
+			c.CORS.Routes = make(map[string]CORSPolicy)
+		}
+		c.CORS.Routes[prefix] = policy
+	}
+	return nil
+}
+
+// decodeStrict decodes a generic YAML or TOML value into out, rejecting keys that match no field
+func decodeStrict(value any, out any) error {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes a config file with the given name into a temporary directory
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_Layers(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+server:
+  port: "9000"
+  read_timeout: 15s
+database:
+  host: db.internal
+  ssl_mode: require
+logging:
+  level: warn
+cors:
+  allowed_origins: ["https://app.example.com"]
+  routes:
+    /api/v1/api-keys:
+      allowed_origins: ["https://admin.example.com"]
+`)
+	// The environment overrides the file and the flags override the environment
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("SERVER_PORT", "9001")
+
+	cfg, err := Load(Sources{File: path, Overrides: []string{"server.port=9002", "rate_limit.burst=5"}})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Server.Port != "9002" || cfg.Logging.Level != "debug" {
+		t.Errorf("expected the flag and environment layers to win, got port %s and level %s", cfg.Server.Port, cfg.Logging.Level)
+	}
+	if cfg.Server.ReadTimeout != 15*time.Second || cfg.Database.Host != "db.internal" || cfg.Database.SSLMode != "require" {
+		t.Errorf("expected the file values, got %+v %+v", cfg.Server, cfg.Database)
+	}
+	if cfg.Server.WriteTimeout != 30*time.Second || cfg.Database.User != "postgres" {
+		t.Error("expected the defaults for settings the file leaves out")
+	}
+	if cfg.RateLimit.Burst != 5 {
+		t.Errorf("expected burst 5, got %d", cfg.RateLimit.Burst)
+	}
+
+	policy := cfg.CORS.Routes["/api/v1/api-keys"]
+	if len(policy.AllowedOrigins) != 1 || policy.AllowedOrigins[0] != "https://admin.example.com" || len(policy.AllowedHeaders) == 0 {
+		t.Errorf("expected the route override to inherit the default policy, got %+v", policy)
+	}
+}
+
+func TestLoad_TOML(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", `
+[server]
+port = "9000"
+drain_period = "10s"
+
+[rate_limit]
+requests_per_second = 2.5
+`)
+	t.Setenv("CONFIG_FILE", path)
+
+	cfg, err := Load(Sources{})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Server.Port != "9000" || cfg.Server.DrainPeriod != 10*time.Second || cfg.RateLimit.RequestsPerSecond != 2.5 {
+		t.Errorf("unexpected configuration %+v %+v", cfg.Server, cfg.RateLimit)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		sets    []string
+		wantErr string
+	}{
+		{"unknown key", "config.yaml", "server:\n  prot: 9000\n", nil, "field prot not found"},
+		{"unknown route key", "config.yaml", "cors:\n  routes:\n    /api:\n      origins: [x]\n", nil, "route /api"},
+		{"unsupported format", "config.json", "{}", nil, "unsupported format"},
+		{"invalid duration", "config.yaml", "server:\n  read_timeout: soon\n", nil, "server: yaml: unmarshal errors"},
+		{"invalid override", "", "", []string{"server.port"}, "expected path=value"},
+		{"unknown override", "", "", []string{"server.prot=1"}, "field prot not found"},
+		{"invalid values", "config.yaml", "server:\n  port: \"0\"\ndatabase:\n  ssl_mode: on\n", nil, "database.ssl_mode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sources Sources
+			if tt.file != "" {
+				sources.File = writeConfigFile(t, tt.file, tt.content)
+			}
+			sources.Overrides = tt.sets
+
+			_, err := Load(sources)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSources_RegisterFlags(t *testing.T) {
+	var sources Sources
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	sources.RegisterFlags(flags)
+
+	if err := flags.Parse([]string{"-config", "app.yaml", "-set", "server.port=1", "-set", "logging.level=debug"}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if sources.File != "app.yaml" || len(sources.Overrides) != 2 || sources.Overrides[1] != "logging.level=debug" {
+		t.Errorf("unexpected sources %+v", sources)
+	}
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// RestartRequired returns the paths of the settings that differ in next but only take effect after a restart.
+// The log level, the CORS policies, the rate limits and the Sentry sample rate can be applied at runtime;
+// every other setting is read once at startup.
+func (c *Config) RestartRequired(next *Config) []string {
+	return changedPaths("", reflect.ValueOf(*c), reflect.ValueOf(*next.WithReloadable(c)), nil)
+}
+
+// WithReloadable returns a copy of c with the settings that can be applied at runtime taken from next
+func (c *Config) WithReloadable(next *Config) *Config {
+	merged := *c
+	merged.Logging.Level = next.Logging.Level
+	merged.CORS = next.CORS
+	merged.RateLimit = next.RateLimit
+	merged.Sentry.SampleRate = next.Sentry.SampleRate
+	return &merged
+}
+
+// changedPaths appends the config file paths of the settings that differ between a and b
+func changedPaths(path string, a, b reflect.Value, paths []string) []string {
+	if a.Kind() != reflect.Struct {
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			paths = append(paths, path)
+		}
+		return paths
+	}
+
+	for i := range a.NumField() {
+		field := a.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		// Inline fields share the path of their parent
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		fieldPath := path
+		if name != "" && path != "" {
+			fieldPath = path + "." + name
+		} else if name != "" {
+			fieldPath = name
+		}
+		paths = changedPaths(fieldPath, a.Field(i), b.Field(i), paths)
+	}
+	return paths
+}
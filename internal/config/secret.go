@@ -0,0 +1,51 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// redactedValue replaces secrets in configuration dumps
+const redactedValue = "REDACTED"
+
+// Secret is a sensitive configuration value. It is masked when formatted or encoded as JSON, so that it
+// does not end up in logs or API responses by accident; Reveal returns the value itself.
+// It is left as is in YAML so that "config print -redacted=false" can output a usable config file.
+type Secret string
+
+// Reveal returns the secret value
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+// String masks the secret, keeping empty values empty so that it is clear whether one is set
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// GoString masks the secret in %#v output
+func (s Secret) GoString() string {
+	return `"` + s.String() + `"`
+}
+
+// MarshalJSON encodes the masked secret
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// redactSecrets masks every Secret field of the struct v points to, including those of nested structs
+func redactSecrets(v reflect.Value) {
+	for i := range v.NumField() {
+		field := v.Field(i)
+		switch {
+		case !field.CanSet():
+		case field.Type() == reflect.TypeFor[Secret]():
+			field.Set(reflect.ValueOf(Secret(field.Interface().(Secret).String())))
+		case field.Kind() == reflect.Struct:
+			redactSecrets(field)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSecret(t *testing.T) {
+	secret := Secret("s3cret")
+
+	if secret.Reveal() != "s3cret" {
+		t.Errorf("expected Reveal to return the value, got %q", secret.Reveal())
+	}
+	for _, format := range []string{"%s", "%v", "%+v", "%#v"} {
+		if got := fmt.Sprintf(format, struct{ Password Secret }{secret}); strings.Contains(got, "s3cret") {
+			t.Errorf("%s: expected the secret to be masked, got %s", format, got)
+		}
+	}
+
+	data, err := json.Marshal(DatabaseConfig{Password: secret})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), `"password":"REDACTED"`) {
+		t.Errorf("expected the secret to be masked in JSON, got %s", data)
+	}
+
+	if Secret("").String() != "" {
+		t.Error("expected an empty secret to stay empty")
+	}
+
+	// YAML keeps the value so that unredacted config dumps can be loaded again
+	data, err = yaml.Marshal(DatabaseConfig{Password: secret})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded DatabaseConfig
+	if err := yaml.Unmarshal(data, &decoded); err != nil || decoded.Password != secret {
+		t.Errorf("expected the secret to round-trip through YAML, got %q (%v)", decoded.Password, err)
+	}
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// sslModes are the sslmode values accepted by Postgres
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// traceExporters are the supported values of TracingConfig.Exporter
+var traceExporters = []string{TraceExporterNone, TraceExporterOTLP, TraceExporterStdout}
+
+// Validate validates the configuration and returns every problem found at once.
+// Problems are named by their path in the config file.
+func (c *Config) Validate() error {
+	v := &validator{errs: slices.Clone(c.loadErrors)}
+
+	v.port("server.port", c.Server.Port)
+	v.nonNegative("server.read_timeout", c.Server.ReadTimeout)
+	v.nonNegative("server.write_timeout", c.Server.WriteTimeout)
+	v.nonNegative("server.idle_timeout", c.Server.IdleTimeout)
+	v.nonNegative("server.read_header_timeout", c.Server.ReadHeaderTimeout)
+	v.nonNegative("server.drain_period", c.Server.DrainPeriod)
+	v.positive("server.shutdown_timeout", c.Server.ShutdownTimeout)
+	v.check(c.Server.MaxHeaderBytes > 0, "server.max_header_bytes: must be positive")
+	for _, proxy := range c.Server.TrustedProxies {
+		_, _, err := net.ParseCIDR(proxy)
+		v.check(err == nil || net.ParseIP(proxy) != nil, fmt.Sprintf("server.trusted_proxies: %q is not an IP address or CIDR", proxy))
+	}
+
+	switch c.Database.Driver {
+	case DriverPostgres:
+		v.check(c.Database.Host != "", "database.host: is required for the postgres driver")
+		v.port("database.port", c.Database.Port)
+		v.check(c.Database.User != "", "database.user: is required for the postgres driver")
+		v.check(c.Database.DBName != "", "database.db_name: is required for the postgres driver")
+		v.oneOf("database.ssl_mode", c.Database.SSLMode, sslModes)
+	case DriverSQLite:
+		v.check(c.Database.SQLitePath != "", "database.sqlite_path: is required for the sqlite driver")
+	default:
+		v.oneOf("database.driver", c.Database.Driver, []string{DriverPostgres, DriverSQLite})
+	}
+	v.nonNegative("database.statement_timeout", c.Database.StatementTimeout)
+	v.check(c.Database.MaxOpenConns >= 0, "database.max_open_conns: must not be negative")
+	v.check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns: must not be negative")
+	v.nonNegative("database.conn_max_lifetime", c.Database.ConnMaxLifetime)
+	v.nonNegative("database.conn_max_idle_time", c.Database.ConnMaxIdleTime)
+
+	if _, err := zapcore.ParseLevel(c.Logging.Level); err != nil {
+		v.errs = append(v.errs, fmt.Errorf("logging.level: %w", err))
+	}
+
+	v.check(c.Sentry.SampleRate >= 0 && c.Sentry.SampleRate <= 1, "sentry.sample_rate: must be between 0 and 1")
+
+	v.nonNegative("api_keys.rotation_grace_period", c.APIKeys.RotationGracePeriod)
+	v.positive("api_keys.usage_flush_interval", c.APIKeys.UsageFlushInterval)
+	v.nonNegative("api_keys.cache_ttl", c.APIKeys.CacheTTL)
+
+	if c.RateLimit.Enabled {
+		v.check(c.RateLimit.RequestsPerSecond > 0, "rate_limit.requests_per_second: must be positive")
+		v.check(c.RateLimit.Burst >= 1, "rate_limit.burst: must be at least 1")
+		v.check(c.RateLimit.AuthFailuresPerMinute > 0, "rate_limit.auth_failures_per_minute: must be positive")
+		v.check(c.RateLimit.AuthFailureBurst >= 1, "rate_limit.auth_failure_burst: must be at least 1")
+	}
+
+	v.positive("health.check_timeout", c.Health.CheckTimeout)
+
+	v.oneOf("tracing.exporter", c.Tracing.Exporter, traceExporters)
+	if c.Tracing.Exporter == TraceExporterOTLP {
+		v.check(c.Tracing.OTLPEndpoint != "", "tracing.otlp_endpoint: is required for the otlp exporter")
+	}
+	v.check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio: must be between 0 and 1")
+
+	v.errs = append(v.errs, validateCORSPolicy("cors", c.CORS.CORSPolicy))
+	prefixes := make([]string, 0, len(c.CORS.Routes))
+	for prefix := range c.CORS.Routes {
+		prefixes = append(prefixes, prefix)
+	}
+	slices.Sort(prefixes)
+	for _, prefix := range prefixes {
+		if !strings.HasPrefix(prefix, "/") {
+			v.errs = append(v.errs, fmt.Errorf("cors: route prefix %q must start with /", prefix))
+		}
+		v.errs = append(v.errs, validateCORSPolicy("cors route "+prefix, c.CORS.Routes[prefix]))
+	}
+
+	if c.Admin.Enabled {
+		v.check(net.ParseIP(c.Admin.Address) != nil, fmt.Sprintf("admin.address: %q is not an IP address", c.Admin.Address))
+		v.port("admin.port", c.Admin.Port)
+		v.check(c.Admin.Port != c.Server.Port, "admin.port: must differ from server.port")
+	}
+
+	return errors.Join(v.errs...)
+}
+
+// validator collects validation errors
+type validator struct {
+	errs []error
+}
+
+// check records the error message unless ok
+func (v *validator) check(ok bool, message string) {
+	if !ok {
+		v.errs = append(v.errs, errors.New(message))
+	}
+}
+
+// port checks that value is a TCP port number
+func (v *validator) port(name, value string) {
+	port, err := strconv.Atoi(value)
+	v.check(err == nil && port > 0 && port <= 65535, fmt.Sprintf("%s: %q is not a port between 1 and 65535", name, value))
+}
+
+// nonNegative checks that a duration is zero or more, zero usually disabling the setting
+func (v *validator) nonNegative(name string, value time.Duration) {
+	v.check(value >= 0, name+": must not be negative")
+}
+
+// positive checks that a duration is more than zero
+func (v *validator) positive(name string, value time.Duration) {
+	v.check(value > 0, name+": must be positive")
+}
+
+// oneOf checks that value is one of the allowed values
+func (v *validator) oneOf(name, value string, allowed []string) {
+	v.check(slices.Contains(allowed, value), fmt.Sprintf("%s: %q is not one of %s", name, value, strings.Join(allowed, ", ")))
+}
+
+// validateCORSPolicy checks that a CORS policy is well formed and does not let every origin make credentialed requests
+func validateCORSPolicy(name string, policy CORSPolicy) error {
+	var errs []error
+	if len(policy.AllowedOrigins) == 0 {
+		errs = append(errs, fmt.Errorf("%s: at least one allowed origin is required", name))
+	}
+	for _, origin := range policy.AllowedOrigins {
+		switch {
+		case origin == "*":
+			if len(policy.AllowedOrigins) > 1 {
+				errs = append(errs, fmt.Errorf("%s: the \"*\" origin cannot be combined with other origins", name))
+			}
+			// Any site could then make requests with the cookies and credentials of its visitors
+			if policy.AllowCredentials {
+				errs = append(errs, fmt.Errorf("%s: credentials cannot be allowed for every origin, list the allowed origins instead", name))
+			}
+		case !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://"):
+			errs = append(errs, fmt.Errorf("%s: origin %q must start with http:// or https://", name, origin))
+		case strings.Count(origin, "*") > 1:
+			errs = append(errs, fmt.Errorf("%s: origin %q may contain at most one wildcard", name, origin))
+		// Wildcards such as https://* or https://*example.com match sites anyone can register
+		case policy.AllowCredentials && strings.Contains(origin, "*") && !isSubdomainWildcard(origin):
+			errs = append(errs, fmt.Errorf("%s: origin %q cannot be used with credentials, only subdomain wildcards such as https://*.example.com can", name, origin))
+		}
+	}
+	if policy.MaxAge < 0 {
+		errs = append(errs, fmt.Errorf("%s: max age must not be negative", name))
+	}
+	return errors.Join(errs...)
+}
+
+// isSubdomainWildcard returns true if origin is scheme://*. followed by a host with at least one dot,
+// so that it only matches the subdomains of that host
+func isSubdomainWildcard(origin string) bool {
+	_, host, _ := strings.Cut(origin, "://")
+	domain, ok := strings.CutPrefix(host, "*.")
+	if !ok || strings.ContainsAny(domain, "*/") {
+		return false
+	}
+	hostname, _, _ := strings.Cut(domain, ":")
+	return strings.Contains(strings.Trim(hostname, "."), ".")
+}
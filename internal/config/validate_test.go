@@ -0,0 +1,141 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr string
+	}{
+		{"defaults", func(*Config) {}, ""},
+		{"allowlist with credentials", func(cfg *Config) {
+			cfg.CORS.AllowedOrigins = []string{"https://app.example.com", "https://*.example.com"}
+			cfg.CORS.AllowCredentials = true
+		}, ""},
+		{"wildcard with credentials", func(cfg *Config) {
+			cfg.CORS.AllowCredentials = true
+		}, "credentials cannot be allowed for every origin"},
+		{"scheme wildcard with credentials", func(cfg *Config) {
+			cfg.CORS.AllowedOrigins = []string{"https://*"}
+			cfg.CORS.AllowCredentials = true
+		}, `origin "https://*" cannot be used with credentials`},
+		{"suffix wildcard with credentials", func(cfg *Config) {
+			cfg.CORS.AllowedOrigins = []string{"https://*example.com"}
+			cfg.CORS.AllowCredentials = true
+		}, `origin "https://*example.com" cannot be used with credentials`},
+		{"top-level domain wildcard with credentials", func(cfg *Config) {
+			cfg.CORS.AllowedOrigins = []string{"https://*.com"}
+			cfg.CORS.AllowCredentials = true
+		}, `origin "https://*.com" cannot be used with credentials`},
+		{"suffix wildcard without credentials", func(cfg *Config) {
+			cfg.CORS.AllowedOrigins = []string{"https://*example.com"}
+		}, ""},
+		{"wildcard with credentials in a route override", func(cfg *Config) {
+			cfg.CORS.Routes = map[string]CORSPolicy{"/api/v1/users": {AllowedOrigins: []string{"*"}, AllowCredentials: true}}
+		}, "cors route /api/v1/users: credentials cannot be allowed"},
+		{"no origins", func(cfg *Config) {
+			cfg.CORS.AllowedOrigins = nil
+		}, "at least one allowed origin is required"},
+		{"origin without scheme", func(cfg *Config) {
+			cfg.CORS.AllowedOrigins = []string{"app.example.com"}
+		}, "must start with http:// or https://"},
+		{"relative route prefix", func(cfg *Config) {
+			cfg.CORS.Routes = map[string]CORSPolicy{"api": {AllowedOrigins: []string{"*"}}}
+		}, "must start with /"},
+		{"sqlite does not need postgres settings", func(cfg *Config) {
+			cfg.Database.Driver = DriverSQLite
+			cfg.Database.Host = ""
+			cfg.Database.SSLMode = ""
+		}, ""},
+		{"trusted proxies", func(cfg *Config) {
+			cfg.Server.TrustedProxies = []string{"10.0.0.0/8", "192.168.1.10"}
+		}, ""},
+		{"trusted proxy that is not an address", func(cfg *Config) {
+			cfg.Server.TrustedProxies = []string{"ingress"}
+		}, `server.trusted_proxies: "ingress" is not an IP address or CIDR`},
+		{"invalid server port", func(cfg *Config) {
+			cfg.Server.Port = "80a"
+		}, `server.port: "80a" is not a port`},
+		{"database port out of range", func(cfg *Config) {
+			cfg.Database.Port = "70000"
+		}, "database.port"},
+		{"unknown ssl mode", func(cfg *Config) {
+			cfg.Database.SSLMode = "on"
+		}, `database.ssl_mode: "on" is not one of`},
+		{"missing database host", func(cfg *Config) {
+			cfg.Database.Host = ""
+		}, "database.host: is required"},
+		{"unknown driver", func(cfg *Config) {
+			cfg.Database.Driver = "mysql"
+		}, "database.driver"},
+		{"negative duration", func(cfg *Config) {
+			cfg.Database.StatementTimeout = -time.Second
+		}, "database.statement_timeout: must not be negative"},
+		{"zero shutdown timeout", func(cfg *Config) {
+			cfg.Server.ShutdownTimeout = 0
+		}, "server.shutdown_timeout: must be positive"},
+		{"unknown log level", func(cfg *Config) {
+			cfg.Logging.Level = "verbose"
+		}, "logging.level"},
+		{"sample ratio above 1", func(cfg *Config) {
+			cfg.Tracing.SampleRatio = 2
+		}, "tracing.sample_ratio"},
+		{"negative Sentry sample rate", func(cfg *Config) {
+			cfg.Sentry.SampleRate = -0.5
+		}, "sentry.sample_rate"},
+		{"admin port shared with the server", func(cfg *Config) {
+			cfg.Admin.Port = cfg.Server.Port
+		}, "admin.port: must differ from server.port"},
+		{"admin address that is not an IP", func(cfg *Config) {
+			cfg.Admin.Address = "localhost"
+		}, `admin.address: "localhost" is not an IP address`},
+		{"admin port only matters when enabled", func(cfg *Config) {
+			cfg.Admin.Enabled = false
+			cfg.Admin.Port = ""
+		}, ""},
+		{"zero auth failure budget", func(cfg *Config) {
+			cfg.RateLimit.AuthFailuresPerMinute = 0
+		}, "rate_limit.auth_failures_per_minute: must be positive"},
+		{"zero burst only matters when rate limiting", func(cfg *Config) {
+			cfg.RateLimit.Enabled = false
+			cfg.RateLimit.Burst = 0
+		}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("expected nil error, got %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_AllErrors(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Server.Port = "0"
+	cfg.Database.SSLMode = "on"
+	cfg.Health.CheckTimeout = 0
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"server.port", "database.ssl_mode", "health.check_timeout"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected the error to mention %s, got %v", want, err)
+		}
+	}
+}
@@ -10,15 +10,24 @@ import (
 
 // APIKey represents an API key entity in the system
 type APIKey struct {
-	ID          uint           `json:"id" gorm:"primaryKey" example:"1"`
-	Name        string         `json:"name" gorm:"not null" validate:"required,min=2,max=100" example:"My API Key"`
-	Key         string         `json:"key" gorm:"uniqueIndex;not null" example:"sk-1234567890abcdef"`
-	Description string         `json:"description" gorm:"type:text" example:"API key for external service"`
-	Active      bool           `json:"active" gorm:"default:true" example:"true"`
-	ExpiresAt   *time.Time     `json:"expires_at,omitempty" example:"2024-12-31T23:59:59Z"`
-	CreatedAt   time.Time      `json:"created_at" example:"2023-01-01T00:00:00Z"`
-	UpdatedAt   time.Time      `json:"updated_at" example:"2023-01-01T00:00:00Z"`
-	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	ID          uint       `json:"id" gorm:"primaryKey;index:idx_api_keys_created_at_id,priority:2" example:"1"`
+	Name        string     `json:"name" gorm:"not null" validate:"required,min=2,max=100" example:"My API Key"`
+	Key         string     `json:"key" gorm:"uniqueIndex;not null" example:"sk-1234567890abcdef"`
+	PreviousKey string     `json:"-" gorm:"index"`
+	GraceUntil  *time.Time `json:"grace_until,omitempty" example:"2024-01-02T00:00:00Z"`
+	Description string     `json:"description" gorm:"type:text" example:"API key for external service"`
+	Scopes      Scopes     `json:"scopes" gorm:"type:text;not null;default:''" example:"users:write"`
+	Active      bool       `json:"active" gorm:"default:true" example:"true"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" example:"2024-12-31T23:59:59Z"`
+	// RateLimitRPS and RateLimitBurst override the global rate limit for this key when set
+	RateLimitRPS   *float64       `json:"rate_limit_rps,omitempty" example:"50"`
+	RateLimitBurst *int           `json:"rate_limit_burst,omitempty" example:"100"`
+	LastUsedAt     *time.Time     `json:"last_used_at,omitempty" gorm:"index" example:"2023-06-01T12:00:00Z"`
+	LastUsedIP     string         `json:"last_used_ip,omitempty" gorm:"size:45" example:"203.0.113.7"`
+	UsageCount     int64          `json:"usage_count" gorm:"not null;default:0" example:"42"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"index:idx_api_keys_created_at_id,priority:1" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt      time.Time      `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 }
 
 // TableName specifies the table name for the APIKey model
@@ -28,57 +37,122 @@ func (APIKey) TableName() string {
 
 // CreateAPIKeyRequest represents the request payload for creating an API key
 type CreateAPIKeyRequest struct {
-	Name        string     `json:"name" binding:"required,min=2,max=100" example:"My API Key"`
-	Description string     `json:"description" example:"API key for external service"`
-	ExpiresAt   *time.Time `json:"expires_at,omitempty" example:"2024-12-31T23:59:59Z"`
+	Name           string     `json:"name" binding:"required,min=2,max=100" example:"My API Key"`
+	Description    string     `json:"description" example:"API key for external service"`
+	Scopes         []string   `json:"scopes" binding:"omitempty,dive,oneof=users:read users:write api-keys:admin server:admin" example:"users:write"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty" example:"2024-12-31T23:59:59Z"`
+	RateLimitRPS   *float64   `json:"rate_limit_rps,omitempty" binding:"omitempty,gt=0" example:"50"`
+	RateLimitBurst *int       `json:"rate_limit_burst,omitempty" binding:"omitempty,min=1" example:"100"`
 }
 
 // UpdateAPIKeyRequest represents the request payload for updating an API key
 type UpdateAPIKeyRequest struct {
-	Name        string     `json:"name" binding:"required,min=2,max=100" example:"My API Key"`
-	Description string     `json:"description" example:"API key for external service"`
-	Active      bool       `json:"active" example:"true"`
-	ExpiresAt   *time.Time `json:"expires_at,omitempty" example:"2024-12-31T23:59:59Z"`
+	Name           string     `json:"name" binding:"required,min=2,max=100" example:"My API Key"`
+	Description    string     `json:"description" example:"API key for external service"`
+	Scopes         []string   `json:"scopes" binding:"omitempty,dive,oneof=users:read users:write api-keys:admin server:admin" example:"users:write"`
+	Active         bool       `json:"active" example:"true"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty" example:"2024-12-31T23:59:59Z"`
+	RateLimitRPS   *float64   `json:"rate_limit_rps,omitempty" binding:"omitempty,gt=0" example:"50"`
+	RateLimitBurst *int       `json:"rate_limit_burst,omitempty" binding:"omitempty,min=1" example:"100"`
 }
 
 // APIKeyResponse represents the response payload for API key data
 type APIKeyResponse struct {
-	ID          uint       `json:"id" example:"1"`
-	Name        string     `json:"name" example:"My API Key"`
-	Key         string     `json:"key,omitempty" example:"sk-1234567890abcdef"`
-	Description string     `json:"description" example:"API key for external service"`
-	Active      bool       `json:"active" example:"true"`
-	ExpiresAt   *time.Time `json:"expires_at,omitempty" example:"2024-12-31T23:59:59Z"`
-	CreatedAt   time.Time  `json:"created_at" example:"2023-01-01T00:00:00Z"`
-	UpdatedAt   time.Time  `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+	ID             uint       `json:"id" example:"1"`
+	Name           string     `json:"name" example:"My API Key"`
+	Key            string     `json:"key,omitempty" example:"sk-1234567890abcdef"`
+	Description    string     `json:"description" example:"API key for external service"`
+	Scopes         []string   `json:"scopes" example:"users:write"`
+	Active         bool       `json:"active" example:"true"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty" example:"2024-12-31T23:59:59Z"`
+	RateLimitRPS   *float64   `json:"rate_limit_rps,omitempty" example:"50"`
+	RateLimitBurst *int       `json:"rate_limit_burst,omitempty" example:"100"`
+	GraceUntil     *time.Time `json:"grace_until,omitempty" example:"2024-01-02T00:00:00Z"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty" example:"2023-06-01T12:00:00Z"`
+	LastUsedIP     string     `json:"last_used_ip,omitempty" example:"203.0.113.7"`
+	UsageCount     int64      `json:"usage_count" example:"42"`
+	CreatedAt      time.Time  `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt      time.Time  `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+}
+
+// RotateAPIKeyRequest represents the optional request payload for rotating an API key.
+// The previous key keeps working until GraceUntil; when omitted the configured grace period applies.
+type RotateAPIKeyRequest struct {
+	GraceUntil *time.Time `json:"grace_until,omitempty" example:"2024-01-02T00:00:00Z"`
+	// HardCutover drops the previous key immediately. It is only set by the CLI, never from a request body.
+	HardCutover bool `json:"-"`
+}
+
+// ListAPIKeysRequest represents the query parameters accepted when listing API keys.
+// API keys are always listed with keyset pagination ordered by (created_at, id).
+type ListAPIKeysRequest struct {
+	Limit       int        `form:"limit" binding:"omitempty,min=1,max=100" example:"20"`
+	Cursor      string     `form:"cursor" example:""`
+	UnusedSince *time.Time `form:"unused_since" time_format:"2006-01-02T15:04:05Z07:00" example:"2024-01-01T00:00:00Z"`
+}
+
+// APIKeyFilter holds the storage-level criteria used when listing API keys
+type APIKeyFilter struct {
+	// UnusedSince keeps keys that were never used or not used since the given time
+	UnusedSince *time.Time
+	Limit       int
+}
+
+// APIKeyUsage aggregates the authenticated requests made with a single API key
+type APIKeyUsage struct {
+	APIKeyID   uint
+	Count      int64
+	LastUsedAt time.Time
+	LastUsedIP string
+}
+
+// APIKeyListResponse represents a paginated list of API keys
+type APIKeyListResponse struct {
+	Data       []*APIKeyResponse `json:"data"`
+	Pagination PaginationMeta    `json:"pagination"`
+	Links      PaginationLinks   `json:"links"`
 }
 
 // ToResponseWithKey converts an APIKey model to APIKeyResponse, including the plaintext key.
 // This should only be used when creating a new key.
 func (ak *APIKey) ToResponseWithKey(plainTextKey string) *APIKeyResponse {
 	return &APIKeyResponse{
-		ID:          ak.ID,
-		Name:        ak.Name,
-		Key:         plainTextKey,
-		Description: ak.Description,
-		Active:      ak.Active,
-		ExpiresAt:   ak.ExpiresAt,
-		CreatedAt:   ak.CreatedAt,
-		UpdatedAt:   ak.UpdatedAt,
+		ID:             ak.ID,
+		Name:           ak.Name,
+		Key:            plainTextKey,
+		Description:    ak.Description,
+		Scopes:         ak.scopeList(),
+		Active:         ak.Active,
+		ExpiresAt:      ak.ExpiresAt,
+		RateLimitRPS:   ak.RateLimitRPS,
+		RateLimitBurst: ak.RateLimitBurst,
+		GraceUntil:     ak.activeGraceUntil(),
+		LastUsedAt:     ak.LastUsedAt,
+		LastUsedIP:     ak.LastUsedIP,
+		UsageCount:     ak.UsageCount,
+		CreatedAt:      ak.CreatedAt,
+		UpdatedAt:      ak.UpdatedAt,
 	}
 }
 
 // ToResponseWithoutKey converts an APIKey model to APIKeyResponse without exposing the key
 func (ak *APIKey) ToResponseWithoutKey() *APIKeyResponse {
 	return &APIKeyResponse{
-		ID:          ak.ID,
-		Name:        ak.Name,
-		Key:         "***", // Mask the key for security
-		Description: ak.Description,
-		Active:      ak.Active,
-		ExpiresAt:   ak.ExpiresAt,
-		CreatedAt:   ak.CreatedAt,
-		UpdatedAt:   ak.UpdatedAt,
+		ID:             ak.ID,
+		Name:           ak.Name,
+		Key:            "***", // Mask the key for security
+		Description:    ak.Description,
+		Scopes:         ak.scopeList(),
+		Active:         ak.Active,
+		ExpiresAt:      ak.ExpiresAt,
+		RateLimitRPS:   ak.RateLimitRPS,
+		RateLimitBurst: ak.RateLimitBurst,
+		GraceUntil:     ak.activeGraceUntil(),
+		LastUsedAt:     ak.LastUsedAt,
+		LastUsedIP:     ak.LastUsedIP,
+		UsageCount:     ak.UsageCount,
+		CreatedAt:      ak.CreatedAt,
+		UpdatedAt:      ak.UpdatedAt,
 	}
 }
 
@@ -86,7 +160,10 @@ func (ak *APIKey) ToResponseWithoutKey() *APIKeyResponse {
 func (ak *APIKey) FromCreateRequest(req *CreateAPIKeyRequest) (string, error) {
 	ak.Name = req.Name
 	ak.Description = req.Description
+	ak.Scopes = NewScopes(req.Scopes)
 	ak.ExpiresAt = req.ExpiresAt
+	ak.RateLimitRPS = req.RateLimitRPS
+	ak.RateLimitBurst = req.RateLimitBurst
 	ak.Active = true // Default to active when creating
 
 	plainTextKey, err := util.GenerateAPIKey()
@@ -102,8 +179,49 @@ func (ak *APIKey) FromCreateRequest(req *CreateAPIKeyRequest) (string, error) {
 func (ak *APIKey) FromUpdateRequest(req *UpdateAPIKeyRequest) {
 	ak.Name = req.Name
 	ak.Description = req.Description
+	ak.Scopes = NewScopes(req.Scopes)
 	ak.Active = req.Active
 	ak.ExpiresAt = req.ExpiresAt
+	ak.RateLimitRPS = req.RateLimitRPS
+	ak.RateLimitBurst = req.RateLimitBurst
+}
+
+// RotateKey replaces the key hash. The current key stays valid until graceUntil,
+// or is dropped immediately when graceUntil is nil.
+func (ak *APIKey) RotateKey(hashedKey string, graceUntil *time.Time) {
+	ak.PreviousKey = ""
+	ak.GraceUntil = nil
+	if graceUntil != nil {
+		ak.PreviousKey = ak.Key
+		ak.GraceUntil = graceUntil
+	}
+	ak.Key = hashedKey
+}
+
+// InGracePeriod returns true if the key replaced by the last rotation is still accepted
+func (ak *APIKey) InGracePeriod() bool {
+	return ak.PreviousKey != "" && ak.GraceUntil != nil && time.Now().Before(*ak.GraceUntil)
+}
+
+// activeGraceUntil returns the end of the grace period, or nil once it is over
+func (ak *APIKey) activeGraceUntil() *time.Time {
+	if !ak.InGracePeriod() {
+		return nil
+	}
+	return ak.GraceUntil
+}
+
+// HasScopes returns true if every given scope has been granted to the API key
+func (ak *APIKey) HasScopes(scopes ...string) bool {
+	return len(ak.Scopes.Missing(scopes...)) == 0
+}
+
+// scopeList returns the granted scopes as a non-nil slice for JSON responses
+func (ak *APIKey) scopeList() []string {
+	if ak.Scopes == nil {
+		return []string{}
+	}
+	return ak.Scopes
 }
 
 // IsExpired returns true if the API key has expired
@@ -121,6 +121,43 @@ func TestAPIKey_IsExpired(t *testing.T) {
 	})
 }
 
+func TestAPIKey_RotateKey(t *testing.T) {
+	t.Run("keeps previous key during grace period", func(t *testing.T) {
+		graceUntil := time.Now().Add(1 * time.Hour)
+		apiKey := &APIKey{Key: "old"}
+		apiKey.RotateKey("new", &graceUntil)
+		if apiKey.Key != "new" || apiKey.PreviousKey != "old" {
+			t.Errorf("unexpected keys after rotation: %+v", apiKey)
+		}
+		if !apiKey.InGracePeriod() {
+			t.Error("expected key to be in grace period")
+		}
+		if apiKey.ToResponseWithoutKey().GraceUntil == nil {
+			t.Error("expected grace_until in response")
+		}
+	})
+
+	t.Run("drops previous key without grace period", func(t *testing.T) {
+		graceUntil := time.Now().Add(1 * time.Hour)
+		apiKey := &APIKey{Key: "old", PreviousKey: "older", GraceUntil: &graceUntil}
+		apiKey.RotateKey("new", nil)
+		if apiKey.PreviousKey != "" || apiKey.InGracePeriod() {
+			t.Errorf("expected previous key to be dropped: %+v", apiKey)
+		}
+	})
+
+	t.Run("grace period over", func(t *testing.T) {
+		past := time.Now().Add(-1 * time.Hour)
+		apiKey := &APIKey{Key: "new", PreviousKey: "old", GraceUntil: &past}
+		if apiKey.InGracePeriod() {
+			t.Error("expected grace period to be over")
+		}
+		if apiKey.ToResponseWithoutKey().GraceUntil != nil {
+			t.Error("expected no grace_until in response")
+		}
+	})
+}
+
 func TestAPIKey_IsValid(t *testing.T) {
 	t.Run("valid if active and not expired", func(t *testing.T) {
 		future := time.Now().Add(1 * time.Hour)
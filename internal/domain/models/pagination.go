@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Pagination defaults shared by all list endpoints
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// SortField describes a single ordering criterion for list queries
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Cursor identifies a position in a keyset paginated list ordered by (created_at, id)
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// PaginationMeta describes the position of a page within the full result set.
+// Total and Offset are only reported for offset pagination; NextCursor only for keyset pagination.
+type PaginationMeta struct {
+	Total      *int64 `json:"total,omitempty" example:"250"`
+	Limit      int    `json:"limit" example:"20"`
+	Offset     int    `json:"offset,omitempty" example:"40"`
+	NextCursor string `json:"next_cursor,omitempty" example:"MTcwNDA2NzIwMDAwMDAwMDAwMDo0Mg.c2lnbmF0dXJl"`
+}
+
+// PaginationLinks holds relative links to the neighbouring pages
+type PaginationLinks struct {
+	Next string `json:"next,omitempty" example:"/api/v1/users?limit=20&offset=60"`
+	Prev string `json:"prev,omitempty" example:"/api/v1/users?limit=20&offset=20"`
+}
+
+// HasNext returns true if there are results after the current page
+func (p PaginationMeta) HasNext() bool {
+	if p.NextCursor != "" {
+		return true
+	}
+	return p.Total != nil && int64(p.Offset+p.Limit) < *p.Total
+}
+
+// HasPrev returns true if there are results before the current page.
+// Keyset pagination only walks forward, so it never has a previous page.
+func (p PaginationMeta) HasPrev() bool {
+	return p.Total != nil && p.Offset > 0
+}
+
+// PrevOffset returns the offset of the previous page, never going below zero
+func (p PaginationMeta) PrevOffset() int {
+	if p.Offset < p.Limit {
+		return 0
+	}
+	return p.Offset - p.Limit
+}
@@ -0,0 +1,49 @@
+package models
+
+import "testing"
+
+func TestPaginationMeta(t *testing.T) {
+	total := int64(50)
+
+	t.Run("first page", func(t *testing.T) {
+		meta := PaginationMeta{Total: &total, Limit: 20, Offset: 0}
+		if !meta.HasNext() {
+			t.Error("expected a next page")
+		}
+		if meta.HasPrev() {
+			t.Error("expected no previous page")
+		}
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		meta := PaginationMeta{Total: &total, Limit: 20, Offset: 40}
+		if meta.HasNext() {
+			t.Error("expected no next page")
+		}
+		if !meta.HasPrev() || meta.PrevOffset() != 20 {
+			t.Errorf("expected previous offset 20, got %d", meta.PrevOffset())
+		}
+	})
+
+	t.Run("previous offset never negative", func(t *testing.T) {
+		meta := PaginationMeta{Total: &total, Limit: 20, Offset: 5}
+		if meta.PrevOffset() != 0 {
+			t.Errorf("expected previous offset 0, got %d", meta.PrevOffset())
+		}
+	})
+}
+
+func TestPaginationMeta_Cursor(t *testing.T) {
+	meta := PaginationMeta{Limit: 20, NextCursor: "abc"}
+	if !meta.HasNext() {
+		t.Error("expected a next page when a cursor is present")
+	}
+	if meta.HasPrev() {
+		t.Error("expected keyset pagination to have no previous page")
+	}
+
+	meta.NextCursor = ""
+	if meta.HasNext() {
+		t.Error("expected no next page without a cursor")
+	}
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Scopes that can be granted to an API key
+const (
+	ScopeUsersRead    = "users:read"
+	ScopeUsersWrite   = "users:write"
+	ScopeAPIKeysAdmin = "api-keys:admin"
+	// ScopeServerAdmin grants access to the admin listener: log level, profiles and effective configuration
+	ScopeServerAdmin = "server:admin"
+)
+
+// AllScopes lists every scope understood by the API
+var AllScopes = []string{ScopeUsersRead, ScopeUsersWrite, ScopeAPIKeysAdmin, ScopeServerAdmin}
+
+// IsValidScope returns true if the scope is one of AllScopes
+func IsValidScope(scope string) bool {
+	for _, known := range AllScopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Scopes is the set of permissions granted to an API key.
+// It is stored as a single space separated column so it stays portable across databases.
+type Scopes []string
+
+// NewScopes returns a sorted, de-duplicated copy of the given scopes
+func NewScopes(scopes []string) Scopes {
+	seen := make(map[string]struct{}, len(scopes))
+	result := make(Scopes, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if scope == "" {
+			continue
+		}
+		if _, ok := seen[scope]; ok {
+			continue
+		}
+		seen[scope] = struct{}{}
+		result = append(result, scope)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Has returns true if the scope has been granted
+func (s Scopes) Has(scope string) bool {
+	for _, granted := range s {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Missing returns the required scopes that have not been granted
+func (s Scopes) Missing(required ...string) []string {
+	var missing []string
+	for _, scope := range required {
+		if !s.Has(scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// Value implements driver.Valuer
+func (s Scopes) Value() (driver.Value, error) {
+	return strings.Join(s, " "), nil
+}
+
+// Scan implements sql.Scanner
+func (s *Scopes) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*s = nil
+	case string:
+		*s = NewScopes(strings.Fields(v))
+	case []byte:
+		*s = NewScopes(strings.Fields(string(v)))
+	default:
+		return fmt.Errorf("cannot scan %T into Scopes", value)
+	}
+	return nil
+}
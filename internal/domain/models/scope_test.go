@@ -0,0 +1,66 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsValidScope(t *testing.T) {
+	for _, scope := range AllScopes {
+		if !IsValidScope(scope) {
+			t.Errorf("expected %q to be valid", scope)
+		}
+	}
+	if IsValidScope("users:delete") {
+		t.Error("expected unknown scope to be invalid")
+	}
+}
+
+func TestNewScopes(t *testing.T) {
+	scopes := NewScopes([]string{"users:write", " api-keys:admin ", "users:write", ""})
+	expected := Scopes{"api-keys:admin", "users:write"}
+	if !reflect.DeepEqual(scopes, expected) {
+		t.Errorf("expected %v, got %v", expected, scopes)
+	}
+}
+
+func TestScopes_Missing(t *testing.T) {
+	scopes := Scopes{ScopeUsersWrite}
+
+	if missing := scopes.Missing(ScopeUsersWrite); len(missing) != 0 {
+		t.Errorf("expected no missing scopes, got %v", missing)
+	}
+
+	missing := scopes.Missing(ScopeUsersWrite, ScopeAPIKeysAdmin)
+	if !reflect.DeepEqual(missing, []string{ScopeAPIKeysAdmin}) {
+		t.Errorf("expected [%s] to be missing, got %v", ScopeAPIKeysAdmin, missing)
+	}
+}
+
+func TestScopes_ValueScan(t *testing.T) {
+	scopes := Scopes{ScopeAPIKeysAdmin, ScopeUsersWrite}
+
+	value, err := scopes.Value()
+	if err != nil {
+		t.Fatalf("Value() error = %v", err)
+	}
+	if value != "api-keys:admin users:write" {
+		t.Errorf("unexpected stored value %q", value)
+	}
+
+	var scanned Scopes
+	if err := scanned.Scan(value); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if !reflect.DeepEqual(scanned, scopes) {
+		t.Errorf("expected %v, got %v", scopes, scanned)
+	}
+
+	if err := scanned.Scan([]byte("users:read")); err != nil || !scanned.Has(ScopeUsersRead) {
+		t.Errorf("expected to scan bytes, got %v (err %v)", scanned, err)
+	}
+
+	if err := scanned.Scan(42); err == nil {
+		t.Error("expected an error when scanning an unsupported type")
+	}
+}
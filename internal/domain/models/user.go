@@ -8,13 +8,13 @@ import (
 
 // User represents a user entity in the system
 type User struct {
-	ID        uint           `json:"id" gorm:"primaryKey" example:"1"`
+	ID        uint           `json:"id" gorm:"primaryKey;index:idx_users_created_at_id,priority:2" example:"1"`
 	Email     string         `json:"email" gorm:"uniqueIndex;not null" validate:"required,email" example:"user@example.com"`
 	FirstName string         `json:"first_name" gorm:"not null" validate:"required,min=2,max=50" example:"John"`
 	LastName  string         `json:"last_name" gorm:"not null" validate:"required,min=2,max=50" example:"Doe"`
 	Age       int            `json:"age" gorm:"not null" validate:"required,min=1,max=120" example:"30"`
 	Active    bool           `json:"active" gorm:"default:true" example:"true"`
-	CreatedAt time.Time      `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	CreatedAt time.Time      `json:"created_at" gorm:"index:idx_users_created_at_id,priority:1" example:"2023-01-01T00:00:00Z"`
 	UpdatedAt time.Time      `json:"updated_at" example:"2023-01-01T00:00:00Z"`
 	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 }
@@ -53,6 +53,44 @@ type UserResponse struct {
 	UpdatedAt time.Time `json:"updated_at" example:"2023-01-01T00:00:00Z"`
 }
 
+// ListUsersRequest represents the query parameters accepted when listing users.
+// Either page/page_size or limit/offset may be used; limit/offset take precedence.
+// Passing cursor (empty for the first page) switches to keyset pagination ordered by (created_at, id).
+type ListUsersRequest struct {
+	Page          int        `form:"page" binding:"omitempty,min=1" example:"1"`
+	PageSize      int        `form:"page_size" binding:"omitempty,min=1,max=100" example:"20"`
+	Limit         int        `form:"limit" binding:"omitempty,min=1,max=100" example:"20"`
+	Offset        int        `form:"offset" binding:"omitempty,min=0" example:"0"`
+	Active        *bool      `form:"active" example:"true"`
+	Email         string     `form:"email" example:"example.com"`
+	MinAge        *int       `form:"min_age" binding:"omitempty,min=1,max=120" example:"18"`
+	MaxAge        *int       `form:"max_age" binding:"omitempty,min=1,max=120" example:"65"`
+	CreatedAfter  *time.Time `form:"created_after" time_format:"2006-01-02T15:04:05Z07:00" example:"2023-01-01T00:00:00Z"`
+	CreatedBefore *time.Time `form:"created_before" time_format:"2006-01-02T15:04:05Z07:00" example:"2024-01-01T00:00:00Z"`
+	Sort          string     `form:"sort" example:"-created_at,last_name"`
+	Cursor        *string    `form:"cursor" example:""`
+}
+
+// UserFilter holds the storage-level criteria used when listing users
+type UserFilter struct {
+	Active        *bool
+	EmailContains string
+	MinAge        *int
+	MaxAge        *int
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Sort          []SortField
+	Limit         int
+	Offset        int
+}
+
+// UserListResponse represents a paginated list of users
+type UserListResponse struct {
+	Data       []UserResponse  `json:"data"`
+	Pagination PaginationMeta  `json:"pagination"`
+	Links      PaginationLinks `json:"links"`
+}
+
 // ToResponse converts a User model to UserResponse
 func (u *User) ToResponse() *UserResponse {
 	return &UserResponse{
@@ -1,22 +1,27 @@
 package repository
 
 import (
+	"context"
 	"errors"
 
 	"go-grafana/internal/domain/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // APIKeyRepository defines the interface for API key data operations
 type APIKeyRepository interface {
-	Create(apiKey *models.APIKey) error
-	GetByID(id uint) (*models.APIKey, error)
-	GetByKey(key string) (*models.APIKey, error)
-	GetAll() ([]*models.APIKey, error)
-	Update(apiKey *models.APIKey) error
-	Delete(id uint) error
-	ExistsByKey(key string) bool
+	Create(ctx context.Context, apiKey *models.APIKey) error
+	GetByID(ctx context.Context, id uint) (*models.APIKey, error)
+	GetByKey(ctx context.Context, key string) (*models.APIKey, error)
+	GetByPreviousKey(ctx context.Context, key string) (*models.APIKey, error)
+	ListAfter(ctx context.Context, filter *models.APIKeyFilter, after *models.Cursor) ([]*models.APIKey, error)
+	Update(ctx context.Context, apiKey *models.APIKey) error
+	UpdateKey(ctx context.Context, apiKey *models.APIKey) error
+	RecordUsage(ctx context.Context, usages []models.APIKeyUsage) error
+	Delete(ctx context.Context, id uint) error
+	ExistsByKey(ctx context.Context, key string) bool
 }
 
 // apiKeyRepository implements APIKeyRepository
@@ -32,7 +37,7 @@ func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
 }
 
 // Create creates a new API key in the database
-func (r *apiKeyRepository) Create(apiKey *models.APIKey) error {
+func (r *apiKeyRepository) Create(ctx context.Context, apiKey *models.APIKey) error {
 	if apiKey.Name == "" {
 		return errors.New("name is required")
 	}
@@ -42,12 +47,15 @@ func (r *apiKeyRepository) Create(apiKey *models.APIKey) error {
 	}
 
 	// Check if key already exists
-	if r.ExistsByKey(apiKey.Key) {
-		return errors.New("API key already exists")
+	if r.ExistsByKey(ctx, apiKey.Key) {
+		return ErrAPIKeyExists
 	}
 
-	result := r.db.Create(apiKey)
+	result := r.db.WithContext(ctx).Create(apiKey)
 	if result.Error != nil {
+		if isUniqueViolation(result.Error) {
+			return ErrAPIKeyExists
+		}
 		return result.Error
 	}
 
@@ -55,16 +63,16 @@ func (r *apiKeyRepository) Create(apiKey *models.APIKey) error {
 }
 
 // GetByID retrieves an API key by its ID
-func (r *apiKeyRepository) GetByID(id uint) (*models.APIKey, error) {
+func (r *apiKeyRepository) GetByID(ctx context.Context, id uint) (*models.APIKey, error) {
 	if id == 0 {
-		return nil, errors.New("invalid API key ID")
+		return nil, ErrAPIKeyNotFound
 	}
 
 	var apiKey models.APIKey
-	result := r.db.First(&apiKey, id)
+	result := r.db.WithContext(ctx).First(&apiKey, id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("API key not found")
+			return nil, ErrAPIKeyNotFound
 		}
 		return nil, result.Error
 	}
@@ -73,16 +81,35 @@ func (r *apiKeyRepository) GetByID(id uint) (*models.APIKey, error) {
 }
 
 // GetByKey retrieves an API key by its key value
-func (r *apiKeyRepository) GetByKey(key string) (*models.APIKey, error) {
+func (r *apiKeyRepository) GetByKey(ctx context.Context, key string) (*models.APIKey, error) {
+	if key == "" {
+		return nil, errors.New("key is required")
+	}
+
+	var apiKey models.APIKey
+	result := r.db.WithContext(ctx).Where(map[string]interface{}{"key": key}).First(&apiKey)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, result.Error
+	}
+
+	return &apiKey, nil
+}
+
+// GetByPreviousKey retrieves an API key by the key value it had before its last rotation.
+// Callers are responsible for checking whether the grace period is still running.
+func (r *apiKeyRepository) GetByPreviousKey(ctx context.Context, key string) (*models.APIKey, error) {
 	if key == "" {
 		return nil, errors.New("key is required")
 	}
 
 	var apiKey models.APIKey
-	result := r.db.Where("key = ?", key).First(&apiKey)
+	result := r.db.WithContext(ctx).Where(map[string]interface{}{"previous_key": key}).First(&apiKey)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("API key not found")
+			return nil, ErrAPIKeyNotFound
 		}
 		return nil, result.Error
 	}
@@ -90,10 +117,16 @@ func (r *apiKeyRepository) GetByKey(key string) (*models.APIKey, error) {
 	return &apiKey, nil
 }
 
-// GetAll retrieves all API keys from the database
-func (r *apiKeyRepository) GetAll() ([]*models.APIKey, error) {
+// ListAfter retrieves up to filter.Limit API keys that come strictly after the cursor
+// in (created_at, id) order. A nil cursor starts from the beginning.
+func (r *apiKeyRepository) ListAfter(ctx context.Context, filter *models.APIKeyFilter, after *models.Cursor) ([]*models.APIKey, error) {
+	query := seekAfter(r.db.WithContext(ctx).Model(&models.APIKey{}), after)
+	if filter.UnusedSince != nil {
+		query = query.Where("(last_used_at IS NULL OR last_used_at < ?)", filter.UnusedSince.UTC())
+	}
+
 	var apiKeys []*models.APIKey
-	result := r.db.Find(&apiKeys)
+	result := query.Limit(filter.Limit).Find(&apiKeys)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -102,65 +135,117 @@ func (r *apiKeyRepository) GetAll() ([]*models.APIKey, error) {
 }
 
 // Update updates an existing API key in the database
-func (r *apiKeyRepository) Update(apiKey *models.APIKey) error {
+func (r *apiKeyRepository) Update(ctx context.Context, apiKey *models.APIKey) error {
 	if apiKey.ID == 0 {
-		return errors.New("invalid API key ID")
+		return ErrAPIKeyNotFound
 	}
 
 	if apiKey.Name == "" {
 		return errors.New("name is required")
 	}
 
-	// Check if API key exists
-	existing, err := r.GetByID(apiKey.ID)
-	if err != nil {
-		return err
+	// Lock the row so concurrent updates cannot overwrite each other's changes
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing models.APIKey
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&existing, apiKey.ID)
+		if result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return ErrAPIKeyNotFound
+			}
+			return result.Error
+		}
+
+		// Update only allowed fields (don't update the key itself)
+		existing.Name = apiKey.Name
+		existing.Description = apiKey.Description
+		existing.Scopes = apiKey.Scopes
+		existing.Active = apiKey.Active
+		existing.ExpiresAt = apiKey.ExpiresAt
+		existing.RateLimitRPS = apiKey.RateLimitRPS
+		existing.RateLimitBurst = apiKey.RateLimitBurst
+
+		if result := tx.Save(&existing); result.Error != nil {
+			return result.Error
+		}
+
+		// Copy updated data back to the original object
+		*apiKey = existing
+		return nil
+	})
+}
+
+// UpdateKey persists new key material for an existing API key, including the previous key
+// and the end of its grace period
+func (r *apiKeyRepository) UpdateKey(ctx context.Context, apiKey *models.APIKey) error {
+	if apiKey.ID == 0 {
+		return ErrAPIKeyNotFound
 	}
 
-	// Update only allowed fields (don't update the key itself)
-	existing.Name = apiKey.Name
-	existing.Description = apiKey.Description
-	existing.Active = apiKey.Active
-	existing.ExpiresAt = apiKey.ExpiresAt
+	if apiKey.Key == "" {
+		return errors.New("key is required")
+	}
 
-	result := r.db.Save(existing)
+	result := r.db.WithContext(ctx).Model(apiKey).Select("key", "previous_key", "grace_until").Updates(apiKey)
 	if result.Error != nil {
+		if isUniqueViolation(result.Error) {
+			return ErrAPIKeyExists
+		}
 		return result.Error
 	}
-
-	// Copy updated data back to the original object
-	*apiKey = *existing
+	if result.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
 
 	return nil
 }
 
+// RecordUsage adds the aggregated usage to each API key in a single transaction.
+// UpdateColumns is used so that recording usage does not bump updated_at.
+func (r *apiKeyRepository) RecordUsage(ctx context.Context, usages []models.APIKeyUsage) error {
+	if len(usages) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, usage := range usages {
+			result := tx.Model(&models.APIKey{}).Where(map[string]interface{}{"id": usage.APIKeyID}).UpdateColumns(map[string]interface{}{
+				"usage_count":  gorm.Expr("usage_count + ?", usage.Count),
+				"last_used_at": usage.LastUsedAt,
+				"last_used_ip": usage.LastUsedIP,
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		return nil
+	})
+}
+
 // Delete removes an API key from the database
-func (r *apiKeyRepository) Delete(id uint) error {
+func (r *apiKeyRepository) Delete(ctx context.Context, id uint) error {
 	if id == 0 {
-		return errors.New("invalid API key ID")
-	}
-
-	// Check if API key exists
-	_, err := r.GetByID(id)
-	if err != nil {
-		return err
+		return ErrAPIKeyNotFound
 	}
 
-	result := r.db.Delete(&models.APIKey{}, id)
+	result := r.db.WithContext(ctx).Delete(&models.APIKey{}, id)
 	if result.Error != nil {
 		return result.Error
 	}
+	if result.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
 
 	return nil
 }
 
-// ExistsByKey checks if an API key exists by its key value
-func (r *apiKeyRepository) ExistsByKey(key string) bool {
+// ExistsByKey checks if an API key exists by its key value.
+// Deleted keys are included because the unique index still covers them.
+func (r *apiKeyRepository) ExistsByKey(ctx context.Context, key string) bool {
 	if key == "" {
 		return false
 	}
 
 	var count int64
-	r.db.Model(&models.APIKey{}).Where("key = ?", key).Count(&count)
+	r.db.WithContext(ctx).Unscoped().Model(&models.APIKey{}).Where(map[string]interface{}{"key": key}).Count(&count)
 	return count > 0
 }
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// uniqueViolationCode is the Postgres SQLSTATE raised when a unique constraint is violated
+const uniqueViolationCode = "23505"
+
+// ErrNotFound is matched by every error returned when a record does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is matched by every error returned when a write clashes with an existing record
+var ErrConflict = errors.New("conflict")
+
+// Record specific errors. Their messages end up in API responses, so they are kept stable.
+var (
+	ErrUserNotFound    = newKindError(ErrNotFound, "user not found")
+	ErrUserEmailExists = newKindError(ErrConflict, "user with this email already exists")
+	ErrAPIKeyNotFound  = newKindError(ErrNotFound, "API key not found")
+	ErrAPIKeyExists    = newKindError(ErrConflict, "API key already exists")
+)
+
+// kindError is an error with its own message that also matches a more general sentinel with errors.Is
+type kindError struct {
+	kind    error
+	message string
+}
+
+// newKindError creates an error with the given message that matches kind
+func newKindError(kind error, message string) error {
+	return &kindError{kind: kind, message: message}
+}
+
+// Error returns the error message
+func (e *kindError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the general sentinel so that errors.Is(err, ErrNotFound) and friends work
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
+
+// isUniqueViolation reports whether err was caused by a unique constraint, whether GORM has
+// already translated it or it comes straight from pgx
+func isUniqueViolation(err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode
+}
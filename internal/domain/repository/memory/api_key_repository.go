@@ -0,0 +1,298 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"go-grafana/internal/domain/models"
+	"go-grafana/internal/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+// apiKeyRepository implements repository.APIKeyRepository in memory.
+// Deleted API keys are kept with DeletedAt set, like GORM soft deletes.
+type apiKeyRepository struct {
+	mu      sync.RWMutex
+	lastID  uint
+	apiKeys map[uint]*models.APIKey
+}
+
+// newAPIKeyRepository creates an empty apiKeyRepository
+func newAPIKeyRepository() *apiKeyRepository {
+	return &apiKeyRepository{
+		apiKeys: make(map[uint]*models.APIKey),
+	}
+}
+
+// Create stores a new API key, assigning its ID and timestamps
+func (r *apiKeyRepository) Create(ctx context.Context, apiKey *models.APIKey) error {
+	if apiKey.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if apiKey.Key == "" {
+		return errors.New("key is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.apiKeys[apiKey.ID]; ok || r.keyTaken(apiKey.Key, 0) {
+		return repository.ErrAPIKeyExists
+	}
+
+	if apiKey.ID == 0 {
+		r.lastID++
+		apiKey.ID = r.lastID
+	}
+	createdAt := now()
+	if apiKey.CreatedAt.IsZero() {
+		apiKey.CreatedAt = createdAt
+	}
+	if apiKey.UpdatedAt.IsZero() {
+		apiKey.UpdatedAt = createdAt
+	}
+	// GORM leaves zero values out of the insert, so the column default applies
+	if !apiKey.Active {
+		apiKey.Active = true
+	}
+
+	r.apiKeys[apiKey.ID] = cloneAPIKey(apiKey)
+	return nil
+}
+
+// GetByID retrieves an API key by its ID
+func (r *apiKeyRepository) GetByID(ctx context.Context, id uint) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	apiKey, ok := r.live(id)
+	if !ok {
+		return nil, repository.ErrAPIKeyNotFound
+	}
+	return cloneAPIKey(apiKey), nil
+}
+
+// GetByKey retrieves an API key by its key value
+func (r *apiKeyRepository) GetByKey(ctx context.Context, key string) (*models.APIKey, error) {
+	if key == "" {
+		return nil, errors.New("key is required")
+	}
+
+	return r.find(func(apiKey *models.APIKey) bool { return apiKey.Key == key })
+}
+
+// GetByPreviousKey retrieves an API key by the key value it had before its last rotation
+func (r *apiKeyRepository) GetByPreviousKey(ctx context.Context, key string) (*models.APIKey, error) {
+	if key == "" {
+		return nil, errors.New("key is required")
+	}
+
+	return r.find(func(apiKey *models.APIKey) bool { return apiKey.PreviousKey == key })
+}
+
+// ListAfter retrieves up to filter.Limit API keys that come strictly after the cursor in (created_at, id) order
+func (r *apiKeyRepository) ListAfter(ctx context.Context, filter *models.APIKeyFilter, after *models.Cursor) ([]*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	apiKeys := make([]*models.APIKey, 0, len(r.apiKeys))
+	for _, apiKey := range r.apiKeys {
+		switch {
+		case apiKey.DeletedAt.Valid:
+		case after != nil && compareCursor(apiKey.CreatedAt, apiKey.ID, after.CreatedAt, after.ID) <= 0:
+		case filter.UnusedSince != nil && apiKey.LastUsedAt != nil && !apiKey.LastUsedAt.Before(*filter.UnusedSince):
+		default:
+			apiKeys = append(apiKeys, cloneAPIKey(apiKey))
+		}
+	}
+	slices.SortFunc(apiKeys, func(a, b *models.APIKey) int {
+		return compareCursor(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	})
+
+	return page(apiKeys, 0, filter.Limit), nil
+}
+
+// Update changes the editable fields of an existing API key and copies the stored result back into apiKey
+func (r *apiKeyRepository) Update(ctx context.Context, apiKey *models.APIKey) error {
+	if apiKey.ID == 0 {
+		return repository.ErrAPIKeyNotFound
+	}
+
+	if apiKey.Name == "" {
+		return errors.New("name is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.live(apiKey.ID)
+	if !ok {
+		return repository.ErrAPIKeyNotFound
+	}
+
+	// Update only allowed fields (don't update the key itself)
+	updated := cloneAPIKey(existing)
+	updated.Name = apiKey.Name
+	updated.Description = apiKey.Description
+	updated.Scopes = slices.Clone(apiKey.Scopes)
+	updated.Active = apiKey.Active
+	updated.ExpiresAt = copyPointer(apiKey.ExpiresAt)
+	updated.RateLimitRPS = copyPointer(apiKey.RateLimitRPS)
+	updated.RateLimitBurst = copyPointer(apiKey.RateLimitBurst)
+	updated.UpdatedAt = now()
+
+	r.apiKeys[apiKey.ID] = updated
+	*apiKey = *cloneAPIKey(updated)
+	return nil
+}
+
+// UpdateKey stores new key material for an existing API key, including the previous key
+// and the end of its grace period
+func (r *apiKeyRepository) UpdateKey(ctx context.Context, apiKey *models.APIKey) error {
+	if apiKey.ID == 0 {
+		return repository.ErrAPIKeyNotFound
+	}
+
+	if apiKey.Key == "" {
+		return errors.New("key is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.live(apiKey.ID)
+	if !ok {
+		return repository.ErrAPIKeyNotFound
+	}
+	if r.keyTaken(apiKey.Key, apiKey.ID) {
+		return repository.ErrAPIKeyExists
+	}
+
+	updated := cloneAPIKey(existing)
+	updated.Key = apiKey.Key
+	updated.PreviousKey = apiKey.PreviousKey
+	updated.GraceUntil = copyPointer(apiKey.GraceUntil)
+	updated.UpdatedAt = now()
+
+	r.apiKeys[apiKey.ID] = updated
+	apiKey.UpdatedAt = updated.UpdatedAt
+	return nil
+}
+
+// RecordUsage adds the aggregated usage to each API key. Unknown and deleted keys are skipped
+// and updated_at is left alone.
+func (r *apiKeyRepository) RecordUsage(ctx context.Context, usages []models.APIKeyUsage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, usage := range usages {
+		apiKey, ok := r.live(usage.APIKeyID)
+		if !ok {
+			continue
+		}
+
+		lastUsedAt := usage.LastUsedAt
+		apiKey.UsageCount += usage.Count
+		apiKey.LastUsedAt = &lastUsedAt
+		apiKey.LastUsedIP = usage.LastUsedIP
+	}
+	return nil
+}
+
+// Delete soft deletes an API key
+func (r *apiKeyRepository) Delete(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	apiKey, ok := r.live(id)
+	if !ok {
+		return repository.ErrAPIKeyNotFound
+	}
+
+	apiKey.DeletedAt = gorm.DeletedAt{Time: now(), Valid: true}
+	return nil
+}
+
+// ExistsByKey checks if an API key exists by its key value, including deleted keys
+func (r *apiKeyRepository) ExistsByKey(ctx context.Context, key string) bool {
+	if key == "" {
+		return false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.keyTaken(key, 0)
+}
+
+// live returns the stored API key with the given ID unless it does not exist or has been deleted
+func (r *apiKeyRepository) live(id uint) (*models.APIKey, bool) {
+	apiKey, ok := r.apiKeys[id]
+	if !ok || apiKey.DeletedAt.Valid {
+		return nil, false
+	}
+	return apiKey, true
+}
+
+// find returns a copy of the API key with the lowest ID that has not been deleted and matches
+func (r *apiKeyRepository) find(match func(apiKey *models.APIKey) bool) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var found *models.APIKey
+	for _, apiKey := range r.apiKeys {
+		if !apiKey.DeletedAt.Valid && match(apiKey) && (found == nil || apiKey.ID < found.ID) {
+			found = apiKey
+		}
+	}
+	if found == nil {
+		return nil, repository.ErrAPIKeyNotFound
+	}
+	return cloneAPIKey(found), nil
+}
+
+// keyTaken reports whether an API key other than exceptID holds the key value, deleted or not
+func (r *apiKeyRepository) keyTaken(key string, exceptID uint) bool {
+	for id, apiKey := range r.apiKeys {
+		if id != exceptID && apiKey.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshot returns a deep copy of the repository contents
+func (r *apiKeyRepository) snapshot() map[uint]*models.APIKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	apiKeys := make(map[uint]*models.APIKey, len(r.apiKeys))
+	for id, apiKey := range r.apiKeys {
+		apiKeys[id] = cloneAPIKey(apiKey)
+	}
+	return apiKeys
+}
+
+// restore replaces the repository contents with a snapshot. IDs are not reused, like Postgres sequences.
+func (r *apiKeyRepository) restore(apiKeys map[uint]*models.APIKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.apiKeys = apiKeys
+}
+
+// cloneAPIKey returns a deep copy of an API key so that stored keys are never shared with callers
+func cloneAPIKey(apiKey *models.APIKey) *models.APIKey {
+	c := *apiKey
+	c.Scopes = slices.Clone(apiKey.Scopes)
+	c.GraceUntil = copyPointer(apiKey.GraceUntil)
+	c.ExpiresAt = copyPointer(apiKey.ExpiresAt)
+	c.LastUsedAt = copyPointer(apiKey.LastUsedAt)
+	c.RateLimitRPS = copyPointer(apiKey.RateLimitRPS)
+	c.RateLimitBurst = copyPointer(apiKey.RateLimitBurst)
+	return &c
+}
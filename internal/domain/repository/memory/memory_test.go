@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"go-grafana/internal/domain/models"
+	"go-grafana/internal/domain/repository"
+	"go-grafana/internal/domain/repository/repositorytest"
+)
+
+func TestUserRepository(t *testing.T) {
+	repositorytest.RunUserRepositoryTests(t, func(t *testing.T) repository.UserRepository {
+		return NewUserRepository()
+	})
+}
+
+func TestAPIKeyRepository(t *testing.T) {
+	repositorytest.RunAPIKeyRepositoryTests(t, func(t *testing.T) repository.APIKeyRepository {
+		return NewAPIKeyRepository()
+	})
+}
+
+func TestStore_WithinTx(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+
+	errAbort := errors.New("abort")
+	err := store.WithinTx(ctx, func(repos repository.Repositories) error {
+		if err := repos.Users.Create(ctx, &models.User{Email: "rollback@example.com", FirstName: "Test", LastName: "User", Age: 30}); err != nil {
+			return err
+		}
+		if err := repos.APIKeys.Create(ctx, &models.APIKey{Name: "rollback", Key: "rollback"}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if err != errAbort {
+		t.Fatalf("expected %v, got %v", errAbort, err)
+	}
+	if _, err := store.Users().GetByEmail(ctx, "rollback@example.com"); err != repository.ErrUserNotFound {
+		t.Errorf("expected the user to be rolled back, got %v", err)
+	}
+	if store.APIKeys().ExistsByKey(ctx, "rollback") {
+		t.Error("expected the API key to be rolled back")
+	}
+
+	// Like a Postgres sequence, IDs handed out by a rolled back transaction are not reused
+	user := &models.User{Email: "commit@example.com", FirstName: "Test", LastName: "User", Age: 30}
+	err = store.WithinTx(ctx, func(repos repository.Repositories) error {
+		return repos.Users.Create(ctx, user)
+	})
+	if err != nil {
+		t.Fatalf("WithinTx() error = %v", err)
+	}
+	if user.ID != 2 {
+		t.Errorf("expected ID 2, got %d", user.ID)
+	}
+}
+
+func TestUserRepository_ConcurrentCreate(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created, conflicts := 0, 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.Create(ctx, &models.User{Email: "race@example.com", FirstName: "Test", LastName: "User", Age: 30})
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				created++
+			case errors.Is(err, repository.ErrConflict):
+				conflicts++
+			default:
+				t.Errorf("unexpected error %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if created != 1 || conflicts != 49 {
+		t.Errorf("expected exactly one create to win, got %d created and %d conflicts", created, conflicts)
+	}
+}
@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"cmp"
+	"time"
+)
+
+// compareCursor orders rows by (created_at, id), the keyset used by ListAfter
+func compareCursor(createdAtA time.Time, idA uint, createdAtB time.Time, idB uint) int {
+	if c := createdAtA.Compare(createdAtB); c != 0 {
+		return c
+	}
+	return cmp.Compare(idA, idB)
+}
+
+// compareBool orders false before true, like Postgres
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// page applies OFFSET and LIMIT to rows that are already in order. Like GORM, a negative limit returns every row.
+func page[T any](rows []T, offset, limit int) []T {
+	if offset >= len(rows) {
+		return rows[:0]
+	}
+	rows = rows[max(offset, 0):]
+	if limit >= 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
@@ -0,0 +1,87 @@
+// Package memory provides in-memory implementations of the repositories for tests and local tooling.
+// They follow the semantics of the Postgres backed repositories, which is checked by the
+// conformance suites in repositorytest.
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go-grafana/internal/domain/repository"
+)
+
+// Store holds in-memory users and API keys and runs units of work over them.
+// It implements repository.Transactor.
+type Store struct {
+	// txMu serializes transactions
+	txMu    sync.Mutex
+	users   *userRepository
+	apiKeys *apiKeyRepository
+}
+
+// NewStore creates an empty store
+func NewStore() *Store {
+	return &Store{
+		users:   newUserRepository(),
+		apiKeys: newAPIKeyRepository(),
+	}
+}
+
+// NewUserRepository creates an empty in-memory UserRepository
+func NewUserRepository() repository.UserRepository {
+	return NewStore().Users()
+}
+
+// NewAPIKeyRepository creates an empty in-memory APIKeyRepository
+func NewAPIKeyRepository() repository.APIKeyRepository {
+	return NewStore().APIKeys()
+}
+
+// Users returns the user repository of the store
+func (s *Store) Users() repository.UserRepository {
+	return s.users
+}
+
+// APIKeys returns the API key repository of the store
+func (s *Store) APIKeys() repository.APIKeyRepository {
+	return s.apiKeys
+}
+
+// Repositories returns every repository of the store
+func (s *Store) Repositories() repository.Repositories {
+	return repository.Repositories{
+		Users:   s.users,
+		APIKeys: s.apiKeys,
+	}
+}
+
+// WithinTx runs fn and restores the previous contents of the store if it fails.
+// Transactions run one at a time and cannot be nested. Unlike Postgres they are not isolated
+// from writes made outside a transaction, which are lost if the transaction rolls back.
+func (s *Store) WithinTx(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	users, apiKeys := s.users.snapshot(), s.apiKeys.snapshot()
+	if err := fn(s.Repositories()); err != nil {
+		s.users.restore(users)
+		s.apiKeys.restore(apiKeys)
+		return err
+	}
+	return nil
+}
+
+// now returns the current time at the microsecond precision Postgres stores
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// copyPointer returns a copy of an optional value so that stored values are not shared with callers
+func copyPointer[T any](v *T) *T {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
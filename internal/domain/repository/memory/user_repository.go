@@ -0,0 +1,256 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"go-grafana/internal/domain/models"
+	"go-grafana/internal/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+// userRepository implements repository.UserRepository in memory.
+// Deleted users are kept with DeletedAt set, like GORM soft deletes.
+type userRepository struct {
+	mu     sync.RWMutex
+	lastID uint
+	users  map[uint]models.User
+}
+
+// newUserRepository creates an empty userRepository
+func newUserRepository() *userRepository {
+	return &userRepository{
+		users: make(map[uint]models.User),
+	}
+}
+
+// Create stores a new user, assigning its ID and timestamps
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// The unique indexes cover soft deleted rows too
+	if _, ok := r.users[user.ID]; ok || r.emailTaken(user.Email, 0) {
+		return repository.ErrUserEmailExists
+	}
+
+	if user.ID == 0 {
+		r.lastID++
+		user.ID = r.lastID
+	}
+	createdAt := now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = createdAt
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = createdAt
+	}
+	// GORM leaves zero values out of the insert, so the column default applies
+	if !user.Active {
+		user.Active = true
+	}
+
+	r.users[user.ID] = *user
+	return nil
+}
+
+// GetByID retrieves a user by their ID
+func (r *userRepository) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.live(id)
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+// List retrieves a page of users matching the filter along with the total number of matches
+func (r *userRepository) List(ctx context.Context, filter *models.UserFilter) ([]models.User, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := r.matching(filter)
+	for _, sort := range filter.Sort {
+		if _, err := compareUserColumn(models.User{}, models.User{}, sort.Field); err != nil {
+			return nil, 0, err
+		}
+	}
+	slices.SortFunc(users, func(a, b models.User) int {
+		for _, sort := range filter.Sort {
+			c, _ := compareUserColumn(a, b, sort.Field)
+			if sort.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	total := int64(len(users))
+	return page(users, filter.Offset, filter.Limit), total, nil
+}
+
+// ListAfter retrieves up to filter.Limit users matching the filter that come strictly after
+// the cursor in (created_at, id) order
+func (r *userRepository) ListAfter(ctx context.Context, filter *models.UserFilter, after *models.Cursor) ([]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := r.matching(filter)
+	slices.SortFunc(users, func(a, b models.User) int {
+		return compareCursor(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	})
+	if after != nil {
+		users = slices.DeleteFunc(users, func(user models.User) bool {
+			return compareCursor(user.CreatedAt, user.ID, after.CreatedAt, after.ID) <= 0
+		})
+	}
+
+	return page(users, 0, filter.Limit), nil
+}
+
+// Update writes every field of an existing user
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.live(user.ID); !ok {
+		return repository.ErrUserNotFound
+	}
+	if r.emailTaken(user.Email, user.ID) {
+		return repository.ErrUserEmailExists
+	}
+
+	user.UpdatedAt = now()
+	r.users[user.ID] = *user
+	return nil
+}
+
+// Delete soft deletes a user by ID
+func (r *userRepository) Delete(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.live(id)
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+
+	user.DeletedAt = gorm.DeletedAt{Time: now(), Valid: true}
+	r.users[id] = user
+	return nil
+}
+
+// GetByEmail retrieves a user by their email address
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Email == email && !user.DeletedAt.Valid {
+			return &user, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+// Count returns the number of users that have not been deleted
+func (r *userRepository) Count(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return int64(len(r.matching(&models.UserFilter{}))), nil
+}
+
+// live returns the user with the given ID unless it does not exist or has been deleted
+func (r *userRepository) live(id uint) (models.User, bool) {
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return models.User{}, false
+	}
+	return user, true
+}
+
+// emailTaken reports whether a user other than exceptID holds the email, deleted or not
+func (r *userRepository) emailTaken(email string, exceptID uint) bool {
+	for id, user := range r.users {
+		if id != exceptID && user.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+// matching returns the users that have not been deleted and match the filter, in no particular order
+func (r *userRepository) matching(filter *models.UserFilter) []models.User {
+	emailContains := strings.ToLower(filter.EmailContains)
+
+	users := make([]models.User, 0, len(r.users))
+	for _, user := range r.users {
+		switch {
+		case user.DeletedAt.Valid:
+		case filter.Active != nil && user.Active != *filter.Active:
+		case !strings.Contains(strings.ToLower(user.Email), emailContains):
+		case filter.MinAge != nil && user.Age < *filter.MinAge:
+		case filter.MaxAge != nil && user.Age > *filter.MaxAge:
+		case filter.CreatedAfter != nil && user.CreatedAt.Before(*filter.CreatedAfter):
+		case filter.CreatedBefore != nil && !user.CreatedAt.Before(*filter.CreatedBefore):
+		default:
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
+// snapshot returns a copy of the repository contents
+func (r *userRepository) snapshot() map[uint]models.User {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make(map[uint]models.User, len(r.users))
+	for id, user := range r.users {
+		users[id] = user
+	}
+	return users
+}
+
+// restore replaces the repository contents with a snapshot. IDs are not reused, like Postgres sequences.
+func (r *userRepository) restore(users map[uint]models.User) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.users = users
+}
+
+// compareUserColumn compares two users on one of the sortable columns
+func compareUserColumn(a, b models.User, column string) (int, error) {
+	switch column {
+	case "id":
+		return cmp.Compare(a.ID, b.ID), nil
+	case "email":
+		return cmp.Compare(a.Email, b.Email), nil
+	case "first_name":
+		return cmp.Compare(a.FirstName, b.FirstName), nil
+	case "last_name":
+		return cmp.Compare(a.LastName, b.LastName), nil
+	case "age":
+		return cmp.Compare(a.Age, b.Age), nil
+	case "active":
+		return compareBool(a.Active, b.Active), nil
+	case "created_at":
+		return a.CreatedAt.Compare(b.CreatedAt), nil
+	case "updated_at":
+		return a.UpdatedAt.Compare(b.UpdatedAt), nil
+	default:
+		return 0, fmt.Errorf("unknown sort column %q", column)
+	}
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"go-grafana/internal/domain/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// seekAfter orders the query by (created_at, id) and, when a cursor is given,
+// restricts it to the rows strictly after that position
+func seekAfter(query *gorm.DB, after *models.Cursor) *gorm.DB {
+	if after != nil {
+		query = query.Where("(created_at, id) > (?, ?)", after.CreatedAt.UTC(), after.ID)
+	}
+	return query.Order(clause.OrderByColumn{Column: clause.Column{Name: "created_at"}}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}})
+}
@@ -0,0 +1,255 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-grafana/internal/domain/models"
+	"go-grafana/internal/domain/repository"
+)
+
+// newAPIKey returns a valid API key that has not been stored yet
+func newAPIKey(key string) *models.APIKey {
+	return &models.APIKey{Name: "key " + key, Key: key, Scopes: models.Scopes{models.ScopeUsersRead}, Active: true}
+}
+
+// createAPIKeys stores the API keys in order and fails the test on the first error
+func createAPIKeys(t *testing.T, repo repository.APIKeyRepository, apiKeys ...*models.APIKey) {
+	t.Helper()
+	for _, apiKey := range apiKeys {
+		if err := repo.Create(context.Background(), apiKey); err != nil {
+			t.Fatalf("Create(%s) error = %v", apiKey.Key, err)
+		}
+	}
+}
+
+// RunAPIKeyRepositoryTests runs the APIKeyRepository conformance suite.
+// newRepo must return an empty repository every time it is called.
+func RunAPIKeyRepositoryTests(t *testing.T, newRepo func(t *testing.T) repository.APIKeyRepository) {
+	ctx := context.Background()
+
+	t.Run("create", func(t *testing.T) {
+		repo := newRepo(t)
+		first, second := newAPIKey("first"), newAPIKey("second")
+		second.Active = false
+		createAPIKeys(t, repo, first, second)
+
+		if first.ID == 0 || second.ID <= first.ID {
+			t.Errorf("expected increasing IDs, got %d and %d", first.ID, second.ID)
+		}
+		found, err := repo.GetByID(ctx, second.ID)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if !found.Active || found.UsageCount != 0 || !found.Scopes.Has(models.ScopeUsersRead) {
+			t.Errorf("unexpected API key %+v", found)
+		}
+
+		if err := repo.Create(ctx, &models.APIKey{Key: "unnamed"}); err == nil {
+			t.Error("expected an error for a key without a name")
+		}
+		if err := repo.Create(ctx, &models.APIKey{Name: "no key"}); err == nil {
+			t.Error("expected an error for a key without key material")
+		}
+	})
+
+	t.Run("duplicate key", func(t *testing.T) {
+		repo := newRepo(t)
+		existing := newAPIKey("taken")
+		createAPIKeys(t, repo, existing)
+
+		if err := repo.Create(ctx, newAPIKey("taken")); err != repository.ErrAPIKeyExists {
+			t.Errorf("expected %v, got %v", repository.ErrAPIKeyExists, err)
+		}
+
+		// Deleted keys keep their unique index entry
+		if err := repo.Delete(ctx, existing.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if !repo.ExistsByKey(ctx, "taken") {
+			t.Error("expected a deleted key to still exist")
+		}
+		if err := repo.Create(ctx, newAPIKey("taken")); !errors.Is(err, repository.ErrConflict) {
+			t.Errorf("expected a conflict for the key of a deleted API key, got %v", err)
+		}
+	})
+
+	t.Run("lookups", func(t *testing.T) {
+		repo := newRepo(t)
+		apiKey := newAPIKey("lookup")
+		createAPIKeys(t, repo, apiKey)
+
+		found, err := repo.GetByKey(ctx, "lookup")
+		if err != nil {
+			t.Fatalf("GetByKey() error = %v", err)
+		}
+		if found.ID != apiKey.ID {
+			t.Errorf("expected API key %d, got %d", apiKey.ID, found.ID)
+		}
+
+		if _, err := repo.GetByKey(ctx, "missing"); err != repository.ErrAPIKeyNotFound {
+			t.Errorf("expected %v, got %v", repository.ErrAPIKeyNotFound, err)
+		}
+		if _, err := repo.GetByKey(ctx, ""); err == nil || errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("expected a validation error for an empty key, got %v", err)
+		}
+		if _, err := repo.GetByID(ctx, 0); err != repository.ErrAPIKeyNotFound {
+			t.Errorf("expected %v, got %v", repository.ErrAPIKeyNotFound, err)
+		}
+		if repo.ExistsByKey(ctx, "") || repo.ExistsByKey(ctx, "missing") {
+			t.Error("expected unknown keys not to exist")
+		}
+	})
+
+	t.Run("update changes allowed fields only", func(t *testing.T) {
+		repo := newRepo(t)
+		apiKey := newAPIKey("update")
+		createAPIKeys(t, repo, apiKey)
+
+		rps := 5.0
+		update := &models.APIKey{ID: apiKey.ID, Name: "renamed", Key: "ignored", Scopes: models.Scopes{models.ScopeUsersWrite}, RateLimitRPS: &rps}
+		if err := repo.Update(ctx, update); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+		if update.Key != "update" || update.CreatedAt.IsZero() {
+			t.Errorf("expected the stored API key to be copied back, got %+v", update)
+		}
+
+		found, _ := repo.GetByID(ctx, apiKey.ID)
+		if found.Name != "renamed" || found.Key != "update" || found.Active || !found.Scopes.Has(models.ScopeUsersWrite) {
+			t.Errorf("unexpected API key after update %+v", found)
+		}
+		if found.RateLimitRPS == nil || *found.RateLimitRPS != 5 {
+			t.Errorf("expected the rate limit to be stored, got %v", found.RateLimitRPS)
+		}
+
+		if err := repo.Update(ctx, &models.APIKey{ID: apiKey.ID + 100, Name: "missing"}); err != repository.ErrAPIKeyNotFound {
+			t.Errorf("expected %v, got %v", repository.ErrAPIKeyNotFound, err)
+		}
+		if err := repo.Update(ctx, &models.APIKey{ID: apiKey.ID}); err == nil {
+			t.Error("expected an error for an update without a name")
+		}
+	})
+
+	t.Run("update key", func(t *testing.T) {
+		repo := newRepo(t)
+		apiKey, other := newAPIKey("old"), newAPIKey("other")
+		createAPIKeys(t, repo, apiKey, other)
+
+		graceUntil := time.Now().Add(time.Hour).UTC().Truncate(time.Microsecond)
+		apiKey.RotateKey("new", &graceUntil)
+		if err := repo.UpdateKey(ctx, apiKey); err != nil {
+			t.Fatalf("UpdateKey() error = %v", err)
+		}
+
+		found, err := repo.GetByPreviousKey(ctx, "old")
+		if err != nil {
+			t.Fatalf("GetByPreviousKey() error = %v", err)
+		}
+		if found.Key != "new" || found.GraceUntil == nil || !found.GraceUntil.Equal(graceUntil) {
+			t.Errorf("unexpected API key after rotation %+v", found)
+		}
+		if _, err := repo.GetByKey(ctx, "old"); err != repository.ErrAPIKeyNotFound {
+			t.Errorf("expected the old key to be replaced, got %v", err)
+		}
+
+		apiKey.RotateKey("other", nil)
+		if err := repo.UpdateKey(ctx, apiKey); err != repository.ErrAPIKeyExists {
+			t.Errorf("expected %v, got %v", repository.ErrAPIKeyExists, err)
+		}
+
+		missing := newAPIKey("missing")
+		missing.ID = other.ID + 100
+		if err := repo.UpdateKey(ctx, missing); err != repository.ErrAPIKeyNotFound {
+			t.Errorf("expected %v, got %v", repository.ErrAPIKeyNotFound, err)
+		}
+	})
+
+	t.Run("record usage", func(t *testing.T) {
+		repo := newRepo(t)
+		apiKey := newAPIKey("usage")
+		createAPIKeys(t, repo, apiKey)
+
+		usedAt := time.Now().UTC().Truncate(time.Microsecond)
+		usages := []models.APIKeyUsage{
+			{APIKeyID: apiKey.ID, Count: 3, LastUsedAt: usedAt, LastUsedIP: "203.0.113.7"},
+			{APIKeyID: apiKey.ID + 100, Count: 1, LastUsedAt: usedAt},
+			{APIKeyID: 0, Count: 1, LastUsedAt: usedAt},
+		}
+		if err := repo.RecordUsage(ctx, usages); err != nil {
+			t.Fatalf("RecordUsage() error = %v", err)
+		}
+		if err := repo.RecordUsage(ctx, usages[:1]); err != nil {
+			t.Fatalf("RecordUsage() error = %v", err)
+		}
+
+		found, _ := repo.GetByID(ctx, apiKey.ID)
+		if found.UsageCount != 6 || found.LastUsedIP != "203.0.113.7" || found.LastUsedAt == nil || !found.LastUsedAt.Equal(usedAt) {
+			t.Errorf("unexpected usage %+v", found)
+		}
+		if !found.UpdatedAt.Equal(apiKey.UpdatedAt) {
+			t.Errorf("expected recording usage not to touch updated_at, got %v want %v", found.UpdatedAt, apiKey.UpdatedAt)
+		}
+	})
+
+	t.Run("soft delete", func(t *testing.T) {
+		repo := newRepo(t)
+		apiKey := newAPIKey("delete")
+		createAPIKeys(t, repo, apiKey)
+
+		if err := repo.Delete(ctx, apiKey.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if _, err := repo.GetByID(ctx, apiKey.ID); err != repository.ErrAPIKeyNotFound {
+			t.Errorf("expected %v, got %v", repository.ErrAPIKeyNotFound, err)
+		}
+		if _, err := repo.GetByKey(ctx, "delete"); err != repository.ErrAPIKeyNotFound {
+			t.Errorf("expected %v, got %v", repository.ErrAPIKeyNotFound, err)
+		}
+		if err := repo.Delete(ctx, apiKey.ID); err != repository.ErrAPIKeyNotFound {
+			t.Errorf("expected a second delete to report %v, got %v", repository.ErrAPIKeyNotFound, err)
+		}
+		if err := repo.Delete(ctx, 0); err != repository.ErrAPIKeyNotFound {
+			t.Errorf("expected %v, got %v", repository.ErrAPIKeyNotFound, err)
+		}
+	})
+
+	t.Run("list after cursor", func(t *testing.T) {
+		repo := newRepo(t)
+		keys := []*models.APIKey{newAPIKey("a"), newAPIKey("b"), newAPIKey("c"), newAPIKey("d")}
+		createAPIKeys(t, repo, keys...)
+
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		usages := []models.APIKeyUsage{
+			{APIKeyID: keys[0].ID, Count: 1, LastUsedAt: now.Add(-48 * time.Hour)},
+			{APIKeyID: keys[1].ID, Count: 1, LastUsedAt: now},
+		}
+		if err := repo.RecordUsage(ctx, usages); err != nil {
+			t.Fatalf("RecordUsage() error = %v", err)
+		}
+		if err := repo.Delete(ctx, keys[3].ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+
+		unusedSince := now.Add(-24 * time.Hour)
+		var got []string
+		var after *models.Cursor
+		for page := 0; page < 5; page++ {
+			apiKeys, err := repo.ListAfter(ctx, &models.APIKeyFilter{UnusedSince: &unusedSince, Limit: 1}, after)
+			if err != nil {
+				t.Fatalf("ListAfter() error = %v", err)
+			}
+			if len(apiKeys) == 0 {
+				break
+			}
+			got = append(got, apiKeys[0].Key)
+			after = &models.Cursor{CreatedAt: apiKeys[0].CreatedAt, ID: apiKeys[0].ID}
+		}
+		if fmt.Sprint(got) != "[a c]" {
+			t.Errorf("expected the stale keys a and c, got %v", got)
+		}
+	})
+}
@@ -0,0 +1,283 @@
+// Package repositorytest holds the conformance suites that every repository implementation must pass,
+// so that the in-memory fakes keep behaving like the database backed repositories.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"go-grafana/internal/domain/models"
+	"go-grafana/internal/domain/repository"
+)
+
+// newUser returns a valid user that has not been stored yet
+func newUser(email string, age int) *models.User {
+	return &models.User{Email: email, FirstName: "Test", LastName: "User", Age: age, Active: true}
+}
+
+// createUsers stores the users in order and fails the test on the first error
+func createUsers(t *testing.T, repo repository.UserRepository, users ...*models.User) {
+	t.Helper()
+	for _, user := range users {
+		if err := repo.Create(context.Background(), user); err != nil {
+			t.Fatalf("Create(%s) error = %v", user.Email, err)
+		}
+	}
+}
+
+// RunUserRepositoryTests runs the UserRepository conformance suite.
+// newRepo must return an empty repository every time it is called.
+func RunUserRepositoryTests(t *testing.T, newRepo func(t *testing.T) repository.UserRepository) {
+	ctx := context.Background()
+
+	t.Run("create assigns IDs and timestamps", func(t *testing.T) {
+		repo := newRepo(t)
+		first, second := newUser("first@example.com", 30), newUser("second@example.com", 30)
+		createUsers(t, repo, first, second)
+
+		if first.ID == 0 || second.ID <= first.ID {
+			t.Errorf("expected increasing IDs, got %d and %d", first.ID, second.ID)
+		}
+		if first.CreatedAt.IsZero() || first.UpdatedAt.IsZero() {
+			t.Errorf("expected timestamps to be set, got %+v", first)
+		}
+	})
+
+	t.Run("create applies the active default", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("inactive@example.com", 30)
+		user.Active = false
+		createUsers(t, repo, user)
+
+		found, err := repo.GetByID(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if !user.Active || !found.Active {
+			t.Error("expected a zero Active to fall back to the column default of true")
+		}
+	})
+
+	t.Run("duplicate email", func(t *testing.T) {
+		repo := newRepo(t)
+		existing := newUser("taken@example.com", 30)
+		createUsers(t, repo, existing)
+
+		err := repo.Create(ctx, newUser("taken@example.com", 40))
+		if err != repository.ErrUserEmailExists {
+			t.Errorf("expected %v, got %v", repository.ErrUserEmailExists, err)
+		}
+
+		// The unique index also covers soft deleted users
+		if err := repo.Delete(ctx, existing.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if err := repo.Create(ctx, newUser("taken@example.com", 40)); !errors.Is(err, repository.ErrConflict) {
+			t.Errorf("expected a conflict for the email of a deleted user, got %v", err)
+		}
+	})
+
+	t.Run("get by id", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("get@example.com", 30)
+		createUsers(t, repo, user)
+
+		found, err := repo.GetByID(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if found.Email != user.Email || found.Age != user.Age || !found.CreatedAt.Equal(user.CreatedAt) {
+			t.Errorf("got %+v, want %+v", found, user)
+		}
+
+		// Returned users are copies
+		found.Age = 99
+		if again, _ := repo.GetByID(ctx, user.ID); again.Age != 30 {
+			t.Error("expected changes to a returned user not to be stored")
+		}
+
+		if _, err := repo.GetByID(ctx, user.ID+100); err != repository.ErrUserNotFound {
+			t.Errorf("expected %v, got %v", repository.ErrUserNotFound, err)
+		}
+	})
+
+	t.Run("get by email", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("lookup@example.com", 30)
+		createUsers(t, repo, user)
+
+		found, err := repo.GetByEmail(ctx, "lookup@example.com")
+		if err != nil {
+			t.Fatalf("GetByEmail() error = %v", err)
+		}
+		if found.ID != user.ID {
+			t.Errorf("expected user %d, got %d", user.ID, found.ID)
+		}
+
+		for _, email := range []string{"missing@example.com", ""} {
+			if _, err := repo.GetByEmail(ctx, email); err != repository.ErrUserNotFound {
+				t.Errorf("GetByEmail(%q): expected %v, got %v", email, repository.ErrUserNotFound, err)
+			}
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		repo := newRepo(t)
+		user, other := newUser("update@example.com", 30), newUser("other@example.com", 30)
+		createUsers(t, repo, user, other)
+
+		user.FirstName = "Changed"
+		user.Active = false
+		if err := repo.Update(ctx, user); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+		found, _ := repo.GetByID(ctx, user.ID)
+		if found.FirstName != "Changed" || found.Active {
+			t.Errorf("expected the update to be stored, got %+v", found)
+		}
+		if found.UpdatedAt.Before(found.CreatedAt) {
+			t.Errorf("expected updated_at %v not to be before created_at %v", found.UpdatedAt, found.CreatedAt)
+		}
+
+		user.Email = other.Email
+		if err := repo.Update(ctx, user); err != repository.ErrUserEmailExists {
+			t.Errorf("expected %v, got %v", repository.ErrUserEmailExists, err)
+		}
+
+		missing := newUser("missing@example.com", 30)
+		missing.ID = other.ID + 100
+		if err := repo.Update(ctx, missing); err != repository.ErrUserNotFound {
+			t.Errorf("expected %v, got %v", repository.ErrUserNotFound, err)
+		}
+		if _, err := repo.GetByEmail(ctx, missing.Email); err != repository.ErrUserNotFound {
+			t.Error("expected updating a missing user not to insert it")
+		}
+	})
+
+	t.Run("soft delete", func(t *testing.T) {
+		repo := newRepo(t)
+		user, kept := newUser("delete@example.com", 30), newUser("kept@example.com", 30)
+		createUsers(t, repo, user, kept)
+
+		if err := repo.Delete(ctx, user.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if _, err := repo.GetByID(ctx, user.ID); err != repository.ErrUserNotFound {
+			t.Errorf("expected %v, got %v", repository.ErrUserNotFound, err)
+		}
+		if _, err := repo.GetByEmail(ctx, user.Email); err != repository.ErrUserNotFound {
+			t.Errorf("expected %v, got %v", repository.ErrUserNotFound, err)
+		}
+		if err := repo.Delete(ctx, user.ID); err != repository.ErrUserNotFound {
+			t.Errorf("expected a second delete to report %v, got %v", repository.ErrUserNotFound, err)
+		}
+		if err := repo.Update(ctx, user); err != repository.ErrUserNotFound {
+			t.Errorf("expected updating a deleted user to report %v, got %v", repository.ErrUserNotFound, err)
+		}
+
+		count, err := repo.Count(ctx)
+		if err != nil {
+			t.Fatalf("Count() error = %v", err)
+		}
+		if count != 1 {
+			t.Errorf("expected deleted users not to be counted, got %d", count)
+		}
+	})
+
+	t.Run("list filters", func(t *testing.T) {
+		repo := newRepo(t)
+		inactive := newUser("carol@example.com", 40)
+		createUsers(t, repo,
+			newUser("alice@example.com", 25),
+			newUser("bob@EXAMPLE.com", 35),
+			inactive,
+			newUser("dave_100%@example.org", 50),
+		)
+		inactive.Active = false
+		if err := repo.Update(ctx, inactive); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+
+		active, minAge, maxAge := true, 30, 45
+		tests := []struct {
+			name   string
+			filter models.UserFilter
+			want   []string
+		}{
+			{"active", models.UserFilter{Active: &active}, []string{"alice@example.com", "bob@EXAMPLE.com", "dave_100%@example.org"}},
+			{"email is case insensitive", models.UserFilter{EmailContains: "example.com"}, []string{"alice@example.com", "bob@EXAMPLE.com", "carol@example.com"}},
+			{"email wildcards are literal", models.UserFilter{EmailContains: "_100%"}, []string{"dave_100%@example.org"}},
+			{"age range", models.UserFilter{MinAge: &minAge, MaxAge: &maxAge}, []string{"bob@EXAMPLE.com", "carol@example.com"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.filter.Limit = 10
+				users, total, err := repo.List(ctx, &tt.filter)
+				if err != nil {
+					t.Fatalf("List() error = %v", err)
+				}
+				if got := emails(users); fmt.Sprint(got) != fmt.Sprint(tt.want) || total != int64(len(tt.want)) {
+					t.Errorf("got %v (total %d), want %v", got, total, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("list sorts and pages", func(t *testing.T) {
+		repo := newRepo(t)
+		createUsers(t, repo,
+			newUser("a@example.com", 30),
+			newUser("b@example.com", 20),
+			newUser("c@example.com", 30),
+			newUser("d@example.com", 40),
+		)
+
+		// Ties on age are broken by ID
+		filter := &models.UserFilter{Sort: []models.SortField{{Field: "age", Desc: true}}, Limit: 2, Offset: 1}
+		users, total, err := repo.List(ctx, filter)
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		if got := emails(users); fmt.Sprint(got) != "[a@example.com c@example.com]" || total != 4 {
+			t.Errorf("got %v (total %d)", got, total)
+		}
+	})
+
+	t.Run("list after cursor", func(t *testing.T) {
+		repo := newRepo(t)
+		deleted := newUser("c@example.com", 30)
+		createUsers(t, repo, newUser("a@example.com", 30), newUser("b@example.com", 30), deleted, newUser("d@example.com", 30))
+		if err := repo.Delete(ctx, deleted.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+
+		var got []string
+		var after *models.Cursor
+		for page := 0; page < 5; page++ {
+			users, err := repo.ListAfter(ctx, &models.UserFilter{Limit: 2}, after)
+			if err != nil {
+				t.Fatalf("ListAfter() error = %v", err)
+			}
+			if len(users) == 0 {
+				break
+			}
+			got = append(got, emails(users)...)
+			last := users[len(users)-1]
+			after = &models.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		}
+		if fmt.Sprint(got) != "[a@example.com b@example.com d@example.com]" {
+			t.Errorf("unexpected users %v", got)
+		}
+	})
+}
+
+// emails returns the email of every user, in order
+func emails(users []models.User) []string {
+	result := make([]string, len(users))
+	for i, user := range users {
+		result[i] = user.Email
+	}
+	return result
+}
@@ -0,0 +1,79 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-grafana/internal/config"
+	"go-grafana/internal/domain/models"
+	"go-grafana/internal/domain/repository"
+	"go-grafana/internal/domain/repository/repositorytest"
+	"go-grafana/pkg/database"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// newSQLiteDB opens a private in-memory SQLite database with the schema in place
+func newSQLiteDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	cfg := &config.Config{Database: config.DatabaseConfig{
+		Driver:           config.DriverSQLite,
+		SQLitePath:       ":memory:",
+		StatementTimeout: 5 * time.Second,
+	}}
+
+	db, err := database.NewDB(cfg, zap.NewNop(), noop.NewTracerProvider(), prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+	t.Cleanup(func() { database.CloseDB(db, zap.NewNop()) })
+	return db
+}
+
+func TestUserRepository_SQLite(t *testing.T) {
+	repositorytest.RunUserRepositoryTests(t, func(t *testing.T) repository.UserRepository {
+		return repository.NewUserRepository(newSQLiteDB(t))
+	})
+}
+
+func TestAPIKeyRepository_SQLite(t *testing.T) {
+	repositorytest.RunAPIKeyRepositoryTests(t, func(t *testing.T) repository.APIKeyRepository {
+		return repository.NewAPIKeyRepository(newSQLiteDB(t))
+	})
+}
+
+func TestTransactor_SQLite(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteDB(t)
+	transactor := repository.NewTransactor(db)
+	users := repository.NewUserRepository(db)
+
+	errAbort := errors.New("abort")
+	err := transactor.WithinTx(ctx, func(repos repository.Repositories) error {
+		if err := repos.Users.Create(ctx, &models.User{Email: "rollback@example.com", FirstName: "Test", LastName: "User", Age: 30}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if err != errAbort {
+		t.Fatalf("expected %v, got %v", errAbort, err)
+	}
+	if _, err := users.GetByEmail(ctx, "rollback@example.com"); err != repository.ErrUserNotFound {
+		t.Errorf("expected the insert to be rolled back, got %v", err)
+	}
+
+	err = transactor.WithinTx(ctx, func(repos repository.Repositories) error {
+		return repos.Users.Create(ctx, &models.User{Email: "commit@example.com", FirstName: "Test", LastName: "User", Age: 30})
+	})
+	if err != nil {
+		t.Fatalf("WithinTx() error = %v", err)
+	}
+	if _, err := users.GetByEmail(ctx, "commit@example.com"); err != nil {
+		t.Errorf("expected the insert to be committed, got %v", err)
+	}
+}
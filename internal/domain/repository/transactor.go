@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Repositories groups the repositories that take part in a unit of work
+type Repositories struct {
+	Users   UserRepository
+	APIKeys APIKeyRepository
+}
+
+// Transactor runs multi-step operations atomically
+type Transactor interface {
+	// WithinTx calls fn with repositories bound to a single database transaction.
+	// The transaction is committed if fn returns nil and rolled back otherwise.
+	WithinTx(ctx context.Context, fn func(repos Repositories) error) error
+}
+
+// gormTransactor implements Transactor on top of GORM transactions
+type gormTransactor struct {
+	db *gorm.DB
+}
+
+// NewTransactor creates a new instance of Transactor
+func NewTransactor(db *gorm.DB) Transactor {
+	return &gormTransactor{
+		db: db,
+	}
+}
+
+// WithinTx runs fn in a database transaction. Calls nested inside fn use savepoints.
+func (t *gormTransactor) WithinTx(ctx context.Context, fn func(repos Repositories) error) error {
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(Repositories{
+			Users:   NewUserRepository(tx),
+			APIKeys: NewAPIKeyRepository(tx),
+		})
+	})
+}
@@ -1,22 +1,26 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"strings"
 
 	"go-grafana/internal/domain/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // UserRepository defines the interface for user data operations
 type UserRepository interface {
-	Create(user *models.User) error
-	GetByID(id uint) (*models.User, error)
-	GetAll() ([]models.User, error)
-	Update(user *models.User) error
-	Delete(id uint) error
-	GetByEmail(email string) (*models.User, error)
-	Count() (int64, error)
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+	List(ctx context.Context, filter *models.UserFilter) ([]models.User, int64, error)
+	ListAfter(ctx context.Context, filter *models.UserFilter, after *models.Cursor) ([]models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	Delete(ctx context.Context, id uint) error
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	Count(ctx context.Context) (int64, error)
 }
 
 // userRepository implements UserRepository interface
@@ -32,65 +36,107 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 }
 
 // Create creates a new user in the database
-func (r *userRepository) Create(user *models.User) error {
-	result := r.db.Create(user)
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	result := r.db.WithContext(ctx).Create(user)
 	if result.Error != nil {
+		if isUniqueViolation(result.Error) {
+			return ErrUserEmailExists
+		}
 		return result.Error
 	}
 	return nil
 }
 
 // GetByID retrieves a user by their ID
-func (r *userRepository) GetByID(id uint) (*models.User, error) {
+func (r *userRepository) GetByID(ctx context.Context, id uint) (*models.User, error) {
 	var user models.User
-	result := r.db.First(&user, id)
+	result := r.db.WithContext(ctx).First(&user, id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, result.Error
 	}
 	return &user, nil
 }
 
-// GetAll retrieves all users from the database
-func (r *userRepository) GetAll() ([]models.User, error) {
+// List retrieves a page of users matching the filter along with the total number of matches
+func (r *userRepository) List(ctx context.Context, filter *models.UserFilter) ([]models.User, int64, error) {
+	var total int64
+	result := applyUserFilter(r.db.WithContext(ctx).Model(&models.User{}), filter).Count(&total)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	query := applyUserFilter(r.db.WithContext(ctx).Model(&models.User{}), filter)
+	for _, sort := range filter.Sort {
+		query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: sort.Field}, Desc: sort.Desc})
+	}
+	// Always break ties on the primary key so pages are deterministic
+	query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}})
+
+	var users []models.User
+	result = query.Limit(filter.Limit).Offset(filter.Offset).Find(&users)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	return users, total, nil
+}
+
+// ListAfter retrieves up to filter.Limit users matching the filter that come strictly after
+// the cursor in (created_at, id) order. A nil cursor starts from the beginning.
+// Sort and Offset are ignored so that iteration stays stable under concurrent inserts.
+func (r *userRepository) ListAfter(ctx context.Context, filter *models.UserFilter, after *models.Cursor) ([]models.User, error) {
+	query := applyUserFilter(r.db.WithContext(ctx).Model(&models.User{}), filter)
+	query = seekAfter(query, after)
+
 	var users []models.User
-	result := r.db.Find(&users)
+	result := query.Limit(filter.Limit).Find(&users)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return users, nil
 }
 
-// Update updates an existing user in the database
-func (r *userRepository) Update(user *models.User) error {
-	result := r.db.Save(user)
+// Update writes every field of an existing user to the database.
+// Unlike Save it never inserts, so a missing or deleted user is reported as not found.
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	if user.ID == 0 {
+		return ErrUserNotFound
+	}
+
+	result := r.db.WithContext(ctx).Model(user).Select("*").Updates(user)
 	if result.Error != nil {
+		if isUniqueViolation(result.Error) {
+			return ErrUserEmailExists
+		}
 		return result.Error
 	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
 	return nil
 }
 
 // Delete removes a user from the database by ID
-func (r *userRepository) Delete(id uint) error {
-	result := r.db.Delete(&models.User{}, id)
+func (r *userRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.User{}, id)
 	if result.Error != nil {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("user not found")
+		return ErrUserNotFound
 	}
 	return nil
 }
 
 // GetByEmail retrieves a user by their email address
-func (r *userRepository) GetByEmail(email string) (*models.User, error) {
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	result := r.db.Where("email = ?", email).First(&user)
+	result := r.db.WithContext(ctx).Where(map[string]interface{}{"email": email}).First(&user)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, result.Error
 	}
@@ -98,11 +144,40 @@ func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 }
 
 // Count returns the total number of users in the database
-func (r *userRepository) Count() (int64, error) {
+func (r *userRepository) Count(ctx context.Context) (int64, error) {
 	var count int64
-	result := r.db.Model(&models.User{}).Count(&count)
+	result := r.db.WithContext(ctx).Model(&models.User{}).Count(&count)
 	if result.Error != nil {
 		return 0, result.Error
 	}
 	return count, nil
 }
+
+// applyUserFilter adds the WHERE conditions described by the filter to the query.
+// Times are passed in UTC because SQLite compares them as text.
+func applyUserFilter(query *gorm.DB, filter *models.UserFilter) *gorm.DB {
+	if filter.Active != nil {
+		query = query.Where("active = ?", *filter.Active)
+	}
+	if filter.EmailContains != "" {
+		query = query.Where(`LOWER(email) LIKE ? ESCAPE '\'`, "%"+escapeLike(strings.ToLower(filter.EmailContains))+"%")
+	}
+	if filter.MinAge != nil {
+		query = query.Where("age >= ?", *filter.MinAge)
+	}
+	if filter.MaxAge != nil {
+		query = query.Where("age <= ?", *filter.MaxAge)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", filter.CreatedAfter.UTC())
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", filter.CreatedBefore.UTC())
+	}
+	return query
+}
+
+// escapeLike escapes the LIKE wildcard characters in a user supplied pattern
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	"go-grafana/internal/problem"
+	"go-grafana/internal/reload"
+	"go-grafana/internal/requestctx"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LogLevel is the body of the log level admin endpoints
+type LogLevel struct {
+	Level string `json:"level" binding:"required" example:"debug"`
+}
+
+// AdminHandler serves the runtime debugging endpoints of the admin listener
+type AdminHandler struct {
+	level    zap.AtomicLevel
+	reloader *reload.Reloader
+	logger   *zap.Logger
+}
+
+// NewAdminHandler creates a new instance of AdminHandler
+func NewAdminHandler(level zap.AtomicLevel, reloader *reload.Reloader, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{
+		level:    level,
+		reloader: reloader,
+		logger:   logger,
+	}
+}
+
+// GetLogLevel returns the current log level
+func (h *AdminHandler) GetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, LogLevel{Level: h.level.String()})
+}
+
+// SetLogLevel changes the log level until the next restart or configuration reload
+func (h *AdminHandler) SetLogLevel(c *gin.Context) {
+	var req LogLevel
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithBindingError(c, err)
+		return
+	}
+
+	level, err := zapcore.ParseLevel(req.Level)
+	if err != nil {
+		p := problem.New(problem.Validation, err.Error())
+		p.Errors = []problem.FieldError{{Field: "level", Code: "oneof", Message: "level must be one of debug, info, warn, error, dpanic, panic or fatal"}}
+		problem.Respond(c, p)
+		return
+	}
+
+	previous := h.level.Level()
+	h.level.SetLevel(level)
+	requestctx.Logger(c.Request.Context(), h.logger).Warn("Log level changed",
+		zap.Stringer("previous", previous),
+		zap.Stringer("level", level),
+	)
+	c.JSON(http.StatusOK, LogLevel{Level: level.String()})
+}
+
+// GetConfig returns the effective configuration with its secrets masked, in the YAML format of
+// "server config print" so that it can be used as a config file
+func (h *AdminHandler) GetConfig(c *gin.Context) {
+	c.YAML(http.StatusOK, h.reloader.Current().Redacted())
+}
+
+// Profile serves the named runtime profile, such as heap or goroutine. The pprof index serves them only
+// under /debug/pprof/, so this is needed to serve them under another prefix.
+func (h *AdminHandler) Profile(c *gin.Context) {
+	pprof.Handler(c.Param("profile")).ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-grafana/internal/config"
+	"go-grafana/internal/reload"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+func newTestAdminRouter(level zap.AtomicLevel, cfg *config.Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	reloader := reload.NewReloader(config.Sources{}, cfg, prometheus.NewRegistry(), zap.NewNop())
+	handler := NewAdminHandler(level, reloader, zap.NewNop())
+
+	router := gin.New()
+	router.GET("/admin/loglevel", handler.GetLogLevel)
+	router.PUT("/admin/loglevel", handler.SetLogLevel)
+	router.GET("/admin/config", handler.GetConfig)
+	router.GET("/admin/debug/pprof/:profile", handler.Profile)
+	return router
+}
+
+func TestAdminHandler_LogLevel(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zap.InfoLevel)
+	router := newTestAdminRouter(level, config.NewConfig())
+
+	do := func(method, body string) (int, LogLevel) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/admin/loglevel", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		var response LogLevel
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	if code, response := do(http.MethodGet, ""); code != http.StatusOK || response.Level != "info" {
+		t.Fatalf("expected 200 info, got %d %+v", code, response)
+	}
+
+	if code, response := do(http.MethodPut, `{"level":"debug"}`); code != http.StatusOK || response.Level != "debug" {
+		t.Fatalf("expected 200 debug, got %d %+v", code, response)
+	}
+	if !level.Enabled(zap.DebugLevel) {
+		t.Error("expected the logger level to be changed")
+	}
+
+	for _, body := range []string{`{"level":"verbose"}`, `{}`} {
+		if code, _ := do(http.MethodPut, body); code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", body, http.StatusBadRequest, code)
+		}
+	}
+	if level.Level() != zap.DebugLevel {
+		t.Errorf("expected invalid requests to leave the level unchanged, got %s", level.Level())
+	}
+}
+
+func TestAdminHandler_GetConfig(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.APIKeys.BootstrapKey = "sk-bootstrap"
+	router := newTestAdminRouter(zap.NewAtomicLevel(), cfg)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/admin/config", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	body := w.Body.String()
+	if strings.Contains(body, "sk-bootstrap") || !strings.Contains(body, "bootstrap_key: REDACTED") {
+		t.Errorf("expected the secrets to be masked, got %s", body)
+	}
+	if !strings.Contains(body, "shutdown_timeout: 30s") {
+		t.Errorf("expected durations in config file format, got %s", body)
+	}
+}
+
+func TestAdminHandler_Profile(t *testing.T) {
+	router := newTestAdminRouter(zap.NewAtomicLevel(), config.NewConfig())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/admin/debug/pprof/goroutine?debug=1", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "goroutine profile") {
+		t.Errorf("expected the goroutine profile, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
 	"go-grafana/internal/domain/models"
+	"go-grafana/internal/requestctx"
 	"go-grafana/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -34,69 +37,69 @@ func NewAPIKeyHandler(apiKeyService service.APIKeyService, logger *zap.Logger) *
 // @Param api_key body models.CreateAPIKeyRequest true "API key information"
 // @Param X-API-Key header string true "API Key" default(sk-1234567890abcdef)
 // @Success 201 {object} models.APIKeyResponse
-// @Failure 400 {object} ErrorResponse
-// @Failure 401 {object} ErrorResponse
-// @Failure 409 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /api-keys [post]
 func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
 	var req models.CreateAPIKeyRequest
 
 	// Bind and validate request
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Error("Failed to bind create API key request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid request body",
-			Message: err.Error(),
-		})
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Failed to bind create API key request", zap.Error(err))
+		respondWithBindingError(c, err)
 		return
 	}
 
 	// Create API key
-	apiKey, err := h.apiKeyService.CreateAPIKey(&req)
+	apiKey, err := h.apiKeyService.CreateAPIKey(c.Request.Context(), &req)
 	if err != nil {
-		h.logger.Error("Failed to create API key", zap.Error(err), zap.String("name", req.Name))
-
-		status := http.StatusInternalServerError
-		if err.Error() == "API key already exists" {
-			status = http.StatusConflict
-		} else if err.Error() == "name is required" {
-			status = http.StatusBadRequest
-		}
-
-		c.JSON(status, ErrorResponse{
-			Error:   "Failed to create API key",
-			Message: err.Error(),
-		})
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Failed to create API key", zap.Error(err), zap.String("name", req.Name))
+		respondWithError(c, h.logger, err)
 		return
 	}
 
-	h.logger.Info("API key created successfully", zap.Uint("api_key_id", apiKey.ID), zap.String("name", apiKey.Name))
+	requestctx.Logger(c.Request.Context(), h.logger).Info("API key created successfully", zap.Uint("api_key_id", apiKey.ID), zap.String("name", apiKey.Name))
 	c.JSON(http.StatusCreated, apiKey)
 }
 
 // GetAPIKeys godoc
-// @Summary Get all API keys
-// @Description Retrieve a list of all API keys (keys are masked for security)
+// @Summary List API keys
+// @Description Retrieve a page of API keys ordered by creation time, including usage statistics (keys are masked for security)
 // @Tags api-keys
 // @Produce json
 // @Param X-API-Key header string true "API Key" default(sk-1234567890abcdef)
-// @Success 200 {array} models.APIKeyResponse
-// @Failure 401 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Param limit query int false "Page size" minimum(1) maximum(100)
+// @Param cursor query string false "Opaque cursor returned in pagination.next_cursor"
+// @Param unused_since query string false "Only keys never used or not used since this time (RFC3339)"
+// @Success 200 {object} models.APIKeyListResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /api-keys [get]
 func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
-	apiKeys, err := h.apiKeyService.GetAllAPIKeys()
+	var req models.ListAPIKeysRequest
+
+	// Bind and validate query parameters
+	if err := c.ShouldBindQuery(&req); err != nil {
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Failed to bind list API keys query", zap.Error(err))
+		respondWithBindingError(c, err)
+		return
+	}
+
+	apiKeys, err := h.apiKeyService.ListAPIKeys(c.Request.Context(), &req)
 	if err != nil {
-		h.logger.Error("Failed to get API keys", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error:   "Failed to retrieve API keys",
-			Message: err.Error(),
-		})
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Failed to get API keys", zap.Error(err))
+		respondWithError(c, h.logger, err)
 		return
 	}
 
-	h.logger.Info("API keys retrieved successfully", zap.Int("count", len(apiKeys)))
+	apiKeys.Links = buildPaginationLinks(c.Request.URL, apiKeys.Pagination)
+
+	requestctx.Logger(c.Request.Context(), h.logger).Info("API keys retrieved successfully", zap.Int("count", len(apiKeys.Data)))
 	c.JSON(http.StatusOK, apiKeys)
 }
 
@@ -108,41 +111,30 @@ func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
 // @Param id path int true "API Key ID"
 // @Param X-API-Key header string true "API Key" default(sk-1234567890abcdef)
 // @Success 200 {object} models.APIKeyResponse
-// @Failure 400 {object} ErrorResponse
-// @Failure 401 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /api-keys/{id} [get]
 func (h *APIKeyHandler) GetAPIKeyByID(c *gin.Context) {
 	// Parse API key ID from URL parameter
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		h.logger.Error("Invalid API key ID", zap.String("id", idStr), zap.Error(err))
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid API key ID",
-			Message: "API key ID must be a valid integer",
-		})
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Invalid API key ID", zap.String("id", idStr), zap.Error(err))
+		respondWithInvalidID(c, "API key ID must be a valid integer")
 		return
 	}
 
-	apiKey, err := h.apiKeyService.GetAPIKeyByID(uint(id))
+	apiKey, err := h.apiKeyService.GetAPIKeyByID(c.Request.Context(), uint(id))
 	if err != nil {
-		h.logger.Error("Failed to get API key by ID", zap.Uint64("id", id), zap.Error(err))
-
-		status := http.StatusInternalServerError
-		if err.Error() == "API key not found" || err.Error() == "invalid API key ID" {
-			status = http.StatusNotFound
-		}
-
-		c.JSON(status, ErrorResponse{
-			Error:   "Failed to retrieve API key",
-			Message: err.Error(),
-		})
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Failed to get API key by ID", zap.Uint64("id", id), zap.Error(err))
+		respondWithError(c, h.logger, err)
 		return
 	}
 
-	h.logger.Info("API key retrieved successfully", zap.Uint("api_key_id", apiKey.ID))
+	requestctx.Logger(c.Request.Context(), h.logger).Info("API key retrieved successfully", zap.Uint("api_key_id", apiKey.ID))
 	c.JSON(http.StatusOK, apiKey)
 }
 
@@ -156,21 +148,19 @@ func (h *APIKeyHandler) GetAPIKeyByID(c *gin.Context) {
 // @Param api_key body models.UpdateAPIKeyRequest true "Updated API key information"
 // @Param X-API-Key header string true "API Key" default(sk-1234567890abcdef)
 // @Success 200 {object} models.APIKeyResponse
-// @Failure 400 {object} ErrorResponse
-// @Failure 401 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /api-keys/{id} [put]
 func (h *APIKeyHandler) UpdateAPIKey(c *gin.Context) {
 	// Parse API key ID from URL parameter
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		h.logger.Error("Invalid API key ID", zap.String("id", idStr), zap.Error(err))
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid API key ID",
-			Message: "API key ID must be a valid integer",
-		})
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Invalid API key ID", zap.String("id", idStr), zap.Error(err))
+		respondWithInvalidID(c, "API key ID must be a valid integer")
 		return
 	}
 
@@ -178,34 +168,69 @@ func (h *APIKeyHandler) UpdateAPIKey(c *gin.Context) {
 
 	// Bind and validate request
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Error("Failed to bind update API key request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid request body",
-			Message: err.Error(),
-		})
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Failed to bind update API key request", zap.Error(err))
+		respondWithBindingError(c, err)
 		return
 	}
 
 	// Update API key
-	apiKey, err := h.apiKeyService.UpdateAPIKey(uint(id), &req)
+	apiKey, err := h.apiKeyService.UpdateAPIKey(c.Request.Context(), uint(id), &req)
 	if err != nil {
-		h.logger.Error("Failed to update API key", zap.Uint64("id", id), zap.Error(err))
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Failed to update API key", zap.Uint64("id", id), zap.Error(err))
+		respondWithError(c, h.logger, err)
+		return
+	}
 
-		status := http.StatusInternalServerError
-		if err.Error() == "API key not found" || err.Error() == "invalid API key ID" {
-			status = http.StatusNotFound
-		} else if err.Error() == "name is required" {
-			status = http.StatusBadRequest
+	requestctx.Logger(c.Request.Context(), h.logger).Info("API key updated successfully", zap.Uint("api_key_id", apiKey.ID))
+	c.JSON(http.StatusOK, apiKey)
+}
+
+// RotateAPIKey godoc
+// @Summary Rotate API key
+// @Description Generate a new secret for an existing API key. The new key is returned once; the previous key keeps working until grace_until.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param id path int true "API Key ID"
+// @Param rotation body models.RotateAPIKeyRequest false "Rotation options"
+// @Param X-API-Key header string true "API Key" default(sk-1234567890abcdef)
+// @Success 200 {object} models.APIKeyResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api-keys/{id}/rotate [post]
+func (h *APIKeyHandler) RotateAPIKey(c *gin.Context) {
+	// Parse API key ID from URL parameter
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Invalid API key ID", zap.String("id", idStr), zap.Error(err))
+		respondWithInvalidID(c, "API key ID must be a valid integer")
+		return
+	}
+
+	var req models.RotateAPIKeyRequest
+
+	// The request body is optional
+	if c.Request.Body != nil && c.Request.Body != http.NoBody {
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			requestctx.Logger(c.Request.Context(), h.logger).Error("Failed to bind rotate API key request", zap.Error(err))
+			respondWithBindingError(c, err)
+			return
 		}
+	}
 
-		c.JSON(status, ErrorResponse{
-			Error:   "Failed to update API key",
-			Message: err.Error(),
-		})
+	// Rotate API key
+	apiKey, err := h.apiKeyService.RotateAPIKey(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Failed to rotate API key", zap.Uint64("id", id), zap.Error(err))
+		respondWithError(c, h.logger, err)
 		return
 	}
 
-	h.logger.Info("API key updated successfully", zap.Uint("api_key_id", apiKey.ID))
+	requestctx.Logger(c.Request.Context(), h.logger).Info("API key rotated successfully", zap.Uint("api_key_id", apiKey.ID))
 	c.JSON(http.StatusOK, apiKey)
 }
 
@@ -217,41 +242,30 @@ func (h *APIKeyHandler) UpdateAPIKey(c *gin.Context) {
 // @Param id path int true "API Key ID"
 // @Param X-API-Key header string true "API Key" default(sk-1234567890abcdef)
 // @Success 204 "No Content"
-// @Failure 400 {object} ErrorResponse
-// @Failure 401 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /api-keys/{id} [delete]
 func (h *APIKeyHandler) DeleteAPIKey(c *gin.Context) {
 	// Parse API key ID from URL parameter
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		h.logger.Error("Invalid API key ID", zap.String("id", idStr), zap.Error(err))
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid API key ID",
-			Message: "API key ID must be a valid integer",
-		})
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Invalid API key ID", zap.String("id", idStr), zap.Error(err))
+		respondWithInvalidID(c, "API key ID must be a valid integer")
 		return
 	}
 
 	// Delete API key
-	err = h.apiKeyService.DeleteAPIKey(uint(id))
+	err = h.apiKeyService.DeleteAPIKey(c.Request.Context(), uint(id))
 	if err != nil {
-		h.logger.Error("Failed to delete API key", zap.Uint64("id", id), zap.Error(err))
-
-		status := http.StatusInternalServerError
-		if err.Error() == "API key not found" || err.Error() == "invalid API key ID" {
-			status = http.StatusNotFound
-		}
-
-		c.JSON(status, ErrorResponse{
-			Error:   "Failed to delete API key",
-			Message: err.Error(),
-		})
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Failed to delete API key", zap.Uint64("id", id), zap.Error(err))
+		respondWithError(c, h.logger, err)
 		return
 	}
 
-	h.logger.Info("API key deleted successfully", zap.Uint64("id", id))
+	requestctx.Logger(c.Request.Context(), h.logger).Info("API key deleted successfully", zap.Uint64("id", id))
 	c.Status(http.StatusNoContent)
 }
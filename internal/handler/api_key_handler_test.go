@@ -2,6 +2,7 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -9,6 +10,8 @@ import (
 	"testing"
 
 	"go-grafana/internal/domain/models"
+	"go-grafana/internal/domain/repository"
+	"go-grafana/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -18,28 +21,40 @@ import (
 type MockAPIKeyService struct {
 	CreateAPIKeyFunc   func(req *models.CreateAPIKeyRequest) (*models.APIKeyResponse, error)
 	GetAPIKeyByIDFunc  func(id uint) (*models.APIKeyResponse, error)
-	GetAllAPIKeysFunc  func() ([]*models.APIKeyResponse, error)
+	ListAPIKeysFunc    func(req *models.ListAPIKeysRequest) (*models.APIKeyListResponse, error)
 	UpdateAPIKeyFunc   func(id uint, req *models.UpdateAPIKeyRequest) (*models.APIKeyResponse, error)
 	DeleteAPIKeyFunc   func(id uint) error
+	RevokeAPIKeyFunc   func(id uint) (*models.APIKeyResponse, error)
+	RotateAPIKeyFunc   func(id uint, req *models.RotateAPIKeyRequest) (*models.APIKeyResponse, error)
+	EnsureAPIKeyFunc   func(plainTextKey, name string, scopes []string) (bool, error)
 	ValidateAPIKeyFunc func(key string) (*models.APIKey, error)
 }
 
-func (m *MockAPIKeyService) CreateAPIKey(req *models.CreateAPIKeyRequest) (*models.APIKeyResponse, error) {
+func (m *MockAPIKeyService) CreateAPIKey(ctx context.Context, req *models.CreateAPIKeyRequest) (*models.APIKeyResponse, error) {
 	return m.CreateAPIKeyFunc(req)
 }
-func (m *MockAPIKeyService) GetAPIKeyByID(id uint) (*models.APIKeyResponse, error) {
+func (m *MockAPIKeyService) GetAPIKeyByID(ctx context.Context, id uint) (*models.APIKeyResponse, error) {
 	return m.GetAPIKeyByIDFunc(id)
 }
-func (m *MockAPIKeyService) GetAllAPIKeys() ([]*models.APIKeyResponse, error) {
-	return m.GetAllAPIKeysFunc()
+func (m *MockAPIKeyService) ListAPIKeys(ctx context.Context, req *models.ListAPIKeysRequest) (*models.APIKeyListResponse, error) {
+	return m.ListAPIKeysFunc(req)
 }
-func (m *MockAPIKeyService) UpdateAPIKey(id uint, req *models.UpdateAPIKeyRequest) (*models.APIKeyResponse, error) {
+func (m *MockAPIKeyService) UpdateAPIKey(ctx context.Context, id uint, req *models.UpdateAPIKeyRequest) (*models.APIKeyResponse, error) {
 	return m.UpdateAPIKeyFunc(id, req)
 }
-func (m *MockAPIKeyService) DeleteAPIKey(id uint) error {
+func (m *MockAPIKeyService) DeleteAPIKey(ctx context.Context, id uint) error {
 	return m.DeleteAPIKeyFunc(id)
 }
-func (m *MockAPIKeyService) ValidateAPIKey(key string) (*models.APIKey, error) {
+func (m *MockAPIKeyService) RevokeAPIKey(ctx context.Context, id uint) (*models.APIKeyResponse, error) {
+	return m.RevokeAPIKeyFunc(id)
+}
+func (m *MockAPIKeyService) RotateAPIKey(ctx context.Context, id uint, req *models.RotateAPIKeyRequest) (*models.APIKeyResponse, error) {
+	return m.RotateAPIKeyFunc(id, req)
+}
+func (m *MockAPIKeyService) EnsureAPIKey(ctx context.Context, plainTextKey, name string, scopes []string) (bool, error) {
+	return m.EnsureAPIKeyFunc(plainTextKey, name, scopes)
+}
+func (m *MockAPIKeyService) ValidateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
 	return m.ValidateAPIKeyFunc(key)
 }
 
@@ -117,21 +132,41 @@ func TestAPIKeyHandler_GetAPIKeys(t *testing.T) {
 	router.GET("/api-keys", handler.GetAPIKeys)
 
 	t.Run("success", func(t *testing.T) {
-		mockService.GetAllAPIKeysFunc = func() ([]*models.APIKeyResponse, error) {
-			return []*models.APIKeyResponse{{ID: 1, Name: "key1"}, {ID: 2, Name: "key2"}}, nil
+		mockService.ListAPIKeysFunc = func(req *models.ListAPIKeysRequest) (*models.APIKeyListResponse, error) {
+			return &models.APIKeyListResponse{
+				Data:       []*models.APIKeyResponse{{ID: 1, Name: "key1"}, {ID: 2, Name: "key2"}},
+				Pagination: models.PaginationMeta{Limit: 2, NextCursor: "next-token"},
+			}, nil
 		}
 
 		w := httptest.NewRecorder()
-		req, _ := http.NewRequest(http.MethodGet, "/api-keys", nil)
+		req, _ := http.NewRequest(http.MethodGet, "/api-keys?limit=2", nil)
 		router.ServeHTTP(w, req)
 
 		if w.Code != http.StatusOK {
 			t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
 		}
-		var resps []*models.APIKeyResponse
-		json.Unmarshal(w.Body.Bytes(), &resps)
-		if len(resps) != 2 {
-			t.Errorf("expected %d keys, got %d", 2, len(resps))
+		var resp models.APIKeyListResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if len(resp.Data) != 2 {
+			t.Errorf("expected %d keys, got %d", 2, len(resp.Data))
+		}
+		if resp.Links.Next != "/api-keys?cursor=next-token&limit=2" {
+			t.Errorf("unexpected next link %q", resp.Links.Next)
+		}
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		mockService.ListAPIKeysFunc = func(req *models.ListAPIKeysRequest) (*models.APIKeyListResponse, error) {
+			return nil, service.ErrInvalidListQuery
+		}
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api-keys?cursor=forged", nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
 		}
 	})
 }
@@ -159,7 +194,7 @@ func TestAPIKeyHandler_GetAPIKeyByID(t *testing.T) {
 
 	t.Run("not found", func(t *testing.T) {
 		mockService.GetAPIKeyByIDFunc = func(id uint) (*models.APIKeyResponse, error) {
-			return nil, errors.New("API key not found")
+			return nil, repository.ErrAPIKeyNotFound
 		}
 
 		w := httptest.NewRecorder()
@@ -172,6 +207,71 @@ func TestAPIKeyHandler_GetAPIKeyByID(t *testing.T) {
 	})
 }
 
+func TestAPIKeyHandler_RotateAPIKey(t *testing.T) {
+	router, mockService, handler := setupTestRouter()
+	router.POST("/api-keys/:id/rotate", handler.RotateAPIKey)
+
+	t.Run("success without body", func(t *testing.T) {
+		mockService.RotateAPIKeyFunc = func(id uint, req *models.RotateAPIKeyRequest) (*models.APIKeyResponse, error) {
+			if req.GraceUntil != nil {
+				t.Errorf("expected no grace_until, got %v", req.GraceUntil)
+			}
+			return &models.APIKeyResponse{ID: id, Key: "sk-new"}, nil
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api-keys/1/rotate", nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("with grace until", func(t *testing.T) {
+		mockService.RotateAPIKeyFunc = func(id uint, req *models.RotateAPIKeyRequest) (*models.APIKeyResponse, error) {
+			if req.GraceUntil == nil || req.GraceUntil.Year() != 2030 {
+				t.Errorf("expected grace_until to be bound, got %v", req.GraceUntil)
+			}
+			return &models.APIKeyResponse{ID: id, Key: "sk-new", GraceUntil: req.GraceUntil}, nil
+		}
+		w := httptest.NewRecorder()
+		body := bytes.NewBufferString(`{"grace_until":"2030-01-01T00:00:00Z"}`)
+		req, _ := http.NewRequest(http.MethodPost, "/api-keys/1/rotate", body)
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("invalid grace period", func(t *testing.T) {
+		mockService.RotateAPIKeyFunc = func(id uint, req *models.RotateAPIKeyRequest) (*models.APIKeyResponse, error) {
+			return nil, service.ErrInvalidGracePeriod
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api-keys/1/rotate", nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockService.RotateAPIKeyFunc = func(id uint, req *models.RotateAPIKeyRequest) (*models.APIKeyResponse, error) {
+			return nil, repository.ErrAPIKeyNotFound
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api-keys/99/rotate", nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
+
 func TestAPIKeyHandler_DeleteAPIKey(t *testing.T) {
 	router, mockService, handler := setupTestRouter()
 	router.DELETE("/api-keys/:id", handler.DeleteAPIKey)
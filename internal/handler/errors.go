@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"errors"
+
+	"go-grafana/internal/domain/repository"
+	"go-grafana/internal/problem"
+	"go-grafana/internal/requestctx"
+	"go-grafana/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// internalErrorDetail is the detail of every 500 response, which must not reveal the underlying error
+const internalErrorDetail = "An unexpected error occurred"
+
+// problemTypeForError maps domain errors from the service and repository layers to problem types
+func problemTypeForError(err error) problem.Type {
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		return problem.Validation
+	case errors.Is(err, repository.ErrNotFound):
+		return problem.NotFound
+	case errors.Is(err, repository.ErrConflict):
+		return problem.Conflict
+	default:
+		return problem.Internal
+	}
+}
+
+// respondWithError writes the problem response for a failed service call.
+// Validation errors list the invalid fields in Errors. Unexpected errors get a fixed detail and are logged
+// with the request ID instead, so that clients can report the ID without seeing the error.
+func respondWithError(c *gin.Context, logger *zap.Logger, err error) {
+	problemType := problemTypeForError(err)
+	if problemType == problem.Internal {
+		requestctx.Logger(c.Request.Context(), logger).Error("Unexpected error", zap.Error(err), zap.String("path", c.Request.URL.Path))
+		problem.Respond(c, problem.New(problemType, internalErrorDetail))
+		return
+	}
+
+	p := problem.New(problemType, err.Error())
+
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		p.Errors = make([]problem.FieldError, len(validationErr.Fields))
+		for i, field := range validationErr.Fields {
+			p.Errors[i] = problem.FieldError{Field: field.Field, Code: "invalid", Message: field.Message}
+		}
+	}
+
+	problem.Respond(c, p)
+}
+
+// respondWithBindingError writes the problem response for a request that failed to bind
+func respondWithBindingError(c *gin.Context, err error) {
+	problem.Respond(c, problem.FromBindingError(err))
+}
+
+// respondWithInvalidID writes the problem response for a malformed ID path parameter
+func respondWithInvalidID(c *gin.Context, message string) {
+	p := problem.New(problem.Validation, message)
+	p.Errors = []problem.FieldError{{Field: "id", Code: "type", Message: message}}
+	problem.Respond(c, p)
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-grafana/internal/domain/repository"
+	"go-grafana/internal/problem"
+	"go-grafana/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestProblemTypeForError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want problem.Type
+	}{
+		{"validation", service.NewValidationError("email", "email is required"), problem.Validation},
+		{"validation sentinel", fmt.Errorf("%w: %q", service.ErrInvalidScope, "nope"), problem.Validation},
+		{"not found", repository.ErrUserNotFound, problem.NotFound},
+		{"wrapped not found", fmt.Errorf("lookup: %w", repository.ErrAPIKeyNotFound), problem.NotFound},
+		{"conflict", repository.ErrUserEmailExists, problem.Conflict},
+		{"unknown", errors.New("connection refused"), problem.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := problemTypeForError(tt.err); got != tt.want {
+				t.Errorf("problemTypeForError() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRespondWithError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("validation errors", func(t *testing.T) {
+		validationErr := service.NewValidationError("email", "email is required")
+		validationErr.Add("age", "age must be between 1 and 120")
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/users", nil)
+		respondWithError(c, zap.NewNop(), validationErr)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+		if got := w.Header().Get("Content-Type"); got != problem.ContentType {
+			t.Errorf("expected content type %q, got %q", problem.ContentType, got)
+		}
+		var resp problem.Problem
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.Type != problem.Validation.URI || resp.Instance != "/api/v1/users" {
+			t.Errorf("unexpected problem: %+v", resp)
+		}
+		if len(resp.Errors) != 2 || resp.Errors[0].Field != "email" || resp.Errors[1].Field != "age" {
+			t.Errorf("unexpected errors: %+v", resp.Errors)
+		}
+	})
+
+	t.Run("no field errors", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/users/9", nil)
+		respondWithError(c, zap.NewNop(), repository.ErrUserNotFound)
+
+		var resp problem.Problem
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.Status != http.StatusNotFound || resp.Detail != "user not found" || resp.Errors != nil {
+			t.Errorf("unexpected problem: %+v", resp)
+		}
+	})
+
+	t.Run("unexpected errors", func(t *testing.T) {
+		core, logs := observer.New(zap.ErrorLevel)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+		respondWithError(c, zap.New(core), errors.New("dial tcp 10.0.0.5:5432: connection refused"))
+
+		var resp problem.Problem
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.Status != http.StatusInternalServerError || resp.Detail != internalErrorDetail {
+			t.Errorf("unexpected problem: %+v", resp)
+		}
+		entries := logs.All()
+		if len(entries) != 1 || entries[0].ContextMap()["error"] != "dial tcp 10.0.0.5:5432: connection refused" {
+			t.Errorf("expected the error to be logged, got %+v", entries)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+
+	"go-grafana/internal/health"
+	"go-grafana/internal/requestctx"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// HealthHandler serves the liveness and readiness probes
+type HealthHandler struct {
+	registry *health.Registry
+	logger   *zap.Logger
+}
+
+// NewHealthHandler creates a new instance of HealthHandler
+func NewHealthHandler(registry *health.Registry, logger *zap.Logger) *HealthHandler {
+	return &HealthHandler{
+		registry: registry,
+		logger:   logger,
+	}
+}
+
+// Livez reports whether the process is alive. It does not check external dependencies,
+// so a database outage does not get healthy pods restarted.
+func (h *HealthHandler) Livez(c *gin.Context) {
+	h.respond(c, "liveness", h.registry.Liveness(c.Request.Context()))
+}
+
+// Readyz godoc
+// @Summary Readiness check
+// @Description Run the dependency checks and report per-check status and latency. Also served at /readyz.
+// @Tags system
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /health [get]
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	h.respond(c, "readiness", h.registry.Readiness(c.Request.Context()))
+}
+
+// respond writes the report with 200 when it is healthy and 503 otherwise. The errors of failed checks
+// are only logged.
+func (h *HealthHandler) respond(c *gin.Context, probe string, report *health.Report) {
+	logger := requestctx.Logger(c.Request.Context(), h.logger)
+	for name, result := range report.Checks {
+		if result.Status != health.StatusOK {
+			logger.Warn("Health check failed",
+				zap.String("probe", probe),
+				zap.String("check", name),
+				zap.Bool("optional", result.Optional),
+				zap.String("error", result.Error),
+			)
+		}
+	}
+
+	if !report.Healthy() {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-grafana/internal/health"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestHealthHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var databaseErr error
+	registry := health.NewRegistry(prometheus.NewRegistry(), time.Second)
+	registry.AddReadinessCheck(health.Check{Name: "database", Run: func(context.Context) error { return databaseErr }})
+
+	core, logs := observer.New(zap.WarnLevel)
+	handler := NewHealthHandler(registry, zap.New(core))
+	router := gin.New()
+	router.GET("/livez", handler.Livez)
+	router.GET("/readyz", handler.Readyz)
+
+	var body string
+	get := func(path string) (int, health.Report) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(w, req)
+		body = w.Body.String()
+
+		var report health.Report
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatalf("failed to decode %s response %q: %v", path, w.Body.String(), err)
+		}
+		return w.Code, report
+	}
+
+	t.Run("ready", func(t *testing.T) {
+		databaseErr = nil
+		code, report := get("/readyz")
+		if code != http.StatusOK || report.Status != health.StatusOK {
+			t.Errorf("expected 200 ok, got %d %+v", code, report)
+		}
+		if report.Checks["database"].Status != health.StatusOK {
+			t.Errorf("expected the database check to be reported, got %+v", report.Checks)
+		}
+	})
+
+	t.Run("not ready", func(t *testing.T) {
+		databaseErr = errors.New("dial tcp db.internal:5432: connection refused")
+		code, report := get("/readyz")
+		if code != http.StatusServiceUnavailable || report.Status != health.StatusFail {
+			t.Errorf("expected 503 fail, got %d %+v", code, report)
+		}
+		if report.Checks["database"].Status != health.StatusFail {
+			t.Errorf("expected the database check to fail, got %+v", report.Checks["database"])
+		}
+		if strings.Contains(body, "db.internal") {
+			t.Errorf("expected the check error not to be served, got %s", body)
+		}
+		entries := logs.FilterField(zap.String("error", databaseErr.Error())).All()
+		if len(entries) != 1 || entries[0].ContextMap()["check"] != "database" {
+			t.Errorf("expected the check error to be logged, got %+v", logs.All())
+		}
+
+		// The process is still alive while the database is unavailable
+		if code, _ := get("/livez"); code != http.StatusOK {
+			t.Errorf("expected liveness to stay 200, got %d", code)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/url"
+	"strconv"
+
+	"go-grafana/internal/domain/models"
+)
+
+// buildPaginationLinks builds next/prev links for a paginated list,
+// preserving every filter and sort parameter of the original request
+func buildPaginationLinks(requestURL *url.URL, meta models.PaginationMeta) models.PaginationLinks {
+	var links models.PaginationLinks
+	if meta.NextCursor != "" {
+		links.Next = cursorLink(requestURL, meta.Limit, meta.NextCursor)
+		return links
+	}
+	if meta.HasNext() {
+		links.Next = pageLink(requestURL, meta.Limit, meta.Offset+meta.Limit)
+	}
+	if meta.HasPrev() {
+		links.Prev = pageLink(requestURL, meta.Limit, meta.PrevOffset())
+	}
+	return links
+}
+
+// pageLink returns the request path with its query rewritten to the given limit and offset
+func pageLink(requestURL *url.URL, limit, offset int) string {
+	query := requestURL.Query()
+	query.Del("page")
+	query.Del("page_size")
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+	return requestURL.Path + "?" + query.Encode()
+}
+
+// cursorLink returns the request path with its query rewritten to continue from the cursor
+func cursorLink(requestURL *url.URL, limit int, cursor string) string {
+	query := requestURL.Query()
+	query.Del("page")
+	query.Del("page_size")
+	query.Del("offset")
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("cursor", cursor)
+	return requestURL.Path + "?" + query.Encode()
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"go-grafana/internal/domain/models"
+	"go-grafana/internal/requestctx"
 	"go-grafana/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -34,68 +35,75 @@ func NewUserHandler(userService service.UserService, logger *zap.Logger) *UserHa
 // @Param X-API-Key header string true "API Key" default(sk-1234567890abcdef)
 // @Param user body models.CreateUserRequest true "User information"
 // @Success 201 {object} models.UserResponse
-// @Failure 400 {object} ErrorResponse
-// @Failure 401 {object} ErrorResponse
-// @Failure 409 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /users [post]
 func (h *UserHandler) CreateUser(c *gin.Context) {
 	var req models.CreateUserRequest
 
 	// Bind and validate request
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Error("Failed to bind create user request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid request body",
-			Message: err.Error(),
-		})
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Failed to bind create user request", zap.Error(err))
+		respondWithBindingError(c, err)
 		return
 	}
 
 	// Create user
-	user, err := h.userService.CreateUser(&req)
+	user, err := h.userService.CreateUser(c.Request.Context(), &req)
 	if err != nil {
-		h.logger.Error("Failed to create user", zap.Error(err), zap.String("email", req.Email))
-
-		status := http.StatusInternalServerError
-		if err.Error() == "user with this email already exists" {
-			status = http.StatusConflict
-		} else if err.Error() == "email is required" || err.Error() == "first name is required" ||
-			err.Error() == "last name is required" || err.Error() == "age must be between 1 and 120" {
-			status = http.StatusBadRequest
-		}
-
-		c.JSON(status, ErrorResponse{
-			Error:   "Failed to create user",
-			Message: err.Error(),
-		})
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Failed to create user", zap.Error(err), zap.String("email", req.Email))
+		respondWithError(c, h.logger, err)
 		return
 	}
 
-	h.logger.Info("User created successfully", zap.Uint("user_id", user.ID), zap.String("email", user.Email))
+	requestctx.Logger(c.Request.Context(), h.logger).Info("User created successfully", zap.Uint("user_id", user.ID), zap.String("email", user.Email))
 	c.JSON(http.StatusCreated, user)
 }
 
 // GetUsers godoc
-// @Summary Get all users
-// @Description Retrieve a list of all users
+// @Summary List users
+// @Description Retrieve a paginated, filterable and sortable list of users
 // @Tags users
 // @Produce json
-// @Success 200 {array} models.UserResponse
-// @Failure 500 {object} ErrorResponse
+// @Param page query int false "Page number (1-based)" minimum(1)
+// @Param page_size query int false "Page size" minimum(1) maximum(100)
+// @Param limit query int false "Maximum number of results (overrides page_size)" minimum(1) maximum(100)
+// @Param offset query int false "Number of results to skip (overrides page)" minimum(0)
+// @Param active query bool false "Filter by active flag"
+// @Param email query string false "Filter by email substring (case-insensitive)"
+// @Param min_age query int false "Minimum age (inclusive)"
+// @Param max_age query int false "Maximum age (inclusive)"
+// @Param created_after query string false "Only users created at or after this RFC 3339 timestamp"
+// @Param created_before query string false "Only users created before this RFC 3339 timestamp"
+// @Param sort query string false "Comma separated sort fields, prefix with - for descending" example(-created_at,last_name)
+// @Param cursor query string false "Opaque keyset cursor; pass it empty to start keyset pagination ordered by (created_at, id)"
+// @Success 200 {object} models.UserListResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /users [get]
 func (h *UserHandler) GetUsers(c *gin.Context) {
-	users, err := h.userService.GetAllUsers()
+	var req models.ListUsersRequest
+
+	// Bind and validate query parameters
+	if err := c.ShouldBindQuery(&req); err != nil {
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Failed to bind list users query", zap.Error(err))
+		respondWithBindingError(c, err)
+		return
+	}
+
+	users, err := h.userService.ListUsers(c.Request.Context(), &req)
 	if err != nil {
-		h.logger.Error("Failed to get users", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error:   "Failed to retrieve users",
-			Message: err.Error(),
-		})
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Failed to get users", zap.Error(err))
+		respondWithError(c, h.logger, err)
 		return
 	}
 
-	h.logger.Info("Users retrieved successfully", zap.Int("count", len(users)))
+	users.Links = buildPaginationLinks(c.Request.URL, users.Pagination)
+
+	requestctx.Logger(c.Request.Context(), h.logger).Info("Users retrieved successfully", zap.Int("count", len(users.Data)))
 	c.JSON(http.StatusOK, users)
 }
 
@@ -106,40 +114,28 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 // @Produce json
 // @Param id path int true "User ID"
 // @Success 200 {object} models.UserResponse
-// @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /users/{id} [get]
 func (h *UserHandler) GetUserByID(c *gin.Context) {
 	// Parse user ID from URL parameter
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		h.logger.Error("Invalid user ID", zap.String("id", idStr), zap.Error(err))
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid user ID",
-			Message: "User ID must be a valid integer",
-		})
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Invalid user ID", zap.String("id", idStr), zap.Error(err))
+		respondWithInvalidID(c, "User ID must be a valid integer")
 		return
 	}
 
-	user, err := h.userService.GetUserByID(uint(id))
+	user, err := h.userService.GetUserByID(c.Request.Context(), uint(id))
 	if err != nil {
-		h.logger.Error("Failed to get user by ID", zap.Uint64("id", id), zap.Error(err))
-
-		status := http.StatusInternalServerError
-		if err.Error() == "user not found" || err.Error() == "invalid user ID" {
-			status = http.StatusNotFound
-		}
-
-		c.JSON(status, ErrorResponse{
-			Error:   "Failed to retrieve user",
-			Message: err.Error(),
-		})
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Failed to get user by ID", zap.Uint64("id", id), zap.Error(err))
+		respondWithError(c, h.logger, err)
 		return
 	}
 
-	h.logger.Info("User retrieved successfully", zap.Uint("user_id", user.ID))
+	requestctx.Logger(c.Request.Context(), h.logger).Info("User retrieved successfully", zap.Uint("user_id", user.ID))
 	c.JSON(http.StatusOK, user)
 }
 
@@ -153,22 +149,20 @@ func (h *UserHandler) GetUserByID(c *gin.Context) {
 // @Param id path int true "User ID"
 // @Param user body models.UpdateUserRequest true "Updated user information"
 // @Success 200 {object} models.UserResponse
-// @Failure 400 {object} ErrorResponse
-// @Failure 401 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 409 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /users/{id} [put]
 func (h *UserHandler) UpdateUser(c *gin.Context) {
 	// Parse user ID from URL parameter
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		h.logger.Error("Invalid user ID", zap.String("id", idStr), zap.Error(err))
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid user ID",
-			Message: "User ID must be a valid integer",
-		})
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Invalid user ID", zap.String("id", idStr), zap.Error(err))
+		respondWithInvalidID(c, "User ID must be a valid integer")
 		return
 	}
 
@@ -176,37 +170,20 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 
 	// Bind and validate request
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Error("Failed to bind update user request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid request body",
-			Message: err.Error(),
-		})
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Failed to bind update user request", zap.Error(err))
+		respondWithBindingError(c, err)
 		return
 	}
 
 	// Update user
-	user, err := h.userService.UpdateUser(uint(id), &req)
+	user, err := h.userService.UpdateUser(c.Request.Context(), uint(id), &req)
 	if err != nil {
-		h.logger.Error("Failed to update user", zap.Uint64("id", id), zap.Error(err))
-
-		status := http.StatusInternalServerError
-		if err.Error() == "user not found" || err.Error() == "invalid user ID" {
-			status = http.StatusNotFound
-		} else if err.Error() == "user with this email already exists" {
-			status = http.StatusConflict
-		} else if err.Error() == "email is required" || err.Error() == "first name is required" ||
-			err.Error() == "last name is required" || err.Error() == "age must be between 1 and 120" {
-			status = http.StatusBadRequest
-		}
-
-		c.JSON(status, ErrorResponse{
-			Error:   "Failed to update user",
-			Message: err.Error(),
-		})
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Failed to update user", zap.Uint64("id", id), zap.Error(err))
+		respondWithError(c, h.logger, err)
 		return
 	}
 
-	h.logger.Info("User updated successfully", zap.Uint("user_id", user.ID))
+	requestctx.Logger(c.Request.Context(), h.logger).Info("User updated successfully", zap.Uint("user_id", user.ID))
 	c.JSON(http.StatusOK, user)
 }
 
@@ -218,47 +195,30 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 // @Param X-API-Key header string true "API Key" default(sk-1234567890abcdef)
 // @Param id path int true "User ID"
 // @Success 204 "No Content"
-// @Failure 400 {object} ErrorResponse
-// @Failure 401 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /users/{id} [delete]
 func (h *UserHandler) DeleteUser(c *gin.Context) {
 	// Parse user ID from URL parameter
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		h.logger.Error("Invalid user ID", zap.String("id", idStr), zap.Error(err))
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid user ID",
-			Message: "User ID must be a valid integer",
-		})
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Invalid user ID", zap.String("id", idStr), zap.Error(err))
+		respondWithInvalidID(c, "User ID must be a valid integer")
 		return
 	}
 
 	// Delete user
-	err = h.userService.DeleteUser(uint(id))
+	err = h.userService.DeleteUser(c.Request.Context(), uint(id))
 	if err != nil {
-		h.logger.Error("Failed to delete user", zap.Uint64("id", id), zap.Error(err))
-
-		status := http.StatusInternalServerError
-		if err.Error() == "user not found" || err.Error() == "invalid user ID" {
-			status = http.StatusNotFound
-		}
-
-		c.JSON(status, ErrorResponse{
-			Error:   "Failed to delete user",
-			Message: err.Error(),
-		})
+		requestctx.Logger(c.Request.Context(), h.logger).Error("Failed to delete user", zap.Uint64("id", id), zap.Error(err))
+		respondWithError(c, h.logger, err)
 		return
 	}
 
-	h.logger.Info("User deleted successfully", zap.Uint64("id", id))
+	requestctx.Logger(c.Request.Context(), h.logger).Info("User deleted successfully", zap.Uint64("id", id))
 	c.Status(http.StatusNoContent)
 }
-
-// ErrorResponse represents an error response
-type ErrorResponse struct {
-	Error   string `json:"error" example:"Bad Request"`
-	Message string `json:"message" example:"Invalid request body"`
-}
@@ -2,13 +2,19 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 
 	"go-grafana/internal/domain/models"
+	"go-grafana/internal/domain/repository"
+	"go-grafana/internal/problem"
+	"go-grafana/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -18,28 +24,28 @@ import (
 type MockUserService struct {
 	CreateUserFunc   func(req *models.CreateUserRequest) (*models.UserResponse, error)
 	GetUserByIDFunc  func(id uint) (*models.UserResponse, error)
-	GetAllUsersFunc  func() ([]models.UserResponse, error)
+	ListUsersFunc    func(req *models.ListUsersRequest) (*models.UserListResponse, error)
 	UpdateUserFunc   func(id uint, req *models.UpdateUserRequest) (*models.UserResponse, error)
 	DeleteUserFunc   func(id uint) error
 	GetUserCountFunc func() (int64, error)
 }
 
-func (m *MockUserService) CreateUser(req *models.CreateUserRequest) (*models.UserResponse, error) {
+func (m *MockUserService) CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.UserResponse, error) {
 	return m.CreateUserFunc(req)
 }
-func (m *MockUserService) GetUserByID(id uint) (*models.UserResponse, error) {
+func (m *MockUserService) GetUserByID(ctx context.Context, id uint) (*models.UserResponse, error) {
 	return m.GetUserByIDFunc(id)
 }
-func (m *MockUserService) GetAllUsers() ([]models.UserResponse, error) {
-	return m.GetAllUsersFunc()
+func (m *MockUserService) ListUsers(ctx context.Context, req *models.ListUsersRequest) (*models.UserListResponse, error) {
+	return m.ListUsersFunc(req)
 }
-func (m *MockUserService) UpdateUser(id uint, req *models.UpdateUserRequest) (*models.UserResponse, error) {
+func (m *MockUserService) UpdateUser(ctx context.Context, id uint, req *models.UpdateUserRequest) (*models.UserResponse, error) {
 	return m.UpdateUserFunc(id, req)
 }
-func (m *MockUserService) DeleteUser(id uint) error {
+func (m *MockUserService) DeleteUser(ctx context.Context, id uint) error {
 	return m.DeleteUserFunc(id)
 }
-func (m *MockUserService) GetUserCount() (int64, error) {
+func (m *MockUserService) GetUserCount(ctx context.Context) (int64, error) {
 	return m.GetUserCountFunc()
 }
 
@@ -79,6 +85,55 @@ func TestUserHandler_CreateUser(t *testing.T) {
 			t.Errorf("expected status %d, got %d", http.StatusCreated, w.Code)
 		}
 	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		jsonBody := []byte(`{"email":"not-an-email","last_name":"User","age":30}`)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/users", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+		var resp problem.Problem
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		want := []problem.FieldError{
+			{Field: "email", Code: "email", Message: "email must be a valid email address"},
+			{Field: "first_name", Code: "required", Message: "first_name is required"},
+		}
+		if !reflect.DeepEqual(resp.Errors, want) {
+			t.Errorf("got errors %+v, want %+v", resp.Errors, want)
+		}
+	})
+
+	errorCases := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"email exists", repository.ErrUserEmailExists, http.StatusConflict},
+		{"validation", service.NewValidationError("age", "age must be between 1 and 120"), http.StatusBadRequest},
+		{"internal", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			jsonBody, _ := json.Marshal(models.CreateUserRequest{Email: "test@example.com", FirstName: "Test", LastName: "User", Age: 30})
+			mockService.CreateUserFunc = func(req *models.CreateUserRequest) (*models.UserResponse, error) {
+				return nil, tc.err
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, "/users", bytes.NewBuffer(jsonBody))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.wantStatus {
+				t.Errorf("expected status %d, got %d", tc.wantStatus, w.Code)
+			}
+		})
+	}
 }
 
 func TestUserHandler_GetUsers(t *testing.T) {
@@ -86,8 +141,12 @@ func TestUserHandler_GetUsers(t *testing.T) {
 	router.GET("/users", handler.GetUsers)
 
 	t.Run("success", func(t *testing.T) {
-		mockService.GetAllUsersFunc = func() ([]models.UserResponse, error) {
-			return []models.UserResponse{{ID: 1}}, nil
+		total := int64(1)
+		mockService.ListUsersFunc = func(req *models.ListUsersRequest) (*models.UserListResponse, error) {
+			return &models.UserListResponse{
+				Data:       []models.UserResponse{{ID: 1}},
+				Pagination: models.PaginationMeta{Total: &total, Limit: 20},
+			}, nil
 		}
 		w := httptest.NewRecorder()
 		req, _ := http.NewRequest(http.MethodGet, "/users", nil)
@@ -96,10 +155,88 @@ func TestUserHandler_GetUsers(t *testing.T) {
 		if w.Code != http.StatusOK {
 			t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
 		}
-		var resps []models.UserResponse
-		json.Unmarshal(w.Body.Bytes(), &resps)
-		if len(resps) != 1 {
-			t.Errorf("expected 1 user, got %d", len(resps))
+		var resp models.UserListResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if len(resp.Data) != 1 {
+			t.Errorf("expected 1 user, got %d", len(resp.Data))
+		}
+		if resp.Links.Next != "" || resp.Links.Prev != "" {
+			t.Errorf("expected no links for a single page, got %+v", resp.Links)
+		}
+	})
+
+	t.Run("binds query and builds links", func(t *testing.T) {
+		total := int64(35)
+		mockService.ListUsersFunc = func(req *models.ListUsersRequest) (*models.UserListResponse, error) {
+			if req.Page != 2 || req.PageSize != 10 || req.Active == nil || !*req.Active || req.Sort != "-age" {
+				t.Errorf("unexpected list request: %+v", req)
+			}
+			return &models.UserListResponse{
+				Data:       []models.UserResponse{{ID: 11}},
+				Pagination: models.PaginationMeta{Total: &total, Limit: 10, Offset: 10},
+			}, nil
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/users?page=2&page_size=10&active=true&sort=-age", nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		var resp models.UserListResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.Links.Next != "/users?active=true&limit=10&offset=20&sort=-age" {
+			t.Errorf("unexpected next link %q", resp.Links.Next)
+		}
+		if resp.Links.Prev != "/users?active=true&limit=10&offset=0&sort=-age" {
+			t.Errorf("unexpected prev link %q", resp.Links.Prev)
+		}
+	})
+
+	t.Run("cursor link", func(t *testing.T) {
+		mockService.ListUsersFunc = func(req *models.ListUsersRequest) (*models.UserListResponse, error) {
+			if req.Cursor == nil || *req.Cursor != "" {
+				t.Errorf("expected an empty cursor to be bound, got %v", req.Cursor)
+			}
+			return &models.UserListResponse{
+				Data:       []models.UserResponse{{ID: 1}},
+				Pagination: models.PaginationMeta{Limit: 1, NextCursor: "next-token"},
+			}, nil
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/users?cursor=&limit=1&active=true", nil)
+		router.ServeHTTP(w, req)
+
+		var resp models.UserListResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.Links.Next != "/users?active=true&cursor=next-token&limit=1" {
+			t.Errorf("unexpected next link %q", resp.Links.Next)
+		}
+		if resp.Links.Prev != "" {
+			t.Errorf("expected no prev link, got %q", resp.Links.Prev)
+		}
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/users?page_size=1000", nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("invalid sort", func(t *testing.T) {
+		mockService.ListUsersFunc = func(req *models.ListUsersRequest) (*models.UserListResponse, error) {
+			return nil, fmt.Errorf("%w: unknown sort field %q", service.ErrInvalidListQuery, "password")
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/users?sort=password", nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
 		}
 	})
 }
@@ -123,7 +260,7 @@ func TestUserHandler_GetUserByID(t *testing.T) {
 
 	t.Run("not found", func(t *testing.T) {
 		mockService.GetUserByIDFunc = func(id uint) (*models.UserResponse, error) {
-			return nil, errors.New("user not found")
+			return nil, repository.ErrUserNotFound
 		}
 		w := httptest.NewRecorder()
 		req, _ := http.NewRequest(http.MethodGet, "/users/99", nil)
@@ -0,0 +1,183 @@
+// Package health runs the dependency checks behind the liveness and readiness probes
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Check statuses reported per check and for a whole probe
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check is a named dependency check
+type Check struct {
+	Name string
+	// Run returns an error when the dependency is unhealthy. Its context is cancelled after the registry timeout.
+	Run func(ctx context.Context) error
+	// Optional checks are reported but do not fail the probe
+	Optional bool
+}
+
+// CheckResult is the outcome of a single check
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Optional  bool    `json:"optional,omitempty"`
+	// Error is logged but never served, since driver errors name hosts, users and databases and the probes are public
+	Error string `json:"-"`
+}
+
+// Report is the outcome of a probe
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Healthy reports whether every required check passed
+func (r *Report) Healthy() bool {
+	return r.Status == StatusOK
+}
+
+// Registry holds the liveness and readiness checks and exports their results as the health_check_status gauge
+type Registry struct {
+	mu        sync.RWMutex
+	timeout   time.Duration
+	liveness  []Check
+	readiness []Check
+	status    *prometheus.GaugeVec
+	draining  atomic.Bool
+}
+
+// NewRegistry creates an empty registry. Every check run is bounded by timeout.
+func NewRegistry(reg prometheus.Registerer, timeout time.Duration) *Registry {
+	status := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "health_check_status",
+		Help: "Result of the latest run of a health check, 1 if it passed and 0 if it failed",
+	}, []string{"check"})
+	reg.MustRegister(status)
+
+	return &Registry{
+		timeout: timeout,
+		status:  status,
+	}
+}
+
+// AddLivenessCheck registers a check run by the liveness probe. Liveness failures get the process
+// restarted, so only checks that a restart can fix belong here.
+func (r *Registry) AddLivenessCheck(check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.liveness = append(r.liveness, check)
+}
+
+// AddReadinessCheck registers a check run by the readiness probe
+func (r *Registry) AddReadinessCheck(check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.readiness = append(r.readiness, check)
+}
+
+// Liveness runs the liveness checks
+func (r *Registry) Liveness(ctx context.Context) *Report {
+	r.mu.RLock()
+	checks := r.liveness
+	r.mu.RUnlock()
+
+	return r.run(ctx, checks)
+}
+
+// Drain makes the readiness probe fail from now on, without running its checks, so that load
+// balancers stop routing requests to a server that is shutting down
+func (r *Registry) Drain() {
+	r.draining.Store(true)
+}
+
+// Readiness runs the readiness checks
+func (r *Registry) Readiness(ctx context.Context) *Report {
+	if r.draining.Load() {
+		return &Report{
+			Status: StatusFail,
+			Checks: map[string]CheckResult{
+				"shutdown": {Status: StatusFail, Error: "server is shutting down"},
+			},
+		}
+	}
+
+	r.mu.RLock()
+	checks := r.readiness
+	r.mu.RUnlock()
+
+	return r.run(ctx, checks)
+}
+
+// run runs the checks concurrently and collects their results
+func (r *Registry) run(ctx context.Context, checks []Check) *Report {
+	results := make([]CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.runCheck(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := &Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(checks))}
+	for i, check := range checks {
+		report.Checks[check.Name] = results[i]
+		if results[i].Status != StatusOK && !check.Optional {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// runCheck runs a single check with the registry timeout and records its status
+func (r *Registry) runCheck(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := runSafely(ctx, check.Run)
+	latency := time.Since(start)
+
+	result := CheckResult{
+		Status:    StatusOK,
+		LatencyMS: float64(latency.Microseconds()) / 1000,
+		Optional:  check.Optional,
+	}
+	if err == nil {
+		// Checks that ignore their context must not pass after the deadline
+		err = ctx.Err()
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+		r.status.WithLabelValues(check.Name).Set(0)
+	} else {
+		r.status.WithLabelValues(check.Name).Set(1)
+	}
+	return result
+}
+
+// runSafely turns a panicking check into a failed one
+func runSafely(ctx context.Context, run func(ctx context.Context) error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("check panicked: %v", recovered)
+		}
+	}()
+	return run(ctx)
+}
@@ -0,0 +1,172 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// gaugeValues returns the health_check_status value of every check gathered from reg
+func gaugeValues(t *testing.T, reg *prometheus.Registry) map[string]float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+
+	values := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != "health_check_status" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			values[metric.GetLabel()[0].GetValue()] = metric.GetGauge().GetValue()
+		}
+	}
+	return values
+}
+
+func TestRegistry_Readiness(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("connection refused") }
+
+	tests := []struct {
+		name       string
+		checks     []Check
+		wantStatus string
+		wantGauges map[string]float64
+	}{
+		{
+			name:       "no checks",
+			wantStatus: StatusOK,
+			wantGauges: map[string]float64{},
+		},
+		{
+			name:       "all passing",
+			checks:     []Check{{Name: "database", Run: ok}, {Name: "migrations", Run: ok}},
+			wantStatus: StatusOK,
+			wantGauges: map[string]float64{"database": 1, "migrations": 1},
+		},
+		{
+			name:       "required check failing",
+			checks:     []Check{{Name: "database", Run: failing}, {Name: "migrations", Run: ok}},
+			wantStatus: StatusFail,
+			wantGauges: map[string]float64{"database": 0, "migrations": 1},
+		},
+		{
+			name:       "optional check failing",
+			checks:     []Check{{Name: "database", Run: ok}, {Name: "sentry", Run: failing, Optional: true}},
+			wantStatus: StatusOK,
+			wantGauges: map[string]float64{"database": 1, "sentry": 0},
+		},
+		{
+			name:       "panicking check",
+			checks:     []Check{{Name: "database", Run: func(context.Context) error { panic("boom") }}},
+			wantStatus: StatusFail,
+			wantGauges: map[string]float64{"database": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := prometheus.NewRegistry()
+			registry := NewRegistry(reg, time.Second)
+			for _, check := range tt.checks {
+				registry.AddReadinessCheck(check)
+			}
+
+			report := registry.Readiness(context.Background())
+			if report.Status != tt.wantStatus {
+				t.Errorf("expected status %s, got %s", tt.wantStatus, report.Status)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Errorf("expected %d check results, got %v", len(tt.checks), report.Checks)
+			}
+			for _, check := range tt.checks {
+				result := report.Checks[check.Name]
+				if result.Optional != check.Optional {
+					t.Errorf("%s: expected optional %v, got %v", check.Name, check.Optional, result.Optional)
+				}
+				if (result.Status == StatusFail) != (result.Error != "") {
+					t.Errorf("%s: expected an error exactly when failing, got %+v", check.Name, result)
+				}
+			}
+
+			got := gaugeValues(t, reg)
+			if len(got) != len(tt.wantGauges) {
+				t.Errorf("expected gauges %v, got %v", tt.wantGauges, got)
+			}
+			for name, want := range tt.wantGauges {
+				if got[name] != want {
+					t.Errorf("%s: expected gauge %v, got %v", name, want, got[name])
+				}
+			}
+		})
+	}
+}
+
+func TestRegistry_Timeout(t *testing.T) {
+	registry := NewRegistry(prometheus.NewRegistry(), 20*time.Millisecond)
+	registry.AddReadinessCheck(Check{Name: "slow", Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	registry.AddReadinessCheck(Check{Name: "ignores context", Run: func(context.Context) error {
+		time.Sleep(40 * time.Millisecond)
+		return nil
+	}})
+
+	start := time.Now()
+	report := registry.Readiness(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the checks to run concurrently within the timeout, took %s", elapsed)
+	}
+	if report.Healthy() {
+		t.Error("expected checks exceeding the timeout to fail")
+	}
+	for name, result := range report.Checks {
+		if result.Status != StatusFail || result.LatencyMS < 20 {
+			t.Errorf("%s: expected a failure after at least 20ms, got %+v", name, result)
+		}
+	}
+}
+
+func TestRegistry_LivenessIsSeparate(t *testing.T) {
+	registry := NewRegistry(prometheus.NewRegistry(), time.Second)
+	registry.AddReadinessCheck(Check{Name: "database", Run: func(context.Context) error { return errors.New("down") }})
+	registry.AddLivenessCheck(Check{Name: "process", Run: func(context.Context) error { return nil }})
+
+	report := registry.Liveness(context.Background())
+	if !report.Healthy() {
+		t.Errorf("expected liveness to ignore readiness checks, got %+v", report)
+	}
+	if _, ok := report.Checks["process"]; !ok || len(report.Checks) != 1 {
+		t.Errorf("expected only the liveness check to run, got %v", report.Checks)
+	}
+}
+
+func TestRegistry_Drain(t *testing.T) {
+	registry := NewRegistry(prometheus.NewRegistry(), time.Second)
+	ran := false
+	registry.AddReadinessCheck(Check{Name: "database", Run: func(context.Context) error {
+		ran = true
+		return nil
+	}})
+	registry.AddLivenessCheck(Check{Name: "process", Run: func(context.Context) error { return nil }})
+
+	registry.Drain()
+
+	report := registry.Readiness(context.Background())
+	if report.Healthy() || report.Checks["shutdown"].Status != StatusFail {
+		t.Errorf("expected readiness to fail while draining, got %+v", report)
+	}
+	if ran {
+		t.Error("expected the readiness checks not to run while draining")
+	}
+	if !registry.Liveness(context.Background()).Healthy() {
+		t.Error("expected liveness to keep passing while draining")
+	}
+}
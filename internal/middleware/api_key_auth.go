@@ -1,27 +1,26 @@
 package middleware
 
 import (
-	"net/http"
 	"strings"
 
+	"go-grafana/internal/domain/models"
+	"go-grafana/internal/problem"
+	"go-grafana/internal/requestctx"
 	"go-grafana/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
-// APIKeyAuthMiddleware creates middleware for API key authentication
-func APIKeyAuthMiddleware(apiKeyService service.APIKeyService, logger *zap.Logger) gin.HandlerFunc {
+// APIKeyAuthMiddleware creates middleware for API key authentication.
+// Successful authentications are reported to the usage tracker, which persists them asynchronously.
+func APIKeyAuthMiddleware(apiKeyService service.APIKeyService, usageTracker service.APIKeyUsageTracker, logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get API key from header
 		apiKeyHeader := c.GetHeader("X-API-Key")
 		if apiKeyHeader == "" {
-			logger.Warn("Missing API key header", zap.String("path", c.Request.URL.Path))
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "Unauthorized",
-				"message": "API key is required",
-			})
-			c.Abort()
+			requestctx.Logger(c.Request.Context(), logger).Warn("Missing API key header", zap.String("path", c.Request.URL.Path))
+			problem.Respond(c, problem.New(problem.Unauthorized, "API key is required"))
 			return
 		}
 
@@ -32,27 +31,19 @@ func APIKeyAuthMiddleware(apiKeyService service.APIKeyService, logger *zap.Logge
 		}
 
 		if apiKey == "" {
-			logger.Warn("Empty API key provided", zap.String("path", c.Request.URL.Path))
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "Unauthorized",
-				"message": "API key cannot be empty",
-			})
-			c.Abort()
+			requestctx.Logger(c.Request.Context(), logger).Warn("Empty API key provided", zap.String("path", c.Request.URL.Path))
+			problem.Respond(c, problem.New(problem.Unauthorized, "API key cannot be empty"))
 			return
 		}
 
 		// Validate the API key
-		validatedAPIKey, err := apiKeyService.ValidateAPIKey(apiKey)
+		validatedAPIKey, err := apiKeyService.ValidateAPIKey(c.Request.Context(), apiKey)
 		if err != nil {
-			logger.Warn("Invalid API key provided",
+			requestctx.Logger(c.Request.Context(), logger).Warn("Invalid API key provided",
 				zap.String("path", c.Request.URL.Path),
 				zap.String("error", err.Error()),
 			)
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "Unauthorized",
-				"message": "Invalid API key",
-			})
-			c.Abort()
+			problem.Respond(c, problem.New(problem.Unauthorized, "Invalid API key"))
 			return
 		}
 
@@ -61,7 +52,9 @@ func APIKeyAuthMiddleware(apiKeyService service.APIKeyService, logger *zap.Logge
 		c.Set("api_key_id", validatedAPIKey.ID)
 		c.Set("api_key_name", validatedAPIKey.Name)
 
-		logger.Debug("API key validated successfully",
+		usageTracker.Track(validatedAPIKey.ID, c.ClientIP())
+
+		requestctx.Logger(c.Request.Context(), logger).Debug("API key validated successfully",
 			zap.Uint("api_key_id", validatedAPIKey.ID),
 			zap.String("api_key_name", validatedAPIKey.Name),
 			zap.String("path", c.Request.URL.Path),
@@ -71,6 +64,35 @@ func APIKeyAuthMiddleware(apiKeyService service.APIKeyService, logger *zap.Logge
 	}
 }
 
+// RequireScopes creates middleware that only lets requests through if the API key stored in the
+// context by APIKeyAuthMiddleware has been granted every given scope. It must run after APIKeyAuthMiddleware.
+func RequireScopes(logger *zap.Logger, scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := GetAPIKeyFromContext(c)
+		apiKey, ok := value.(*models.APIKey)
+		if !exists || !ok {
+			requestctx.Logger(c.Request.Context(), logger).Error("Scope check without an authenticated API key", zap.String("path", c.Request.URL.Path))
+			problem.Respond(c, problem.New(problem.Unauthorized, "API key is required"))
+			return
+		}
+
+		missing := apiKey.Scopes.Missing(scopes...)
+		if len(missing) > 0 {
+			requestctx.Logger(c.Request.Context(), logger).Warn("API key is missing required scopes",
+				zap.Uint("api_key_id", apiKey.ID),
+				zap.Strings("missing_scopes", missing),
+				zap.String("path", c.Request.URL.Path),
+			)
+			p := problem.New(problem.Forbidden, "API key is missing required scope: "+strings.Join(missing, ", "))
+			p.MissingScopes = missing
+			problem.Respond(c, p)
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // GetAPIKeyFromContext retrieves the API key from the Gin context
 func GetAPIKeyFromContext(c *gin.Context) (interface{}, bool) {
 	return c.Get("api_key")
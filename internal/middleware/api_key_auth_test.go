@@ -1,12 +1,15 @@
 package middleware
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"go-grafana/internal/domain/models"
+	"go-grafana/internal/problem"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -17,24 +20,49 @@ type MockAPIKeyService struct {
 	ValidateAPIKeyFunc func(key string) (*models.APIKey, error)
 }
 
-func (m *MockAPIKeyService) CreateAPIKey(req *models.CreateAPIKeyRequest) (*models.APIKeyResponse, error) {
+func (m *MockAPIKeyService) CreateAPIKey(ctx context.Context, req *models.CreateAPIKeyRequest) (*models.APIKeyResponse, error) {
 	return nil, nil
 }
-func (m *MockAPIKeyService) GetAPIKeyByID(id uint) (*models.APIKeyResponse, error) { return nil, nil }
-func (m *MockAPIKeyService) GetAllAPIKeys() ([]*models.APIKeyResponse, error)      { return nil, nil }
-func (m *MockAPIKeyService) UpdateAPIKey(id uint, req *models.UpdateAPIKeyRequest) (*models.APIKeyResponse, error) {
+func (m *MockAPIKeyService) GetAPIKeyByID(ctx context.Context, id uint) (*models.APIKeyResponse, error) {
 	return nil, nil
 }
-func (m *MockAPIKeyService) DeleteAPIKey(id uint) error { return nil }
-func (m *MockAPIKeyService) ValidateAPIKey(key string) (*models.APIKey, error) {
+func (m *MockAPIKeyService) ListAPIKeys(ctx context.Context, req *models.ListAPIKeysRequest) (*models.APIKeyListResponse, error) {
+	return nil, nil
+}
+func (m *MockAPIKeyService) UpdateAPIKey(ctx context.Context, id uint, req *models.UpdateAPIKeyRequest) (*models.APIKeyResponse, error) {
+	return nil, nil
+}
+func (m *MockAPIKeyService) DeleteAPIKey(ctx context.Context, id uint) error { return nil }
+func (m *MockAPIKeyService) RevokeAPIKey(ctx context.Context, id uint) (*models.APIKeyResponse, error) {
+	return nil, nil
+}
+func (m *MockAPIKeyService) RotateAPIKey(ctx context.Context, id uint, req *models.RotateAPIKeyRequest) (*models.APIKeyResponse, error) {
+	return nil, nil
+}
+func (m *MockAPIKeyService) EnsureAPIKey(ctx context.Context, plainTextKey, name string, scopes []string) (bool, error) {
+	return false, nil
+}
+func (m *MockAPIKeyService) ValidateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
 	return m.ValidateAPIKeyFunc(key)
 }
 
+// MockAPIKeyUsageTracker records tracked usage for middleware tests
+type MockAPIKeyUsageTracker struct {
+	tracked []uint
+}
+
+func (m *MockAPIKeyUsageTracker) Track(apiKeyID uint, clientIP string) {
+	m.tracked = append(m.tracked, apiKeyID)
+}
+func (m *MockAPIKeyUsageTracker) Start()                         {}
+func (m *MockAPIKeyUsageTracker) Stop(ctx context.Context) error { return nil }
+
 func TestAPIKeyAuthMiddleware(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockService := &MockAPIKeyService{}
+	mockTracker := &MockAPIKeyUsageTracker{}
 	logger := zap.NewNop()
-	middleware := APIKeyAuthMiddleware(mockService, logger)
+	middleware := APIKeyAuthMiddleware(mockService, mockTracker, logger)
 
 	router := gin.New()
 	router.Use(middleware)
@@ -58,6 +86,9 @@ func TestAPIKeyAuthMiddleware(t *testing.T) {
 		if w.Code != http.StatusOK {
 			t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
 		}
+		if len(mockTracker.tracked) != 1 || mockTracker.tracked[0] != 1 {
+			t.Errorf("expected usage of key 1 to be tracked, got %v", mockTracker.tracked)
+		}
 	})
 
 	t.Run("missing key", func(t *testing.T) {
@@ -68,6 +99,14 @@ func TestAPIKeyAuthMiddleware(t *testing.T) {
 		if w.Code != http.StatusUnauthorized {
 			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
 		}
+		if got := w.Header().Get("Content-Type"); got != problem.ContentType {
+			t.Errorf("expected content type %q, got %q", problem.ContentType, got)
+		}
+		var resp problem.Problem
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.Type != problem.Unauthorized.URI || resp.Status != http.StatusUnauthorized || resp.Instance != "/test" {
+			t.Errorf("unexpected problem: %+v", resp)
+		}
 	})
 
 	t.Run("invalid key", func(t *testing.T) {
@@ -79,6 +118,66 @@ func TestAPIKeyAuthMiddleware(t *testing.T) {
 		req.Header.Set("X-API-Key", "invalid-key")
 		router.ServeHTTP(w, req)
 
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+		if len(mockTracker.tracked) != 1 {
+			t.Errorf("expected failed authentication not to be tracked, got %v", mockTracker.tracked)
+		}
+	})
+}
+
+func TestRequireScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := zap.NewNop()
+
+	newRouter := func(apiKey *models.APIKey) *gin.Engine {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			if apiKey != nil {
+				c.Set("api_key", apiKey)
+			}
+			c.Next()
+		})
+		router.GET("/test", RequireScopes(logger, models.ScopeUsersWrite), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		return router
+	}
+
+	t.Run("granted", func(t *testing.T) {
+		router := newRouter(&models.APIKey{ID: 1, Scopes: models.Scopes{models.ScopeUsersWrite}})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("missing scope", func(t *testing.T) {
+		router := newRouter(&models.APIKey{ID: 1, Scopes: models.Scopes{models.ScopeUsersRead}})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
+		}
+		var resp problem.Problem
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.Type != problem.Forbidden.URI || len(resp.MissingScopes) != 1 || resp.MissingScopes[0] != models.ScopeUsersWrite {
+			t.Errorf("expected problem to name the missing scope, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		router := newRouter(nil)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+		router.ServeHTTP(w, req)
+
 		if w.Code != http.StatusUnauthorized {
 			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
 		}
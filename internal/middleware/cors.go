@@ -1,56 +1,106 @@
 package middleware
 
 import (
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"go-grafana/internal/config"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
-// CORSMiddleware provides CORS configuration
+// CORSMiddleware applies the configured CORS policy, or the override of the route group a request belongs to.
+// The policies can be replaced at runtime with Update.
 type CORSMiddleware struct {
-	logger *zap.Logger
+	policies *atomic.Pointer[corsPolicies]
+	logger   *zap.Logger
 }
 
 // NewCORSMiddleware creates a new CORS middleware instance
-func NewCORSMiddleware(logger *zap.Logger) CORSMiddleware {
-	return CORSMiddleware{
-		logger: logger,
+func NewCORSMiddleware(cfg *config.Config, logger *zap.Logger) CORSMiddleware {
+	m := CORSMiddleware{
+		policies: new(atomic.Pointer[corsPolicies]),
+		logger:   logger,
 	}
+	m.Update(cfg.CORS)
+	return m
 }
 
-// Handle returns a Gin middleware function for CORS
-func (m CORSMiddleware) Handle() gin.HandlerFunc {
-	config := cors.DefaultConfig()
-
-	// Allow all origins for development
-	// In production, you should specify allowed origins
-	config.AllowAllOrigins = true
-
-	// Allow specific methods
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-
-	// Allow specific headers
-	config.AllowHeaders = []string{
-		"Origin",
-		"Content-Type",
-		"Accept",
-		"Authorization",
-		"X-Requested-With",
-	}
+// corsPolicies holds the CORS handlers built from a CORS configuration
+type corsPolicies struct {
+	defaultHandler gin.HandlerFunc
+	// routes are sorted by prefix length, the longest first
+	routes []corsRoute
+}
 
-	// Allow credentials
-	config.AllowCredentials = true
+// corsRoute is the CORS handler of the routes under a path prefix
+type corsRoute struct {
+	prefix  string
+	handler gin.HandlerFunc
+}
 
-	// Expose headers
-	config.ExposeHeaders = []string{
-		"Content-Length",
-		"Content-Type",
+// Update replaces the CORS policies; requests already being handled keep the previous ones.
+// The policies must have passed config validation.
+func (m CORSMiddleware) Update(cfg config.CORSConfig) {
+	policies := &corsPolicies{
+		defaultHandler: cors.New(corsConfig(cfg.CORSPolicy)),
+		routes:         make([]corsRoute, 0, len(cfg.Routes)),
+	}
+	for prefix, policy := range cfg.Routes {
+		policies.routes = append(policies.routes, corsRoute{
+			prefix:  strings.TrimSuffix(prefix, "/"),
+			handler: cors.New(corsConfig(policy)),
+		})
+		m.logger.Info("CORS route override configured",
+			zap.String("prefix", prefix),
+			zap.Strings("allowed_origins", policy.AllowedOrigins),
+		)
 	}
+	slices.SortFunc(policies.routes, func(a, b corsRoute) int {
+		return len(b.prefix) - len(a.prefix)
+	})
+	m.policies.Store(policies)
 
 	m.logger.Info("CORS middleware configured",
-		zap.Bool("allow_all_origins", config.AllowAllOrigins),
-		zap.Strings("allow_methods", config.AllowMethods),
+		zap.Strings("allowed_origins", cfg.AllowedOrigins),
+		zap.Strings("allowed_methods", cfg.AllowedMethods),
+		zap.Bool("allow_credentials", cfg.AllowCredentials),
 	)
+}
 
-	return cors.New(config)
+// Handle returns a Gin middleware function for CORS. Preflight requests do not reach the middleware of
+// route groups, so the overrides are matched here by path prefix, the longest prefix first.
+func (m CORSMiddleware) Handle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policies := m.policies.Load()
+		path := c.Request.URL.Path
+		for _, route := range policies.routes {
+			if path == route.prefix || strings.HasPrefix(path, route.prefix+"/") {
+				route.handler(c)
+				return
+			}
+		}
+		policies.defaultHandler(c)
+	}
+}
+
+// corsConfig translates a CORS policy to the gin-contrib/cors configuration
+func corsConfig(policy config.CORSPolicy) cors.Config {
+	corsConfig := cors.Config{
+		AllowMethods:     policy.AllowedMethods,
+		AllowHeaders:     policy.AllowedHeaders,
+		ExposeHeaders:    policy.ExposedHeaders,
+		AllowCredentials: policy.AllowCredentials,
+		MaxAge:           policy.MaxAge,
+		AllowWildcard:    true,
+	}
+	if slices.Contains(policy.AllowedOrigins, "*") {
+		corsConfig.AllowAllOrigins = true
+	} else {
+		corsConfig.AllowOrigins = policy.AllowedOrigins
+	}
+	return corsConfig
 }
@@ -3,23 +3,50 @@ package middleware
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"go-grafana/internal/config"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
+// newCORSTestConfig returns a configuration with the given default CORS policy
+func newCORSTestConfig(policy config.CORSPolicy) *config.Config {
+	if policy.AllowedMethods == nil {
+		policy.AllowedMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	}
+	if policy.AllowedHeaders == nil {
+		policy.AllowedHeaders = []string{"Origin", "Content-Type", "X-API-Key", RequestIDHeader}
+	}
+	return &config.Config{CORS: config.CORSConfig{CORSPolicy: policy}}
+}
+
+// newCORSTestRouter returns a router with the CORS middleware and a GET route at each path
+func newCORSTestRouter(cfg *config.Config, paths ...string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(NewCORSMiddleware(cfg, zap.NewNop()).Handle())
+	for _, path := range paths {
+		router.GET(path, func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+	}
+	return router
+}
+
 func TestNewCORSMiddleware(t *testing.T) {
 	logger := zap.NewNop()
-	corsMiddleware := NewCORSMiddleware(logger)
+	corsMiddleware := NewCORSMiddleware(config.NewConfig(), logger)
 	if corsMiddleware.logger == nil {
 		t.Error("expected logger to be initialized")
 	}
 }
 
 func TestCORSMiddleware_Handle(t *testing.T) {
-	logger := zap.NewNop()
-	corsMiddleware := NewCORSMiddleware(logger)
+	corsMiddleware := NewCORSMiddleware(config.NewConfig(), zap.NewNop())
 	handler := corsMiddleware.Handle()
 
 	if handler == nil {
@@ -43,4 +70,143 @@ func TestCORSMiddleware_Handle(t *testing.T) {
 	if w.Header().Get("Access-Control-Allow-Origin") == "" {
 		t.Error("expected Access-Control-Allow-Origin header to be set")
 	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Error("expected credentials not to be allowed by default")
+	}
+}
+
+func TestCORSMiddleware_Preflight(t *testing.T) {
+	router := newCORSTestRouter(config.NewConfig(), "/test")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodOptions, "/test", nil)
+	req.Header.Set("Origin", "http://example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "X-API-Key")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Headers"); !containsHeader(got, "X-Api-Key") {
+		t.Errorf("expected X-API-Key to be allowed, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Max-Age"); got != "43200" {
+		t.Errorf("expected preflight results to be cacheable for 12h, got %q", got)
+	}
+}
+
+func TestCORSMiddleware_AllowedOrigins(t *testing.T) {
+	cfg := newCORSTestConfig(config.CORSPolicy{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowCredentials: true,
+	})
+	router := newCORSTestRouter(cfg, "/test")
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://app.example.com", true},
+		{"https://admin.example.org", true},
+		{"https://evil.example.net", false},
+		{"http://app.example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Origin", tt.origin)
+			router.ServeHTTP(w, req)
+
+			allowOrigin := w.Header().Get("Access-Control-Allow-Origin")
+			if tt.allowed && (allowOrigin != tt.origin || w.Header().Get("Access-Control-Allow-Credentials") != "true") {
+				t.Errorf("expected the origin to be allowed with credentials, got %q", allowOrigin)
+			}
+			if !tt.allowed && (allowOrigin != "" || w.Code != http.StatusForbidden) {
+				t.Errorf("expected the origin to be rejected, got status %d and %q", w.Code, allowOrigin)
+			}
+		})
+	}
+}
+
+func TestCORSMiddleware_RouteOverrides(t *testing.T) {
+	cfg := newCORSTestConfig(config.CORSPolicy{AllowedOrigins: []string{"*"}})
+	cfg.CORS.Routes = map[string]config.CORSPolicy{
+		"/api/v1/api-keys": {
+			AllowedOrigins:   []string{"https://admin.example.com"},
+			AllowedMethods:   []string{"GET"},
+			AllowedHeaders:   []string{"X-API-Key"},
+			AllowCredentials: true,
+			MaxAge:           10 * time.Minute,
+		},
+	}
+	router := newCORSTestRouter(cfg, "/api/v1/users", "/api/v1/api-keys", "/api/v1/api-keys/:id", "/api/v1/api-keys-legacy")
+
+	tests := []struct {
+		path       string
+		origin     string
+		wantOrigin string
+	}{
+		{"/api/v1/users", "https://app.example.com", "*"},
+		{"/api/v1/api-keys", "https://admin.example.com", "https://admin.example.com"},
+		{"/api/v1/api-keys/7", "https://admin.example.com", "https://admin.example.com"},
+		{"/api/v1/api-keys/7", "https://app.example.com", ""},
+		{"/api/v1/api-keys-legacy", "https://app.example.com", "*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" from "+tt.origin, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			router.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("expected Access-Control-Allow-Origin %q, got %q", tt.wantOrigin, got)
+			}
+		})
+	}
+}
+
+// containsHeader reports whether a comma-separated header list contains the header
+func containsHeader(list, header string) bool {
+	for _, item := range strings.Split(list, ",") {
+		if http.CanonicalHeaderKey(strings.TrimSpace(item)) == header {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCORSMiddleware_Update(t *testing.T) {
+	cfg := newCORSTestConfig(config.CORSPolicy{AllowedOrigins: []string{"https://app.example.com"}})
+	corsMiddleware := NewCORSMiddleware(cfg, zap.NewNop())
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(corsMiddleware.Handle())
+	router.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	allowOrigin := func(origin string) string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Origin", origin)
+		router.ServeHTTP(w, req)
+		return w.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	if got := allowOrigin("https://admin.example.com"); got != "" {
+		t.Fatalf("expected the origin to be rejected before the update, got %q", got)
+	}
+
+	updated := newCORSTestConfig(config.CORSPolicy{AllowedOrigins: []string{"https://app.example.com", "https://admin.example.com"}})
+	corsMiddleware.Update(updated.CORS)
+
+	if got := allowOrigin("https://admin.example.com"); got != "https://admin.example.com" {
+		t.Errorf("expected the origin to be allowed after the update, got %q", got)
+	}
 }
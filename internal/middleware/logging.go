@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"go-grafana/internal/requestctx"
+
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -20,8 +22,8 @@ func NewLoggingMiddleware(logger *zap.Logger) LoggingMiddleware {
 // Handle returns a Gin middleware function for logging
 func (m LoggingMiddleware) Handle() gin.HandlerFunc {
 	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		// Log structured data using Zap
-		m.logger.Info("HTTP Request",
+		// Log structured data using Zap, through the request logger so the line carries the request and trace IDs
+		requestctx.Logger(param.Request.Context(), m.logger).Info("HTTP Request",
 			zap.String("method", param.Method),
 			zap.String("path", param.Path),
 			zap.String("client_ip", param.ClientIP),
@@ -4,9 +4,12 @@ import (
 	"strconv"
 	"time"
 
+	"go-grafana/internal/requestctx"
+
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -80,14 +83,19 @@ func (m MetricsMiddleware) Handle() gin.HandlerFunc {
 		duration := time.Since(start).Seconds()
 		status := strconv.Itoa(c.Writer.Status())
 
-		// Record request duration
-		m.httpRequestDuration.WithLabelValues(c.Request.Method, path).Observe(duration)
+		// Record request duration, linking sampled requests to their trace
+		observer := m.httpRequestDuration.WithLabelValues(c.Request.Method, path)
+		if spanContext := trace.SpanContextFromContext(c.Request.Context()); spanContext.IsSampled() {
+			observer.(prometheus.ExemplarObserver).ObserveWithExemplar(duration, prometheus.Labels{"trace_id": spanContext.TraceID().String()})
+		} else {
+			observer.Observe(duration)
+		}
 
 		// Record total requests
 		m.httpRequestsTotal.WithLabelValues(c.Request.Method, path, status).Inc()
 
 		// Log metrics for debugging
-		m.logger.Debug("Request metrics recorded",
+		requestctx.Logger(c.Request.Context(), m.logger).Debug("Request metrics recorded",
 			zap.String("method", c.Request.Method),
 			zap.String("path", path),
 			zap.String("status", status),
@@ -96,7 +104,11 @@ func (m MetricsMiddleware) Handle() gin.HandlerFunc {
 	}
 }
 
-// MetricsHandler returns the Prometheus metrics handler
+// MetricsHandler returns the Prometheus metrics handler.
+// Exemplars are only exposed to scrapers that negotiate the OpenMetrics format.
 func (m MetricsMiddleware) MetricsHandler() gin.HandlerFunc {
-	return gin.WrapH(promhttp.Handler())
+	return gin.WrapH(promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	))
 }
@@ -0,0 +1,263 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-grafana/internal/config"
+	"go-grafana/internal/domain/models"
+	"go-grafana/internal/problem"
+	"go-grafana/internal/requestctx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// rateLimitSweepInterval is how often idle buckets are removed
+const rateLimitSweepInterval = time.Minute
+
+// anonymousRateLimitLabel is the api_key label used for requests without an API key,
+// so that client IPs do not end up as metric labels
+const anonymousRateLimitLabel = "anonymous"
+
+// authFailureRateLimitLabel is the api_key label used for requests rejected after too many failed authentications
+const authFailureRateLimitLabel = "auth_failure"
+
+// tokenBucket is a token bucket refilled continuously at rate tokens per second up to burst
+type tokenBucket struct {
+	rate     float64
+	burst    float64
+	tokens   float64
+	lastSeen time.Time
+}
+
+// refill adds the tokens accumulated since the bucket was last seen
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.lastSeen).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+	}
+	b.lastSeen = now
+}
+
+// take removes a token and returns true, or returns false if the bucket is empty
+func (b *tokenBucket) take() bool {
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// untilAvailable returns how long until the next token is available
+func (b *tokenBucket) untilAvailable() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// untilFull returns how long until the bucket is full again
+func (b *tokenBucket) untilFull() time.Duration {
+	return time.Duration((b.burst - b.tokens) / b.rate * float64(time.Second))
+}
+
+// RateLimitMiddleware throttles requests with a token bucket per API key, or per client IP
+// when the request is not authenticated. The global limits can be changed at runtime with SetLimits.
+type RateLimitMiddleware struct {
+	logger         *zap.Logger
+	limits         atomic.Pointer[config.RateLimitConfig]
+	throttledTotal *prometheus.CounterVec
+	now            func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// NewRateLimitMiddleware creates a new rate limit middleware instance
+func NewRateLimitMiddleware(cfg *config.Config, reg prometheus.Registerer, logger *zap.Logger) *RateLimitMiddleware {
+	throttledTotal := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_requests_throttled_total",
+			Help: "Total number of HTTP requests rejected by the rate limiter",
+		},
+		[]string{"api_key"},
+	)
+	reg.MustRegister(throttledTotal)
+
+	m := &RateLimitMiddleware{
+		logger:         logger,
+		throttledTotal: throttledTotal,
+		now:            time.Now,
+		buckets:        make(map[string]*tokenBucket),
+		lastSweep:      time.Now(),
+	}
+	m.SetLimits(cfg.RateLimit)
+	return m
+}
+
+// SetLimits replaces the global limits. Buckets using them start over, full, on their next request.
+func (m *RateLimitMiddleware) SetLimits(limits config.RateLimitConfig) {
+	m.limits.Store(&limits)
+}
+
+// Handle returns a Gin middleware function for rate limiting.
+// It must run after APIKeyAuthMiddleware on protected routes so that limits apply per API key.
+func (m *RateLimitMiddleware) Handle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limits := m.limits.Load()
+		if !limits.Enabled {
+			c.Next()
+			return
+		}
+
+		bucketKey, label, rate, burst := limitFor(c, limits)
+		if rate <= 0 || burst <= 0 {
+			c.Next()
+			return
+		}
+
+		allowed, limit, remaining, reset, retryAfter := m.take(bucketKey, rate, burst)
+
+		c.Header("RateLimit-Limit", strconv.Itoa(limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+
+		if !allowed {
+			m.throttledTotal.WithLabelValues(label).Inc()
+			requestctx.Logger(c.Request.Context(), m.logger).Warn("Request throttled",
+				zap.String("api_key", label),
+				zap.String("path", c.Request.URL.Path),
+			)
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+			problem.Respond(c, problem.New(problem.RateLimited, "Rate limit exceeded, retry later"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ThrottleFailedAuth returns a Gin middleware function that rejects clients which failed to authenticate too often.
+// It must run before APIKeyAuthMiddleware: every 401 response takes a token from a bucket per client IP, and once
+// the bucket is empty the client is rejected without its API key being checked, so keys cannot be guessed faster
+// than the configured budget. Successful requests take no token.
+func (m *RateLimitMiddleware) ThrottleFailedAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limits := m.limits.Load()
+		if !limits.Enabled || limits.AuthFailuresPerMinute <= 0 || limits.AuthFailureBurst <= 0 {
+			c.Next()
+			return
+		}
+
+		bucketKey := "auth:" + c.ClientIP()
+		rate := limits.AuthFailuresPerMinute / 60
+		if retryAfter, blocked := m.blocked(bucketKey, rate, limits.AuthFailureBurst); blocked {
+			m.throttledTotal.WithLabelValues(authFailureRateLimitLabel).Inc()
+			requestctx.Logger(c.Request.Context(), m.logger).Warn("Request throttled after failed authentications",
+				zap.String("path", c.Request.URL.Path),
+			)
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+			problem.Respond(c, problem.New(problem.RateLimited, "Too many failed authentication attempts, retry later"))
+			return
+		}
+
+		c.Next()
+
+		if c.Writer.Status() == http.StatusUnauthorized {
+			m.take(bucketKey, rate, limits.AuthFailureBurst)
+		}
+	}
+}
+
+// limitFor returns the bucket key, metric label and limits for the request.
+// Per-key overrides on the authenticated API key take precedence over the global limits.
+func limitFor(c *gin.Context, limits *config.RateLimitConfig) (string, string, float64, int) {
+	value, exists := GetAPIKeyFromContext(c)
+	apiKey, ok := value.(*models.APIKey)
+	if !exists || !ok {
+		return "ip:" + c.ClientIP(), anonymousRateLimitLabel, limits.RequestsPerSecond, limits.Burst
+	}
+
+	rate, burst := limits.RequestsPerSecond, limits.Burst
+	if apiKey.RateLimitRPS != nil {
+		rate = *apiKey.RateLimitRPS
+	}
+	if apiKey.RateLimitBurst != nil {
+		burst = *apiKey.RateLimitBurst
+	}
+
+	id := strconv.FormatUint(uint64(apiKey.ID), 10)
+	return "key:" + id, id, rate, burst
+}
+
+// take consumes a token from the bucket and reports the resulting rate limit state
+func (m *RateLimitMiddleware) take(bucketKey string, rate float64, burst int) (allowed bool, limit, remaining int, reset, retryAfter time.Duration) {
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	bucket := m.bucket(bucketKey, rate, burst, now)
+	allowed = bucket.take()
+
+	return allowed, burst, int(bucket.tokens), bucket.untilFull(), bucket.untilAvailable()
+}
+
+// blocked reports whether the bucket is empty and how long until it has a token again, without taking one
+func (m *RateLimitMiddleware) blocked(bucketKey string, rate float64, burst int) (time.Duration, bool) {
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	if _, ok := m.buckets[bucketKey]; !ok {
+		return 0, false
+	}
+	retryAfter := m.bucket(bucketKey, rate, burst, now).untilAvailable()
+	return retryAfter, retryAfter > 0
+}
+
+// bucket returns the refilled bucket for the key. The caller must hold the lock.
+func (m *RateLimitMiddleware) bucket(bucketKey string, rate float64, burst int, now time.Time) *tokenBucket {
+	bucket, ok := m.buckets[bucketKey]
+	if !ok || bucket.rate != rate || bucket.burst != float64(burst) {
+		// Start a fresh bucket for new clients and whenever the limits of a key change
+		bucket = &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), lastSeen: now}
+		m.buckets[bucketKey] = bucket
+	}
+
+	bucket.refill(now)
+	return bucket
+}
+
+// sweep drops buckets that have refilled completely, since they behave exactly like new ones.
+// The caller must hold the lock.
+func (m *RateLimitMiddleware) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for bucketKey, bucket := range m.buckets {
+		bucket.refill(now)
+		if bucket.tokens >= bucket.burst {
+			delete(m.buckets, bucketKey)
+		}
+	}
+}
+
+// ceilSeconds rounds a duration up to whole seconds for use in headers
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,246 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-grafana/internal/config"
+	"go-grafana/internal/domain/models"
+	"go-grafana/internal/problem"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+func newTestRateLimitRouter(rps float64, burst int, apiKey *models.APIKey) (*gin.Engine, *RateLimitMiddleware, *prometheus.Registry) {
+	gin.SetMode(gin.TestMode)
+	reg := prometheus.NewRegistry()
+	cfg := &config.Config{RateLimit: config.RateLimitConfig{Enabled: true, RequestsPerSecond: rps, Burst: burst}}
+	rateLimit := NewRateLimitMiddleware(cfg, reg, zap.NewNop())
+
+	router := gin.New()
+	if apiKey != nil {
+		router.Use(func(c *gin.Context) {
+			c.Set("api_key", apiKey)
+			c.Next()
+		})
+	}
+	router.Use(rateLimit.Handle())
+	router.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router, rateLimit, reg
+}
+
+func doRateLimitedRequest(router *gin.Engine, remoteAddr string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+	req.RemoteAddr = remoteAddr
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitMiddleware_PerClientIP(t *testing.T) {
+	router, rateLimit, reg := newTestRateLimitRouter(1, 2, nil)
+	now := time.Now()
+	rateLimit.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		w := doRateLimitedRequest(router, "10.0.0.1:1234")
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status %d, got %d", i, http.StatusOK, w.Code)
+		}
+	}
+
+	w := doRateLimitedRequest(router, "10.0.0.1:1234")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != problem.ContentType {
+		t.Errorf("expected content type %q, got %q", problem.ContentType, got)
+	}
+	if w.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected Retry-After 1, got %q", w.Header().Get("Retry-After"))
+	}
+	if w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("unexpected rate limit headers: %v", w.Header())
+	}
+	if got := testutil.ToFloat64(rateLimit.throttledTotal.WithLabelValues(anonymousRateLimitLabel)); got != 1 {
+		t.Errorf("expected 1 throttled request, got %v", got)
+	}
+	if count, _ := testutil.GatherAndCount(reg, "http_requests_throttled_total"); count != 1 {
+		t.Errorf("expected throttled counter to be registered, got %d series", count)
+	}
+
+	// Another client has its own bucket
+	if w := doRateLimitedRequest(router, "10.0.0.2:1234"); w.Code != http.StatusOK {
+		t.Errorf("expected other client to pass, got %d", w.Code)
+	}
+
+	// Tokens refill over time
+	now = now.Add(time.Second)
+	if w := doRateLimitedRequest(router, "10.0.0.1:1234"); w.Code != http.StatusOK {
+		t.Errorf("expected request after refill to pass, got %d", w.Code)
+	}
+}
+
+func TestRateLimitMiddleware_PerAPIKeyOverride(t *testing.T) {
+	rps, burst := 1.0, 3
+	apiKey := &models.APIKey{ID: 7, RateLimitRPS: &rps, RateLimitBurst: &burst}
+	router, rateLimit, _ := newTestRateLimitRouter(1, 1, apiKey)
+	now := time.Now()
+	rateLimit.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		w := doRateLimitedRequest(router, "10.0.0.1:1234")
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status %d, got %d", i, http.StatusOK, w.Code)
+		}
+	}
+
+	// Requests from a different IP share the key's bucket
+	w := doRateLimitedRequest(router, "10.0.0.2:1234")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if got := testutil.ToFloat64(rateLimit.throttledTotal.WithLabelValues("7")); got != 1 {
+		t.Errorf("expected 1 throttled request for key 7, got %v", got)
+	}
+}
+
+func TestRateLimitMiddleware_Disabled(t *testing.T) {
+	router, rateLimit, _ := newTestRateLimitRouter(1, 1, nil)
+	rateLimit.SetLimits(config.RateLimitConfig{Enabled: false})
+
+	for i := 0; i < 5; i++ {
+		if w := doRateLimitedRequest(router, "10.0.0.1:1234"); w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	}
+}
+
+func TestRateLimitMiddleware_SetLimits(t *testing.T) {
+	router, rateLimit, _ := newTestRateLimitRouter(1, 1, nil)
+	now := time.Now()
+	rateLimit.now = func() time.Time { return now }
+
+	doRateLimitedRequest(router, "10.0.0.1:1234")
+	if w := doRateLimitedRequest(router, "10.0.0.1:1234"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+
+	rateLimit.SetLimits(config.RateLimitConfig{Enabled: true, RequestsPerSecond: 1, Burst: 3})
+
+	w := doRateLimitedRequest(router, "10.0.0.1:1234")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the new burst to apply, got status %d", w.Code)
+	}
+	if got := w.Header().Get("RateLimit-Limit"); got != "3" {
+		t.Errorf("expected RateLimit-Limit 3, got %q", got)
+	}
+}
+
+func TestRateLimitMiddleware_ThrottleFailedAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{RateLimit: config.RateLimitConfig{Enabled: true, AuthFailuresPerMinute: 60, AuthFailureBurst: 2}}
+	rateLimit := NewRateLimitMiddleware(cfg, prometheus.NewRegistry(), zap.NewNop())
+	now := time.Now()
+	rateLimit.now = func() time.Time { return now }
+
+	router := gin.New()
+	router.Use(rateLimit.ThrottleFailedAuth(), func(c *gin.Context) {
+		if c.GetHeader("X-API-Key") != "valid" {
+			problem.Respond(c, problem.New(problem.Unauthorized, "Invalid API key"))
+			return
+		}
+		c.Next()
+	})
+	router.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	do := func(remoteAddr, key string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-API-Key", key)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Successful requests do not use up the budget
+	for i := 0; i < 5; i++ {
+		if code := do("10.0.0.1:1234", "valid"); code != http.StatusOK {
+			t.Fatalf("request %d: expected status %d, got %d", i, http.StatusOK, code)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := do("10.0.0.1:1234", "guess"); code != http.StatusUnauthorized {
+			t.Fatalf("guess %d: expected status %d, got %d", i, http.StatusUnauthorized, code)
+		}
+	}
+
+	// The budget is spent, so even a valid key is not checked until a token is back
+	for _, key := range []string{"guess", "valid"} {
+		if code := do("10.0.0.1:1234", key); code != http.StatusTooManyRequests {
+			t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, code)
+		}
+	}
+	if got := testutil.ToFloat64(rateLimit.throttledTotal.WithLabelValues(authFailureRateLimitLabel)); got != 2 {
+		t.Errorf("expected 2 throttled requests, got %v", got)
+	}
+
+	// Another client has its own budget
+	if code := do("10.0.0.2:1234", "guess"); code != http.StatusUnauthorized {
+		t.Errorf("expected other client to reach authentication, got %d", code)
+	}
+
+	now = now.Add(time.Second)
+	if code := do("10.0.0.1:1234", "valid"); code != http.StatusOK {
+		t.Errorf("expected request after refill to pass, got %d", code)
+	}
+}
+
+func TestRateLimitMiddleware_ForwardedFor(t *testing.T) {
+	do := func(router *gin.Engine, remoteAddr, forwardedFor string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("untrusted peer", func(t *testing.T) {
+		router, rateLimit, _ := newTestRateLimitRouter(1, 1, nil)
+		router.SetTrustedProxies(nil)
+		now := time.Now()
+		rateLimit.now = func() time.Time { return now }
+
+		if code := do(router, "10.0.0.1:1234", "203.0.113.1"); code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+		}
+		// A different spoofed address does not get a fresh bucket
+		if code := do(router, "10.0.0.1:1234", "203.0.113.2"); code != http.StatusTooManyRequests {
+			t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, code)
+		}
+	})
+
+	t.Run("trusted proxy", func(t *testing.T) {
+		router, rateLimit, _ := newTestRateLimitRouter(1, 1, nil)
+		router.SetTrustedProxies([]string{"10.0.0.0/8"})
+		now := time.Now()
+		rateLimit.now = func() time.Time { return now }
+
+		if code := do(router, "10.0.0.1:1234", "203.0.113.1"); code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+		}
+		if code := do(router, "10.0.0.1:1234", "203.0.113.2"); code != http.StatusOK {
+			t.Errorf("expected clients behind the proxy to have their own buckets, got %d", code)
+		}
+	})
+}
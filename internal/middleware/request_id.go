@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"go-grafana/internal/requestctx"
+	"go-grafana/pkg/tracing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader is the header that carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the length of request IDs accepted from callers
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware assigns every request an ID that links its logs, error response and Sentry events
+type RequestIDMiddleware struct {
+	logger *zap.Logger
+}
+
+// NewRequestIDMiddleware creates a new request ID middleware instance
+func NewRequestIDMiddleware(logger *zap.Logger) RequestIDMiddleware {
+	return RequestIDMiddleware{
+		logger: logger,
+	}
+}
+
+// Handle returns a Gin middleware function that accepts the caller's X-Request-ID or generates one.
+// The ID is echoed in the response, stored in the Gin and request contexts along with a logger tagged
+// with it, and set as a tag on the Sentry scope of the request.
+func (m RequestIDMiddleware) Handle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Set("request_id", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		ctx := c.Request.Context()
+		logger := m.logger.With(append([]zap.Field{zap.String("request_id", requestID)}, tracing.LogFields(ctx)...)...)
+		ctx = requestctx.WithLogger(requestctx.WithRequestID(ctx, requestID), logger)
+
+		// sentrygin picks up the hub from the request context instead of cloning its own
+		hub := sentry.GetHubFromContext(ctx)
+		if hub == nil {
+			hub = sentry.CurrentHub().Clone()
+		}
+		hub.Scope().SetTag("request_id", requestID)
+		c.Request = c.Request.WithContext(sentry.SetHubOnContext(ctx, hub))
+
+		c.Next()
+	}
+}
+
+// GetRequestIDFromContext retrieves the request ID from the Gin context
+func GetRequestIDFromContext(c *gin.Context) (string, bool) {
+	requestID, exists := c.Get("request_id")
+	if !exists {
+		return "", false
+	}
+
+	if id, ok := requestID.(string); ok {
+		return id, true
+	}
+
+	return "", false
+}
+
+// validRequestID reports whether a caller supplied request ID is safe to log and echo.
+// Only short IDs made of letters, digits and the separators - _ . : are accepted.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range requestID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
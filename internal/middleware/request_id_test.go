@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-grafana/internal/problem"
+	"go-grafana/internal/requestctx"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRequestIDMiddleware_Handle(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		wantSame bool
+	}{
+		{"generated when missing", "", false},
+		{"accepted from the caller", "client-req_42.a:b", true},
+		{"replaced when it contains unsafe characters", "bad id\nforged", false},
+		{"replaced when too long", strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+			logger := zap.New(zapcore.NewCore(encoder, zapcore.AddSync(&buffer), zap.InfoLevel))
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(NewRequestIDMiddleware(logger).Handle())
+
+			var ginID, contextID, sentryTag string
+			router.GET("/test", func(c *gin.Context) {
+				ginID, _ = GetRequestIDFromContext(c)
+				contextID = requestctx.RequestID(c.Request.Context())
+				if hub := sentry.GetHubFromContext(c.Request.Context()); hub != nil {
+					event := hub.Scope().ApplyToEvent(sentry.NewEvent(), nil, nil)
+					sentryTag = event.Tags["request_id"]
+				}
+				requestctx.Logger(c.Request.Context(), zap.NewNop()).Info("handled")
+				problem.Respond(c, problem.New(problem.Internal, "boom"))
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			router.ServeHTTP(w, req)
+
+			requestID := w.Header().Get(RequestIDHeader)
+			if requestID == "" {
+				t.Fatal("expected the request ID to be echoed in the response")
+			}
+			if (requestID == tt.incoming) != tt.wantSame {
+				t.Errorf("incoming %q, got %q", tt.incoming, requestID)
+			}
+			if ginID != requestID || contextID != requestID || sentryTag != requestID {
+				t.Errorf("expected %q in the gin context, request context and Sentry scope, got %q, %q and %q", requestID, ginID, contextID, sentryTag)
+			}
+			if !strings.Contains(buffer.String(), `"request_id":"`+requestID+`"`) {
+				t.Errorf("expected the request logger to be tagged with the request ID, got %s", buffer.String())
+			}
+			if !strings.Contains(w.Body.String(), `"request_id":"`+requestID+`"`) {
+				t.Errorf("expected the problem response to carry the request ID, got %s", w.Body.String())
+			}
+		})
+	}
+}
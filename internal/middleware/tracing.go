@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMiddleware starts a server span for every request, continuing the W3C trace context of the caller
+type TracingMiddleware struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// NewTracingMiddleware creates a new tracing middleware instance using the global propagator
+func NewTracingMiddleware(tracerProvider trace.TracerProvider) TracingMiddleware {
+	return TracingMiddleware{
+		tracer:     tracerProvider.Tracer("go-grafana/internal/middleware"),
+		propagator: otel.GetTextMapPropagator(),
+	}
+}
+
+// Handle returns a Gin middleware function for tracing.
+// The trace context of the span is also written to the response headers so callers can correlate it.
+func (m TracingMiddleware) Handle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := m.propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		// Name spans after the route template to keep their cardinality low
+		route := c.FullPath()
+		name := c.Request.Method
+		if route != "" {
+			name += " " + route
+		}
+
+		ctx, span := m.tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+				semconv.ClientAddress(c.ClientIP()),
+				semconv.UserAgentOriginal(c.Request.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		m.propagator.Inject(ctx, propagation.HeaderCarrier(c.Writer.Header()))
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		// Client errors are the caller's fault and do not fail the server span
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		for _, err := range c.Errors {
+			span.RecordError(err.Err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// incomingTraceparent is a sampled W3C trace context sent by a caller
+const incomingTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// newTestTracingMiddleware creates a tracing middleware that records spans instead of exporting them
+func newTestTracingMiddleware() (TracingMiddleware, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return TracingMiddleware{
+		tracer:     tracerProvider.Tracer("test"),
+		propagator: propagation.TraceContext{},
+	}, recorder
+}
+
+func TestTracingMiddleware_Handle(t *testing.T) {
+	tracingMiddleware, recorder := newTestTracingMiddleware()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(tracingMiddleware.Handle())
+	var handlerSpan trace.SpanContext
+	router.GET("/users/:id", func(c *gin.Context) {
+		handlerSpan = trace.SpanContextFromContext(c.Request.Context())
+		c.Status(http.StatusInternalServerError)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("traceparent", incomingTraceparent)
+	router.ServeHTTP(w, req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected one span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /users/:id" || span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("expected a server span named after the route, got %q (%v)", span.Name(), span.SpanKind())
+	}
+	if span.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("expected the span to continue the incoming trace, got %v with parent %v", span.SpanContext(), span.Parent())
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("expected a server error to fail the span, got %v", span.Status())
+	}
+	if handlerSpan.SpanID() != span.SpanContext().SpanID() {
+		t.Error("expected the request context to carry the server span")
+	}
+
+	// The trace context is propagated back to the caller
+	want := "00-4bf92f3577b34da6a3ce929d0e0e4736-" + span.SpanContext().SpanID().String() + "-01"
+	if got := w.Header().Get("traceparent"); got != want {
+		t.Errorf("expected traceparent %s, got %s", want, got)
+	}
+}
+
+func TestTracingMiddleware_Exemplars(t *testing.T) {
+	tracingMiddleware, _ := newTestTracingMiddleware()
+	reg := prometheus.NewRegistry()
+	metricsMiddleware := newTestMetricsMiddleware(reg)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(tracingMiddleware.Handle(), metricsMiddleware.Handle())
+	router.GET("/test-exemplar", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/test-exemplar", nil)
+	req.Header.Set("traceparent", incomingTraceparent)
+	router.ServeHTTP(w, req)
+
+	metricFamilies, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("could not gather metrics: %v", err)
+	}
+	var exemplarTraceID string
+	for _, mf := range metricFamilies {
+		if mf.GetName() != "http_request_duration_seconds" {
+			continue
+		}
+		for _, bucket := range mf.GetMetric()[0].GetHistogram().GetBucket() {
+			if exemplar := bucket.GetExemplar(); exemplar != nil {
+				exemplarTraceID = exemplar.GetLabel()[0].GetValue()
+			}
+		}
+	}
+	if exemplarTraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected an exemplar with the trace ID, got %q", exemplarTraceID)
+	}
+}
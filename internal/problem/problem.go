@@ -0,0 +1,184 @@
+// Package problem writes error responses as RFC 7807 problem details (application/problem+json).
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go-grafana/internal/requestctx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ContentType is the media type of problem detail responses
+const ContentType = "application/problem+json"
+
+// Type identifies a kind of problem. Clients should branch on URI rather than on Title or Detail.
+type Type struct {
+	URI    string
+	Title  string
+	Status int
+}
+
+// Problem types returned by the API
+var (
+	Validation   = Type{URI: "/problems/validation-error", Title: "Validation Failed", Status: 400}
+	Unauthorized = Type{URI: "/problems/unauthorized", Title: "Unauthorized", Status: 401}
+	Forbidden    = Type{URI: "/problems/forbidden", Title: "Forbidden", Status: 403}
+	NotFound     = Type{URI: "/problems/not-found", Title: "Not Found", Status: 404}
+	Conflict     = Type{URI: "/problems/conflict", Title: "Conflict", Status: 409}
+	RateLimited  = Type{URI: "/problems/rate-limited", Title: "Too Many Requests", Status: 429}
+	Internal     = Type{URI: "/problems/internal-error", Title: "Internal Server Error", Status: 500}
+)
+
+// FieldError describes why a single request field is invalid
+type FieldError struct {
+	// Field is the JSON or query parameter name of the field
+	Field string `json:"field" example:"email"`
+	// Code is the name of the failed rule, such as required, email, min or max
+	Code    string `json:"code" example:"required"`
+	Message string `json:"message" example:"email is required"`
+}
+
+// Problem is an RFC 7807 problem details object
+type Problem struct {
+	Type     string `json:"type" example:"/problems/validation-error"`
+	Title    string `json:"title" example:"Validation Failed"`
+	Status   int    `json:"status" example:"400"`
+	Detail   string `json:"detail,omitempty" example:"email is required"`
+	Instance string `json:"instance,omitempty" example:"/api/v1/users"`
+	// Errors lists the invalid fields of a request that failed validation
+	Errors []FieldError `json:"errors,omitempty"`
+	// MissingScopes lists the scopes an API key lacks for a forbidden request
+	MissingScopes []string `json:"missing_scopes,omitempty"`
+	// RequestID identifies the request in logs and error reports
+	RequestID string `json:"request_id,omitempty" example:"3f2b8c1e-5a4d-4e7f-9b21-6c0d8e9f1a2b"`
+}
+
+// New creates a problem of the given type
+func New(t Type, detail string) *Problem {
+	return &Problem{
+		Type:   t.URI,
+		Title:  t.Title,
+		Status: t.Status,
+		Detail: detail,
+	}
+}
+
+// Respond writes the problem as the response and aborts the remaining handlers.
+// The request path is used as the instance unless one is already set, and the request ID is added if there is one.
+func Respond(c *gin.Context, p *Problem) {
+	if c.Request != nil {
+		if p.Instance == "" {
+			p.Instance = c.Request.URL.Path
+		}
+		if p.RequestID == "" {
+			p.RequestID = requestctx.RequestID(c.Request.Context())
+		}
+	}
+
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// FromBindingError creates a validation problem from an error returned by gin's ShouldBind functions.
+// Validator and JSON type errors are listed per field; any other error becomes the detail.
+func FromBindingError(err error) *Problem {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, len(validationErrs))
+		messages := make([]string, len(validationErrs))
+		for i, fieldErr := range validationErrs {
+			fields[i] = FieldError{
+				Field:   fieldName(fieldErr),
+				Code:    fieldErr.Tag(),
+				Message: fieldMessage(fieldErr),
+			}
+			messages[i] = fields[i].Message
+		}
+
+		p := New(Validation, strings.Join(messages, "; "))
+		p.Errors = fields
+		return p
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		p := New(Validation, fmt.Sprintf("%s must be of type %s", typeErr.Field, typeErr.Type))
+		p.Errors = []FieldError{{Field: typeErr.Field, Code: "type", Message: p.Detail}}
+		return p
+	}
+
+	return New(Validation, err.Error())
+}
+
+// fieldName returns the path of the invalid field using JSON or form names, e.g. scopes[0]
+func fieldName(fieldErr validator.FieldError) string {
+	namespace := fieldErr.Namespace()
+	// Drop the name of the request struct itself
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return fieldErr.Field()
+}
+
+// fieldMessage returns a human readable message for a failed validation rule
+func fieldMessage(fieldErr validator.FieldError) string {
+	field := fieldErr.Field()
+	switch fieldErr.Tag() {
+	case "required":
+		return field + " is required"
+	case "email":
+		return field + " must be a valid email address"
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, strings.ReplaceAll(fieldErr.Param(), " ", ", "))
+	case "min", "gte":
+		if isLengthKind(fieldErr.Kind()) {
+			return fmt.Sprintf("%s must be at least %s characters long", field, fieldErr.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s", field, fieldErr.Param())
+	case "max", "lte":
+		if isLengthKind(fieldErr.Kind()) {
+			return fmt.Sprintf("%s must be at most %s characters long", field, fieldErr.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s", field, fieldErr.Param())
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", field, fieldErr.Param())
+	case "lt":
+		return fmt.Sprintf("%s must be less than %s", field, fieldErr.Param())
+	default:
+		return field + " is invalid"
+	}
+}
+
+// isLengthKind reports whether min and max rules apply to the length of a value of the given kind
+func isLengthKind(kind reflect.Kind) bool {
+	return kind == reflect.String || kind == reflect.Slice || kind == reflect.Map || kind == reflect.Array
+}
+
+// init makes the validator report fields by their JSON or form names instead of their Go names,
+// so that field errors match what clients send
+func init() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+}
@@ -0,0 +1,155 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-grafana/internal/requestctx"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testRequest struct {
+	Name   string   `json:"name" binding:"required,min=2"`
+	Age    int      `json:"age" binding:"omitempty,max=120"`
+	Scopes []string `json:"scopes" binding:"omitempty,dive,oneof=read write"`
+}
+
+type testQuery struct {
+	Limit int `form:"limit" binding:"omitempty,min=1"`
+}
+
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/test", func(c *gin.Context) {
+		var req testRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			Respond(c, FromBindingError(err))
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	router.GET("/test", func(c *gin.Context) {
+		var query testQuery
+		if err := c.ShouldBindQuery(&query); err != nil {
+			Respond(c, FromBindingError(err))
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func doRequest(router *gin.Engine, method, target, body string) (*httptest.ResponseRecorder, Problem) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	var p Problem
+	json.Unmarshal(w.Body.Bytes(), &p)
+	return w, p
+}
+
+func TestRespond(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/users/7?x=1", nil)
+
+	Respond(c, New(NotFound, "user not found"))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("expected content type %q, got %q", ContentType, got)
+	}
+	if !c.IsAborted() {
+		t.Error("expected the context to be aborted")
+	}
+
+	var p Problem
+	json.Unmarshal(w.Body.Bytes(), &p)
+	want := Problem{Type: NotFound.URI, Title: "Not Found", Status: http.StatusNotFound, Detail: "user not found", Instance: "/api/v1/users/7"}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("got %+v, want %+v", p, want)
+	}
+}
+
+func TestRespond_RequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/users/7", nil)
+	c.Request = c.Request.WithContext(requestctx.WithRequestID(c.Request.Context(), "req-123"))
+
+	Respond(c, New(Internal, "boom"))
+
+	var p Problem
+	json.Unmarshal(w.Body.Bytes(), &p)
+	if p.RequestID != "req-123" {
+		t.Errorf("expected request ID req-123, got %q", p.RequestID)
+	}
+}
+
+func TestFromBindingError(t *testing.T) {
+	router := newTestRouter()
+
+	t.Run("validator errors", func(t *testing.T) {
+		w, p := doRequest(router, http.MethodPost, "/test", `{"age":200,"scopes":["read","admin"]}`)
+
+		if w.Code != http.StatusBadRequest || p.Type != Validation.URI {
+			t.Fatalf("expected a validation problem, got %d %+v", w.Code, p)
+		}
+		want := []FieldError{
+			{Field: "name", Code: "required", Message: "name is required"},
+			{Field: "age", Code: "max", Message: "age must be at most 120"},
+			{Field: "scopes[1]", Code: "oneof", Message: "scopes[1] must be one of: read, write"},
+		}
+		if !reflect.DeepEqual(p.Errors, want) {
+			t.Errorf("got errors %+v, want %+v", p.Errors, want)
+		}
+		if p.Detail != "name is required; age must be at most 120; scopes[1] must be one of: read, write" {
+			t.Errorf("unexpected detail %q", p.Detail)
+		}
+	})
+
+	t.Run("string length", func(t *testing.T) {
+		_, p := doRequest(router, http.MethodPost, "/test", `{"name":"a"}`)
+
+		if len(p.Errors) != 1 || p.Errors[0].Message != "name must be at least 2 characters long" {
+			t.Errorf("unexpected errors %+v", p.Errors)
+		}
+	})
+
+	t.Run("type mismatch", func(t *testing.T) {
+		_, p := doRequest(router, http.MethodPost, "/test", `{"name":"ok","age":"old"}`)
+
+		if len(p.Errors) != 1 || p.Errors[0].Field != "age" || p.Errors[0].Code != "type" {
+			t.Errorf("unexpected errors %+v", p.Errors)
+		}
+	})
+
+	t.Run("query parameters use form names", func(t *testing.T) {
+		_, p := doRequest(router, http.MethodGet, "/test?limit=-1", "")
+
+		if len(p.Errors) != 1 || p.Errors[0].Field != "limit" || p.Errors[0].Code != "min" {
+			t.Errorf("unexpected errors %+v", p.Errors)
+		}
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		p := FromBindingError(errors.New("unexpected EOF"))
+
+		if p.Status != http.StatusBadRequest || p.Detail != "unexpected EOF" || p.Errors != nil {
+			t.Errorf("unexpected problem %+v", p)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package redact
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// core wraps a zap core to mask secrets in messages and fields before they are written
+type core struct {
+	zapcore.Core
+	redactor *Redactor
+}
+
+// NewCore wraps c so that the fields named sensitive by the redactor are masked, as are the secrets found in
+// messages, string fields and errors
+func NewCore(c zapcore.Core, redactor *Redactor) zapcore.Core {
+	return &core{Core: c, redactor: redactor}
+}
+
+// With masks the fields added to every entry of the returned core
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	return &core{Core: c.Core.With(c.fields(fields)), redactor: c.redactor}
+}
+
+// Check adds this core to the entry when the wrapped core would write it. The wrapped core decides rather
+// than its level alone, since cores such as the Sentry one also accept entries below their level.
+func (c *core) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Core.Check(entry, nil) != nil {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write masks the entry and writes it to the wrapped core
+func (c *core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = String(entry.Message)
+	return c.Core.Write(entry, c.fields(fields))
+}
+
+// fields returns the fields with their secrets masked, copying them only when something is masked
+func (c *core) fields(fields []zapcore.Field) []zapcore.Field {
+	var masked []zapcore.Field
+	for i, field := range fields {
+		redacted, ok := c.field(field)
+		if !ok {
+			if masked != nil {
+				masked[i] = field
+			}
+			continue
+		}
+		if masked == nil {
+			masked = make([]zapcore.Field, len(fields))
+			copy(masked, fields[:i])
+		}
+		masked[i] = redacted
+	}
+	if masked == nil {
+		return fields
+	}
+	return masked
+}
+
+// field returns the field masked and true when it holds a secret
+func (c *core) field(field zapcore.Field) (zapcore.Field, bool) {
+	if field.Type == zapcore.SkipType {
+		return field, false
+	}
+	if c.redactor.Sensitive(field.Key) {
+		return zap.String(field.Key, Mask), true
+	}
+
+	var text string
+	switch field.Type {
+	case zapcore.StringType:
+		text = field.String
+	case zapcore.ErrorType:
+		err, ok := field.Interface.(error)
+		if !ok || err == nil {
+			return field, false
+		}
+		text = err.Error()
+	default:
+		return field, false
+	}
+
+	if redacted := String(text); redacted != text {
+		return zap.String(field.Key, redacted), true
+	}
+	return field, false
+}
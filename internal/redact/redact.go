@@ -0,0 +1,59 @@
+// Package redact masks secrets in log entries and error reports
+package redact
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Mask replaces redacted values
+const Mask = "REDACTED"
+
+// patterns match secrets embedded in free text, with the replacement keeping what identifies the kind of secret
+var patterns = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	// API keys
+	{regexp.MustCompile(`\bsk-[A-Za-z0-9]{8,}`), "sk-" + Mask},
+	// Credentials in URLs, such as Sentry DSNs and postgres:// connection strings
+	{regexp.MustCompile(`://[^/@\s]+@`), "://" + Mask + "@"},
+	// Passwords in key=value connection strings
+	{regexp.MustCompile(`(?i)\bpassword=\S+`), "password=" + Mask},
+}
+
+// String masks the secrets found in s
+func String(s string) string {
+	for _, pattern := range patterns {
+		s = pattern.re.ReplaceAllString(s, pattern.replacement)
+	}
+	return s
+}
+
+// Redactor masks secrets by the name of the field, header or key holding them, and by their pattern in any text
+type Redactor struct {
+	fields map[string]struct{}
+}
+
+// New creates a redactor that masks the values of the given field names, matched regardless of case
+func New(fields []string) *Redactor {
+	r := &Redactor{fields: make(map[string]struct{}, len(fields))}
+	for _, field := range fields {
+		r.fields[strings.ToLower(field)] = struct{}{}
+	}
+	return r
+}
+
+// Sensitive reports whether values of the named field are always masked
+func (r *Redactor) Sensitive(name string) bool {
+	_, ok := r.fields[strings.ToLower(name)]
+	return ok
+}
+
+// Value masks the value of the named field entirely when the field is sensitive, and the secrets found in it otherwise
+func (r *Redactor) Value(name, value string) string {
+	if r.Sensitive(name) {
+		return Mask
+	}
+	return String(value)
+}
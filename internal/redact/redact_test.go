@@ -0,0 +1,76 @@
+package redact
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"API key", "invalid key sk-1a2b3c4d5e6f7a8b", "invalid key sk-REDACTED"},
+		{"Sentry DSN", "https://abc123@o1.ingest.sentry.io/42", "https://REDACTED@o1.ingest.sentry.io/42"},
+		{"connection URL", "postgres://app:hunter2@db:5432/app", "postgres://REDACTED@db:5432/app"},
+		{"key=value connection string", "host=db password=hunter2 dbname=app", "host=db password=REDACTED dbname=app"},
+		{"words ending in sk", "task-queue and risk-free", "task-queue and risk-free"},
+		{"short sk prefix", "sk-abc", "sk-abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := String(tt.input); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRedactor_Value(t *testing.T) {
+	redactor := New([]string{"password", "X-API-Key"})
+
+	if got := redactor.Value("x-api-key", "anything"); got != Mask {
+		t.Errorf("expected sensitive names to match regardless of case, got %q", got)
+	}
+	if got := redactor.Value("Password", ""); got != Mask {
+		t.Errorf("expected sensitive values to be masked, got %q", got)
+	}
+	if got := redactor.Value("path", "/api/v1/users"); got != "/api/v1/users" {
+		t.Errorf("expected other values to be kept, got %q", got)
+	}
+}
+
+func TestNewCore(t *testing.T) {
+	var buffer bytes.Buffer
+	encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	inner := zapcore.NewCore(encoder, zapcore.AddSync(&buffer), zap.InfoLevel)
+	logger := zap.New(NewCore(inner, New([]string{"password"}))).With(zap.String("password", "with-secret"))
+
+	logger.Info("validating sk-0123456789abcdef",
+		zap.String("password", "field-secret"),
+		zap.String("path", "/api/v1/users"),
+		zap.String("key", "sk-fedcba9876543210"),
+		zap.Error(errors.New("dial postgres://app:hunter2@db/app")),
+		zap.Int("count", 3),
+	)
+	logger.Debug("below the level", zap.String("password", "debug-secret"))
+
+	output := buffer.String()
+	for _, secret := range []string{"with-secret", "field-secret", "0123456789abcdef", "fedcba9876543210", "hunter2", "debug-secret"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("expected %q to be masked, got %s", secret, output)
+		}
+	}
+	for _, kept := range []string{`"path":"/api/v1/users"`, `"count":3`, `"error":"dial postgres://REDACTED@db/app"`, `"key":"sk-REDACTED"`} {
+		if !strings.Contains(output, kept) {
+			t.Errorf("expected %s in the output, got %s", kept, output)
+		}
+	}
+}
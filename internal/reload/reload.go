@@ -0,0 +1,191 @@
+// Package reload applies configuration changes to the running server without a restart
+package reload
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go-grafana/internal/config"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// fileCheckInterval is how often the config file is checked for changes
+const fileCheckInterval = 5 * time.Second
+
+// Results of a reload, exported as the result label of config_reload_total
+const (
+	ResultSuccess = "success"
+	ResultPartial = "partial"
+	ResultError   = "error"
+)
+
+// Hook applies the runtime settings of a newly loaded configuration
+type Hook func(cfg *config.Config)
+
+// Reloader loads the configuration again from its sources on SIGHUP and whenever the config file changes.
+// Only the settings that can change at runtime are applied, through the registered hooks; changes to any other
+// setting are logged and left for the next restart, so the current configuration always matches the running server.
+type Reloader struct {
+	sources       config.Sources
+	logger        *zap.Logger
+	checkInterval time.Duration
+	reloadTotal   *prometheus.CounterVec
+	lastReload    prometheus.Gauge
+
+	mu      sync.Mutex
+	current *config.Config
+	hooks   []Hook
+
+	// fileHash is the config file content last seen by the watch loop
+	fileHash [sha256.Size]byte
+
+	done     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// NewReloader creates a reloader for the configuration cfg loaded from sources
+func NewReloader(sources config.Sources, cfg *config.Config, reg prometheus.Registerer, logger *zap.Logger) *Reloader {
+	reloadTotal := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "config_reload_total",
+		Help: "Total number of configuration reloads by result",
+	}, []string{"result"})
+	lastReload := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "config_last_reload_timestamp",
+		Help: "Unix time of the last successful configuration reload",
+	})
+	reg.MustRegister(reloadTotal, lastReload)
+
+	r := &Reloader{
+		sources:       sources,
+		logger:        logger,
+		checkInterval: fileCheckInterval,
+		reloadTotal:   reloadTotal,
+		lastReload:    lastReload,
+		current:       cfg,
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	r.fileHash, _ = r.hashFile()
+	return r
+}
+
+// OnReload registers a hook run on every successful reload
+func (r *Reloader) OnReload(hook Hook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.hooks = append(r.hooks, hook)
+}
+
+// Current returns the configuration in effect
+func (r *Reloader) Current() *config.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current
+}
+
+// Reload loads the configuration and applies its runtime settings, unless it is invalid
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := config.Load(r.sources)
+	if err != nil {
+		r.reloadTotal.WithLabelValues(ResultError).Inc()
+		r.logger.Error("Configuration reload failed, keeping the current configuration", zap.Error(err))
+		return err
+	}
+
+	result := ResultSuccess
+	if settings := r.current.RestartRequired(next); len(settings) > 0 {
+		result = ResultPartial
+		r.logger.Warn("Configuration reload skipped settings that only change on restart",
+			zap.Strings("settings", settings),
+		)
+	}
+
+	applied := r.current.WithReloadable(next)
+	for _, hook := range r.hooks {
+		hook(applied)
+	}
+	r.current = applied
+
+	r.reloadTotal.WithLabelValues(result).Inc()
+	r.lastReload.SetToCurrentTime()
+	r.logger.Info("Configuration reloaded")
+	return nil
+}
+
+// Start reloads the configuration in the background on SIGHUP and when the config file changes
+func (r *Reloader) Start() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go r.run(signals)
+}
+
+// Stop stops watching for changes and waits for a reload in progress or the context to expire
+func (r *Reloader) Stop(ctx context.Context) error {
+	r.stopOnce.Do(func() { close(r.done) })
+
+	select {
+	case <-r.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run reloads on every signal received and whenever the config file content changes
+func (r *Reloader) run(signals chan os.Signal) {
+	defer close(r.stopped)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(r.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-signals:
+			r.logger.Info("Reloading configuration on SIGHUP")
+			// The file content is reloaded now, so the next check must not reload it again
+			if hash, err := r.hashFile(); err == nil {
+				r.fileHash = hash
+			}
+			_ = r.Reload()
+		case <-ticker.C:
+			// Compare content rather than modification times, which do not change when Kubernetes
+			// swaps the symlink of a mounted ConfigMap
+			hash, err := r.hashFile()
+			if err != nil || hash == r.fileHash {
+				continue
+			}
+			r.fileHash = hash
+			r.logger.Info("Reloading configuration, config file changed", zap.String("file", r.sources.ConfigFile()))
+			_ = r.Reload()
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// hashFile hashes the content of the config file; without a config file the hash is always zero
+func (r *Reloader) hashFile() ([sha256.Size]byte, error) {
+	file := r.sources.ConfigFile()
+	if file == "" {
+		return [sha256.Size]byte{}, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
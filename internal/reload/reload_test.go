@@ -0,0 +1,163 @@
+package reload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-grafana/internal/config"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+// newTestReloader writes the config file and returns a reloader for it with the configuration it loads
+func newTestReloader(t *testing.T, content string) (*Reloader, string) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, file, content)
+
+	sources := config.Sources{File: file}
+	cfg, err := config.Load(sources)
+	if err != nil {
+		t.Fatalf("failed to load the config: %v", err)
+	}
+	return NewReloader(sources, cfg, prometheus.NewRegistry(), zap.NewNop()), file
+}
+
+func writeConfig(t *testing.T, file, content string) {
+	t.Helper()
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write the config: %v", err)
+	}
+}
+
+func TestReloader_Reload(t *testing.T) {
+	reloader, file := newTestReloader(t, "logging:\n  level: info\n")
+
+	var applied *config.Config
+	reloader.OnReload(func(cfg *config.Config) {
+		applied = cfg
+	})
+
+	writeConfig(t, file, "logging:\n  level: debug\nrate_limit:\n  burst: 50\n")
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if applied == nil || applied.Logging.Level != "debug" || applied.RateLimit.Burst != 50 {
+		t.Fatalf("expected the hook to get the new settings, got %+v", applied)
+	}
+	if reloader.Current() != applied {
+		t.Error("expected the reloaded configuration to be current")
+	}
+	if got := testutil.ToFloat64(reloader.reloadTotal.WithLabelValues(ResultSuccess)); got != 1 {
+		t.Errorf("expected 1 successful reload, got %v", got)
+	}
+	if got := testutil.ToFloat64(reloader.lastReload); got == 0 {
+		t.Error("expected the last reload timestamp to be set")
+	}
+}
+
+func TestReloader_Reload_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		result  string
+		wantErr string
+	}{
+		{"invalid", "logging:\n  level: verbose\n", ResultError, "logging.level"},
+		{"malformed", "logging: [\n", ResultError, "config file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reloader, file := newTestReloader(t, "logging:\n  level: info\n")
+			previous := reloader.Current()
+			reloader.OnReload(func(*config.Config) {
+				t.Error("expected the hooks not to run")
+			})
+
+			writeConfig(t, file, tt.content)
+			err := reloader.Reload()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error about %s, got %v", tt.wantErr, err)
+			}
+			if reloader.Current() != previous {
+				t.Error("expected the current configuration to be kept")
+			}
+			if got := testutil.ToFloat64(reloader.reloadTotal.WithLabelValues(tt.result)); got != 1 {
+				t.Errorf("expected 1 %s reload, got %v", tt.result, got)
+			}
+		})
+	}
+}
+
+func TestReloader_Reload_RestartRequired(t *testing.T) {
+	reloader, file := newTestReloader(t, "logging:\n  level: info\nserver:\n  port: \"8080\"\n")
+
+	var applied *config.Config
+	reloader.OnReload(func(cfg *config.Config) {
+		applied = cfg
+	})
+
+	// The runtime settings are applied while the port change waits for a restart
+	writeConfig(t, file, "logging:\n  level: debug\nserver:\n  port: \"9090\"\n")
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if applied == nil || applied.Logging.Level != "debug" {
+		t.Fatalf("expected the hook to get the new log level, got %+v", applied)
+	}
+	if applied.Server.Port != "8080" {
+		t.Errorf("expected the running port 8080 to be kept, got %s", applied.Server.Port)
+	}
+	if reloader.Current() != applied {
+		t.Error("expected the applied configuration to be current")
+	}
+	if got := testutil.ToFloat64(reloader.reloadTotal.WithLabelValues(ResultPartial)); got != 1 {
+		t.Errorf("expected 1 partial reload, got %v", got)
+	}
+
+	// A pending restart-only change does not block later runtime changes
+	writeConfig(t, file, "logging:\n  level: warn\nserver:\n  port: \"9090\"\nrate_limit:\n  burst: 50\n")
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if applied.Logging.Level != "warn" || applied.RateLimit.Burst != 50 {
+		t.Errorf("expected the later runtime settings to be applied, got %+v", applied)
+	}
+	if got := testutil.ToFloat64(reloader.reloadTotal.WithLabelValues(ResultPartial)); got != 2 {
+		t.Errorf("expected 2 partial reloads, got %v", got)
+	}
+}
+
+func TestReloader_WatchFile(t *testing.T) {
+	reloader, file := newTestReloader(t, "logging:\n  level: info\n")
+	reloader.checkInterval = 10 * time.Millisecond
+
+	reloaded := make(chan string, 1)
+	reloader.OnReload(func(cfg *config.Config) {
+		reloaded <- cfg.Logging.Level
+	})
+
+	reloader.Start()
+	defer func() {
+		if err := reloader.Stop(context.Background()); err != nil {
+			t.Errorf("unexpected error on stop: %v", err)
+		}
+	}()
+
+	writeConfig(t, file, "logging:\n  level: warn\n")
+	select {
+	case level := <-reloaded:
+		if level != "warn" {
+			t.Errorf("expected level warn, got %s", level)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the config file change to be reloaded")
+	}
+}
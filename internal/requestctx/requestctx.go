@@ -0,0 +1,41 @@
+// Package requestctx carries request-scoped values, the request ID and a logger tagged with it,
+// through the context of a request
+package requestctx
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// contextKey is the type of the context keys defined by this package
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	loggerKey
+)
+
+// WithRequestID returns a copy of ctx that carries the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestID returns the request ID carried by ctx, or an empty string if there is none
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// WithLogger returns a copy of ctx that carries a request-scoped logger
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// Logger returns the request-scoped logger carried by ctx, or fallback if there is none
+func Logger(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*zap.Logger); ok {
+		return logger
+	}
+	return fallback
+}
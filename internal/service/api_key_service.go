@@ -1,8 +1,12 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"time"
 
+	"go-grafana/internal/config"
 	"go-grafana/internal/domain/models"
 	"go-grafana/internal/domain/repository"
 	"go-grafana/internal/util"
@@ -10,30 +14,53 @@ import (
 
 // APIKeyService defines the interface for API key business operations
 type APIKeyService interface {
-	CreateAPIKey(req *models.CreateAPIKeyRequest) (*models.APIKeyResponse, error)
-	GetAPIKeyByID(id uint) (*models.APIKeyResponse, error)
-	GetAllAPIKeys() ([]*models.APIKeyResponse, error)
-	UpdateAPIKey(id uint, req *models.UpdateAPIKeyRequest) (*models.APIKeyResponse, error)
-	DeleteAPIKey(id uint) error
-	ValidateAPIKey(key string) (*models.APIKey, error)
+	CreateAPIKey(ctx context.Context, req *models.CreateAPIKeyRequest) (*models.APIKeyResponse, error)
+	GetAPIKeyByID(ctx context.Context, id uint) (*models.APIKeyResponse, error)
+	ListAPIKeys(ctx context.Context, req *models.ListAPIKeysRequest) (*models.APIKeyListResponse, error)
+	UpdateAPIKey(ctx context.Context, id uint, req *models.UpdateAPIKeyRequest) (*models.APIKeyResponse, error)
+	DeleteAPIKey(ctx context.Context, id uint) error
+	RevokeAPIKey(ctx context.Context, id uint) (*models.APIKeyResponse, error)
+	RotateAPIKey(ctx context.Context, id uint, req *models.RotateAPIKeyRequest) (*models.APIKeyResponse, error)
+	EnsureAPIKey(ctx context.Context, plainTextKey, name string, scopes []string) (bool, error)
+	ValidateAPIKey(ctx context.Context, key string) (*models.APIKey, error)
 }
 
+// minSeedKeyLength is the shortest plaintext key accepted by EnsureAPIKey
+const minSeedKeyLength = 32
+
+// ErrInvalidScope is returned when a request grants a scope the API does not know about
+var ErrInvalidScope = newValidationSentinel("invalid scope")
+
+// ErrInvalidAPIKey is returned when an API key is unknown, inactive or expired
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// ErrInvalidGracePeriod is returned when a rotation asks for a grace period that has already ended
+var ErrInvalidGracePeriod = newValidationSentinel("invalid grace period")
+
 // apiKeyService implements APIKeyService
 type apiKeyService struct {
-	apiKeyRepo repository.APIKeyRepository
+	apiKeyRepo          repository.APIKeyRepository
+	cursors             *util.CursorCodec
+	rotationGracePeriod time.Duration
 }
 
 // NewAPIKeyService creates a new instance of APIKeyService
-func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository) APIKeyService {
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, cursors *util.CursorCodec, cfg *config.Config) APIKeyService {
 	return &apiKeyService{
-		apiKeyRepo: apiKeyRepo,
+		apiKeyRepo:          apiKeyRepo,
+		cursors:             cursors,
+		rotationGracePeriod: cfg.APIKeys.RotationGracePeriod,
 	}
 }
 
 // CreateAPIKey creates a new API key
-func (s *apiKeyService) CreateAPIKey(req *models.CreateAPIKeyRequest) (*models.APIKeyResponse, error) {
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, req *models.CreateAPIKeyRequest) (*models.APIKeyResponse, error) {
 	if req.Name == "" {
-		return nil, errors.New("name is required")
+		return nil, NewValidationError("name", "name is required")
+	}
+
+	if err := validateScopes(req.Scopes); err != nil {
+		return nil, err
 	}
 
 	apiKey := &models.APIKey{}
@@ -42,7 +69,7 @@ func (s *apiKeyService) CreateAPIKey(req *models.CreateAPIKeyRequest) (*models.A
 		return nil, err
 	}
 
-	err = s.apiKeyRepo.Create(apiKey)
+	err = s.apiKeyRepo.Create(ctx, apiKey)
 	if err != nil {
 		return nil, err
 	}
@@ -51,12 +78,12 @@ func (s *apiKeyService) CreateAPIKey(req *models.CreateAPIKeyRequest) (*models.A
 }
 
 // GetAPIKeyByID retrieves an API key by its ID
-func (s *apiKeyService) GetAPIKeyByID(id uint) (*models.APIKeyResponse, error) {
+func (s *apiKeyService) GetAPIKeyByID(ctx context.Context, id uint) (*models.APIKeyResponse, error) {
 	if id == 0 {
-		return nil, errors.New("invalid API key ID")
+		return nil, NewValidationError("id", "invalid API key ID")
 	}
 
-	apiKey, err := s.apiKeyRepo.GetByID(id)
+	apiKey, err := s.apiKeyRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -64,33 +91,62 @@ func (s *apiKeyService) GetAPIKeyByID(id uint) (*models.APIKeyResponse, error) {
 	return apiKey.ToResponseWithoutKey(), nil
 }
 
-// GetAllAPIKeys retrieves all API keys
-func (s *apiKeyService) GetAllAPIKeys() ([]*models.APIKeyResponse, error) {
-	apiKeys, err := s.apiKeyRepo.GetAll()
+// ListAPIKeys retrieves a page of API keys in (created_at, id) order
+func (s *apiKeyService) ListAPIKeys(ctx context.Context, req *models.ListAPIKeysRequest) (*models.APIKeyListResponse, error) {
+	if req == nil {
+		req = &models.ListAPIKeysRequest{}
+	}
+
+	after, err := decodeCursor(s.cursors, req.Cursor)
 	if err != nil {
 		return nil, err
 	}
 
+	// Fetch one extra row to find out whether another page exists
+	limit := pageSizeOrDefault(req.Limit)
+	filter := &models.APIKeyFilter{UnusedSince: req.UnusedSince, Limit: limit + 1}
+	apiKeys, err := s.apiKeyRepo.ListAfter(ctx, filter, after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+
+	var nextCursor string
+	if len(apiKeys) > limit {
+		apiKeys = apiKeys[:limit]
+		last := apiKeys[len(apiKeys)-1]
+		nextCursor = s.cursors.Encode(last.CreatedAt, last.ID)
+	}
+
 	responses := make([]*models.APIKeyResponse, len(apiKeys))
 	for i, apiKey := range apiKeys {
 		responses[i] = apiKey.ToResponseWithoutKey()
 	}
 
-	return responses, nil
+	return &models.APIKeyListResponse{
+		Data: responses,
+		Pagination: models.PaginationMeta{
+			Limit:      limit,
+			NextCursor: nextCursor,
+		},
+	}, nil
 }
 
 // UpdateAPIKey updates an existing API key
-func (s *apiKeyService) UpdateAPIKey(id uint, req *models.UpdateAPIKeyRequest) (*models.APIKeyResponse, error) {
+func (s *apiKeyService) UpdateAPIKey(ctx context.Context, id uint, req *models.UpdateAPIKeyRequest) (*models.APIKeyResponse, error) {
 	if id == 0 {
-		return nil, errors.New("invalid API key ID")
+		return nil, NewValidationError("id", "invalid API key ID")
 	}
 
 	if req.Name == "" {
-		return nil, errors.New("name is required")
+		return nil, NewValidationError("name", "name is required")
+	}
+
+	if err := validateScopes(req.Scopes); err != nil {
+		return nil, err
 	}
 
 	// Get existing API key
-	existing, err := s.apiKeyRepo.GetByID(id)
+	existing, err := s.apiKeyRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -98,7 +154,7 @@ func (s *apiKeyService) UpdateAPIKey(id uint, req *models.UpdateAPIKeyRequest) (
 	// Update with new data
 	existing.FromUpdateRequest(req)
 
-	err = s.apiKeyRepo.Update(existing)
+	err = s.apiKeyRepo.Update(ctx, existing)
 	if err != nil {
 		return nil, err
 	}
@@ -107,30 +163,160 @@ func (s *apiKeyService) UpdateAPIKey(id uint, req *models.UpdateAPIKeyRequest) (
 }
 
 // DeleteAPIKey deletes an API key
-func (s *apiKeyService) DeleteAPIKey(id uint) error {
+func (s *apiKeyService) DeleteAPIKey(ctx context.Context, id uint) error {
+	if id == 0 {
+		return NewValidationError("id", "invalid API key ID")
+	}
+
+	return s.apiKeyRepo.Delete(ctx, id)
+}
+
+// RevokeAPIKey deactivates an API key while keeping it for auditing
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, id uint) (*models.APIKeyResponse, error) {
 	if id == 0 {
-		return errors.New("invalid API key ID")
+		return nil, NewValidationError("id", "invalid API key ID")
+	}
+
+	existing, err := s.apiKeyRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	existing.Active = false
+	if err := s.apiKeyRepo.Update(ctx, existing); err != nil {
+		return nil, err
 	}
 
-	return s.apiKeyRepo.Delete(id)
+	return existing.ToResponseWithoutKey(), nil
+}
+
+// RotateAPIKey replaces the secret of an API key, keeping its ID and metadata.
+// The new plaintext key is returned once; the old one keeps validating until the grace period ends.
+func (s *apiKeyService) RotateAPIKey(ctx context.Context, id uint, req *models.RotateAPIKeyRequest) (*models.APIKeyResponse, error) {
+	if id == 0 {
+		return nil, NewValidationError("id", "invalid API key ID")
+	}
+
+	graceUntil, err := s.graceUntil(req)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.apiKeyRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	plainTextKey, err := util.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	existing.RotateKey(util.HashAPIKey(plainTextKey), graceUntil)
+
+	if err := s.apiKeyRepo.UpdateKey(ctx, existing); err != nil {
+		return nil, err
+	}
+
+	return existing.ToResponseWithKey(plainTextKey), nil
+}
+
+// graceUntil resolves how long the previous key stays valid after a rotation.
+// A nil result means the previous key stops working immediately.
+func (s *apiKeyService) graceUntil(req *models.RotateAPIKeyRequest) (*time.Time, error) {
+	now := time.Now()
+	if req != nil && req.HardCutover {
+		if req.GraceUntil != nil {
+			return nil, fmt.Errorf("%w: grace_until cannot be combined with a hard cutover", ErrInvalidGracePeriod)
+		}
+		return nil, nil
+	}
+	if req != nil && req.GraceUntil != nil {
+		if !req.GraceUntil.After(now) {
+			return nil, fmt.Errorf("%w: grace_until must be in the future", ErrInvalidGracePeriod)
+		}
+		return req.GraceUntil, nil
+	}
+
+	if s.rotationGracePeriod <= 0 {
+		return nil, nil
+	}
+	graceUntil := now.Add(s.rotationGracePeriod)
+	return &graceUntil, nil
+}
+
+// EnsureAPIKey creates an API key with a caller supplied plaintext secret unless a key with the
+// same secret already exists, including one that has since been deleted. It returns true if the key was created.
+func (s *apiKeyService) EnsureAPIKey(ctx context.Context, plainTextKey, name string, scopes []string) (bool, error) {
+	if len(plainTextKey) < minSeedKeyLength {
+		return false, NewValidationError("key", fmt.Sprintf("API key must be at least %d characters long", minSeedKeyLength))
+	}
+
+	if err := validateScopes(scopes); err != nil {
+		return false, err
+	}
+
+	hashedKey := util.HashAPIKey(plainTextKey)
+	if s.apiKeyRepo.ExistsByKey(ctx, hashedKey) {
+		return false, nil
+	}
+
+	apiKey := &models.APIKey{
+		Name:   name,
+		Key:    hashedKey,
+		Scopes: models.NewScopes(scopes),
+		Active: true,
+	}
+	if err := s.apiKeyRepo.Create(ctx, apiKey); err != nil {
+		// Another replica seeded the same key between the check and the insert
+		if errors.Is(err, repository.ErrConflict) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
 }
 
 // ValidateAPIKey validates an API key and returns the API key object if valid
-func (s *apiKeyService) ValidateAPIKey(key string) (*models.APIKey, error) {
+func (s *apiKeyService) ValidateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
 	if key == "" {
-		return nil, errors.New("API key is required")
+		return nil, NewValidationError("key", "API key is required")
 	}
 
 	hashedKey := util.HashAPIKey(key)
 
-	apiKey, err := s.apiKeyRepo.GetByKey(hashedKey)
+	apiKey, err := s.apiKeyRepo.GetByKey(ctx, hashedKey)
 	if err != nil {
-		return nil, errors.New("invalid API key")
+		if !errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("failed to look up API key: %w", err)
+		}
+
+		// Fall back to a key that was rotated recently and is still within its grace period
+		apiKey, err = s.apiKeyRepo.GetByPreviousKey(ctx, hashedKey)
+		if err != nil {
+			if !errors.Is(err, repository.ErrNotFound) {
+				return nil, fmt.Errorf("failed to look up API key: %w", err)
+			}
+			return nil, ErrInvalidAPIKey
+		}
+		if !apiKey.InGracePeriod() {
+			return nil, ErrInvalidAPIKey
+		}
 	}
 
 	if !apiKey.IsValid() {
-		return nil, errors.New("API key is not valid")
+		return nil, fmt.Errorf("%w: key is inactive or expired", ErrInvalidAPIKey)
 	}
 
 	return apiKey, nil
 }
+
+// validateScopes checks that every requested scope is known
+func validateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !models.IsValidScope(scope) {
+			return fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+	}
+	return nil
+}
@@ -1,51 +1,72 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
+	"go-grafana/internal/config"
 	"go-grafana/internal/domain/models"
+	"go-grafana/internal/domain/repository"
+	"go-grafana/internal/domain/repository/memory"
 	"go-grafana/internal/util"
 )
 
 // MockAPIKeyRepository is a mock implementation of APIKeyRepository for testing
 type MockAPIKeyRepository struct {
-	CreateFunc      func(apiKey *models.APIKey) error
-	GetByIDFunc     func(id uint) (*models.APIKey, error)
-	GetByKeyFunc    func(key string) (*models.APIKey, error)
-	GetAllFunc      func() ([]*models.APIKey, error)
-	UpdateFunc      func(apiKey *models.APIKey) error
-	DeleteFunc      func(id uint) error
-	ExistsByKeyFunc func(key string) bool
+	CreateFunc           func(apiKey *models.APIKey) error
+	GetByIDFunc          func(id uint) (*models.APIKey, error)
+	GetByKeyFunc         func(key string) (*models.APIKey, error)
+	GetByPreviousKeyFunc func(key string) (*models.APIKey, error)
+	ListAfterFunc        func(filter *models.APIKeyFilter, after *models.Cursor) ([]*models.APIKey, error)
+	UpdateFunc           func(apiKey *models.APIKey) error
+	RecordUsageFunc      func(usages []models.APIKeyUsage) error
+	UpdateKeyFunc        func(apiKey *models.APIKey) error
+	DeleteFunc           func(id uint) error
+	ExistsByKeyFunc      func(key string) bool
 }
 
-func (m *MockAPIKeyRepository) Create(apiKey *models.APIKey) error {
+func (m *MockAPIKeyRepository) Create(ctx context.Context, apiKey *models.APIKey) error {
 	return m.CreateFunc(apiKey)
 }
-func (m *MockAPIKeyRepository) GetByID(id uint) (*models.APIKey, error) {
+func (m *MockAPIKeyRepository) GetByID(ctx context.Context, id uint) (*models.APIKey, error) {
 	return m.GetByIDFunc(id)
 }
-func (m *MockAPIKeyRepository) GetByKey(key string) (*models.APIKey, error) {
+func (m *MockAPIKeyRepository) GetByKey(ctx context.Context, key string) (*models.APIKey, error) {
 	return m.GetByKeyFunc(key)
 }
-func (m *MockAPIKeyRepository) GetAll() ([]*models.APIKey, error) {
-	return m.GetAllFunc()
+func (m *MockAPIKeyRepository) GetByPreviousKey(ctx context.Context, key string) (*models.APIKey, error) {
+	return m.GetByPreviousKeyFunc(key)
 }
-func (m *MockAPIKeyRepository) Update(apiKey *models.APIKey) error {
+func (m *MockAPIKeyRepository) ListAfter(ctx context.Context, filter *models.APIKeyFilter, after *models.Cursor) ([]*models.APIKey, error) {
+	return m.ListAfterFunc(filter, after)
+}
+func (m *MockAPIKeyRepository) Update(ctx context.Context, apiKey *models.APIKey) error {
 	return m.UpdateFunc(apiKey)
 }
-func (m *MockAPIKeyRepository) Delete(id uint) error {
+func (m *MockAPIKeyRepository) UpdateKey(ctx context.Context, apiKey *models.APIKey) error {
+	return m.UpdateKeyFunc(apiKey)
+}
+func (m *MockAPIKeyRepository) RecordUsage(ctx context.Context, usages []models.APIKeyUsage) error {
+	return m.RecordUsageFunc(usages)
+}
+func (m *MockAPIKeyRepository) Delete(ctx context.Context, id uint) error {
 	return m.DeleteFunc(id)
 }
-func (m *MockAPIKeyRepository) ExistsByKey(key string) bool {
+func (m *MockAPIKeyRepository) ExistsByKey(ctx context.Context, key string) bool {
 	return m.ExistsByKeyFunc(key)
 }
 
+var testCursors = util.NewCursorCodec([]byte("test-secret"))
+
+var testConfig = &config.Config{APIKeys: config.APIKeyConfig{RotationGracePeriod: time.Hour}}
+
 func TestNewAPIKeyService(t *testing.T) {
 	mockRepo := &MockAPIKeyRepository{}
-	service := NewAPIKeyService(mockRepo)
+	service := NewAPIKeyService(mockRepo, testCursors, testConfig)
 	if service == nil {
 		t.Error("NewAPIKeyService() returned nil")
 	}
@@ -53,7 +74,7 @@ func TestNewAPIKeyService(t *testing.T) {
 
 func TestAPIKeyService_CreateAPIKey(t *testing.T) {
 	mockRepo := &MockAPIKeyRepository{}
-	service := NewAPIKeyService(mockRepo)
+	service := NewAPIKeyService(mockRepo, testCursors, testConfig)
 
 	t.Run("success", func(t *testing.T) {
 		req := &models.CreateAPIKeyRequest{Name: "test key"}
@@ -62,7 +83,7 @@ func TestAPIKeyService_CreateAPIKey(t *testing.T) {
 			return nil
 		}
 
-		resp, err := service.CreateAPIKey(req)
+		resp, err := service.CreateAPIKey(context.Background(), req)
 		if err != nil {
 			t.Fatalf("CreateAPIKey() error = %v, wantErr %v", err, false)
 		}
@@ -76,18 +97,44 @@ func TestAPIKeyService_CreateAPIKey(t *testing.T) {
 
 	t.Run("empty name", func(t *testing.T) {
 		req := &models.CreateAPIKeyRequest{Name: ""}
-		_, err := service.CreateAPIKey(req)
+		_, err := service.CreateAPIKey(context.Background(), req)
 		if err == nil {
 			t.Error("expected an error for empty name, got nil")
 		}
 	})
 
+	t.Run("scopes", func(t *testing.T) {
+		req := &models.CreateAPIKeyRequest{Name: "test key", Scopes: []string{models.ScopeUsersWrite, models.ScopeUsersWrite}}
+		mockRepo.CreateFunc = func(apiKey *models.APIKey) error {
+			if !reflect.DeepEqual(apiKey.Scopes, models.Scopes{models.ScopeUsersWrite}) {
+				t.Errorf("expected de-duplicated scopes, got %v", apiKey.Scopes)
+			}
+			return nil
+		}
+
+		resp, err := service.CreateAPIKey(context.Background(), req)
+		if err != nil {
+			t.Fatalf("CreateAPIKey() error = %v", err)
+		}
+		if !reflect.DeepEqual(resp.Scopes, []string{models.ScopeUsersWrite}) {
+			t.Errorf("expected scopes in response, got %v", resp.Scopes)
+		}
+	})
+
+	t.Run("unknown scope", func(t *testing.T) {
+		req := &models.CreateAPIKeyRequest{Name: "test key", Scopes: []string{"users:delete"}}
+		_, err := service.CreateAPIKey(context.Background(), req)
+		if !errors.Is(err, ErrInvalidScope) {
+			t.Errorf("expected ErrInvalidScope, got %v", err)
+		}
+	})
+
 	t.Run("repository create error", func(t *testing.T) {
 		req := &models.CreateAPIKeyRequest{Name: "test key"}
 		mockRepo.CreateFunc = func(apiKey *models.APIKey) error {
 			return errors.New("db error")
 		}
-		_, err := service.CreateAPIKey(req)
+		_, err := service.CreateAPIKey(context.Background(), req)
 		if err == nil {
 			t.Error("expected a repository error, got nil")
 		}
@@ -96,7 +143,7 @@ func TestAPIKeyService_CreateAPIKey(t *testing.T) {
 
 func TestAPIKeyService_GetAPIKeyByID(t *testing.T) {
 	mockRepo := &MockAPIKeyRepository{}
-	service := NewAPIKeyService(mockRepo)
+	service := NewAPIKeyService(mockRepo, testCursors, testConfig)
 
 	t.Run("success", func(t *testing.T) {
 		expectedAPIKey := &models.APIKey{ID: 1, Name: "test"}
@@ -104,9 +151,9 @@ func TestAPIKeyService_GetAPIKeyByID(t *testing.T) {
 			if id == 1 {
 				return expectedAPIKey, nil
 			}
-			return nil, errors.New("not found")
+			return nil, repository.ErrAPIKeyNotFound
 		}
-		resp, err := service.GetAPIKeyByID(1)
+		resp, err := service.GetAPIKeyByID(context.Background(), 1)
 		if err != nil {
 			t.Fatalf("GetAPIKeyByID() error = %v", err)
 		}
@@ -116,7 +163,7 @@ func TestAPIKeyService_GetAPIKeyByID(t *testing.T) {
 	})
 
 	t.Run("invalid id", func(t *testing.T) {
-		_, err := service.GetAPIKeyByID(0)
+		_, err := service.GetAPIKeyByID(context.Background(), 0)
 		if err == nil {
 			t.Error("expected error for invalid id, got nil")
 		}
@@ -124,38 +171,95 @@ func TestAPIKeyService_GetAPIKeyByID(t *testing.T) {
 
 	t.Run("not found", func(t *testing.T) {
 		mockRepo.GetByIDFunc = func(id uint) (*models.APIKey, error) {
-			return nil, errors.New("not found")
+			return nil, repository.ErrAPIKeyNotFound
 		}
-		_, err := service.GetAPIKeyByID(99)
+		_, err := service.GetAPIKeyByID(context.Background(), 99)
 		if err == nil {
 			t.Error("expected error for not found, got nil")
 		}
 	})
 }
 
-func TestAPIKeyService_GetAllAPIKeys(t *testing.T) {
+func TestAPIKeyService_ListAPIKeys(t *testing.T) {
 	mockRepo := &MockAPIKeyRepository{}
-	service := NewAPIKeyService(mockRepo)
+	service := NewAPIKeyService(mockRepo, testCursors, testConfig)
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
 
 	t.Run("success", func(t *testing.T) {
 		keys := []*models.APIKey{{ID: 1}, {ID: 2}}
-		mockRepo.GetAllFunc = func() ([]*models.APIKey, error) {
+		mockRepo.ListAfterFunc = func(filter *models.APIKeyFilter, after *models.Cursor) ([]*models.APIKey, error) {
+			if after != nil {
+				t.Errorf("expected first page to have no cursor, got %+v", after)
+			}
 			return keys, nil
 		}
-		resps, err := service.GetAllAPIKeys()
+		resp, err := service.ListAPIKeys(context.Background(), &models.ListAPIKeysRequest{})
 		if err != nil {
-			t.Fatalf("GetAllAPIKeys() error = %v", err)
+			t.Fatalf("ListAPIKeys() error = %v", err)
+		}
+		if len(resp.Data) != 2 {
+			t.Fatalf("expected 2 keys, got %d", len(resp.Data))
 		}
-		if len(resps) != 2 {
-			t.Fatalf("expected 2 keys, got %d", len(resps))
+		if resp.Pagination.NextCursor != "" {
+			t.Errorf("expected no next cursor, got %q", resp.Pagination.NextCursor)
+		}
+	})
+
+	t.Run("unused since filter", func(t *testing.T) {
+		unusedSince := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+		mockRepo.ListAfterFunc = func(filter *models.APIKeyFilter, after *models.Cursor) ([]*models.APIKey, error) {
+			if filter.UnusedSince == nil || !filter.UnusedSince.Equal(unusedSince) {
+				t.Errorf("expected unused_since to be passed through, got %v", filter.UnusedSince)
+			}
+			return nil, nil
+		}
+		if _, err := service.ListAPIKeys(context.Background(), &models.ListAPIKeysRequest{UnusedSince: &unusedSince}); err != nil {
+			t.Fatalf("ListAPIKeys() error = %v", err)
+		}
+	})
+
+	t.Run("next cursor round trip", func(t *testing.T) {
+		mockRepo.ListAfterFunc = func(filter *models.APIKeyFilter, after *models.Cursor) ([]*models.APIKey, error) {
+			if filter.Limit != 3 {
+				t.Errorf("expected one extra row to be requested, got limit %d", filter.Limit)
+			}
+			return []*models.APIKey{
+				{ID: 1, CreatedAt: createdAt},
+				{ID: 2, CreatedAt: createdAt},
+				{ID: 3, CreatedAt: createdAt},
+			}, nil
+		}
+		resp, err := service.ListAPIKeys(context.Background(), &models.ListAPIKeysRequest{Limit: 2})
+		if err != nil {
+			t.Fatalf("ListAPIKeys() error = %v", err)
+		}
+		if len(resp.Data) != 2 || resp.Pagination.NextCursor == "" {
+			t.Fatalf("expected 2 keys and a next cursor, got %d keys and cursor %q", len(resp.Data), resp.Pagination.NextCursor)
+		}
+
+		mockRepo.ListAfterFunc = func(filter *models.APIKeyFilter, after *models.Cursor) ([]*models.APIKey, error) {
+			if after == nil || after.ID != 2 || !after.CreatedAt.Equal(createdAt) {
+				t.Errorf("expected cursor after key 2, got %+v", after)
+			}
+			return nil, nil
+		}
+		if _, err := service.ListAPIKeys(context.Background(), &models.ListAPIKeysRequest{Limit: 2, Cursor: resp.Pagination.NextCursor}); err != nil {
+			t.Fatalf("ListAPIKeys() error = %v", err)
+		}
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		_, err := service.ListAPIKeys(context.Background(), &models.ListAPIKeysRequest{Cursor: "forged"})
+		if !errors.Is(err, ErrInvalidListQuery) {
+			t.Errorf("expected ErrInvalidListQuery, got %v", err)
 		}
 	})
 
 	t.Run("db error", func(t *testing.T) {
-		mockRepo.GetAllFunc = func() ([]*models.APIKey, error) {
+		mockRepo.ListAfterFunc = func(filter *models.APIKeyFilter, after *models.Cursor) ([]*models.APIKey, error) {
 			return nil, errors.New("db error")
 		}
-		_, err := service.GetAllAPIKeys()
+		_, err := service.ListAPIKeys(context.Background(), &models.ListAPIKeysRequest{})
 		if err == nil {
 			t.Error("expected db error, got nil")
 		}
@@ -164,7 +268,7 @@ func TestAPIKeyService_GetAllAPIKeys(t *testing.T) {
 
 func TestAPIKeyService_UpdateAPIKey(t *testing.T) {
 	mockRepo := &MockAPIKeyRepository{}
-	service := NewAPIKeyService(mockRepo)
+	service := NewAPIKeyService(mockRepo, testCursors, testConfig)
 
 	t.Run("success", func(t *testing.T) {
 		existingKey := &models.APIKey{ID: 1, Name: "old name"}
@@ -176,7 +280,7 @@ func TestAPIKeyService_UpdateAPIKey(t *testing.T) {
 		}
 
 		req := &models.UpdateAPIKeyRequest{Name: "new name"}
-		resp, err := service.UpdateAPIKey(1, req)
+		resp, err := service.UpdateAPIKey(context.Background(), 1, req)
 		if err != nil {
 			t.Fatalf("UpdateAPIKey() error = %v", err)
 		}
@@ -187,7 +291,7 @@ func TestAPIKeyService_UpdateAPIKey(t *testing.T) {
 
 	t.Run("invalid id", func(t *testing.T) {
 		req := &models.UpdateAPIKeyRequest{Name: "new name"}
-		_, err := service.UpdateAPIKey(0, req)
+		_, err := service.UpdateAPIKey(context.Background(), 0, req)
 		if err == nil {
 			t.Error("expected error for invalid id, got nil")
 		}
@@ -196,29 +300,242 @@ func TestAPIKeyService_UpdateAPIKey(t *testing.T) {
 
 func TestAPIKeyService_DeleteAPIKey(t *testing.T) {
 	mockRepo := &MockAPIKeyRepository{}
-	service := NewAPIKeyService(mockRepo)
+	service := NewAPIKeyService(mockRepo, testCursors, testConfig)
 
 	t.Run("success", func(t *testing.T) {
 		mockRepo.DeleteFunc = func(id uint) error {
 			return nil
 		}
-		err := service.DeleteAPIKey(1)
+		err := service.DeleteAPIKey(context.Background(), 1)
 		if err != nil {
 			t.Fatalf("DeleteAPIKey() error = %v", err)
 		}
 	})
 
 	t.Run("invalid id", func(t *testing.T) {
-		err := service.DeleteAPIKey(0)
+		err := service.DeleteAPIKey(context.Background(), 0)
 		if err == nil {
 			t.Error("expected error for invalid id, got nil")
 		}
 	})
 }
 
+func TestAPIKeyService_RevokeAPIKey(t *testing.T) {
+	mockRepo := &MockAPIKeyRepository{}
+	service := NewAPIKeyService(mockRepo, testCursors, testConfig)
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo.GetByIDFunc = func(id uint) (*models.APIKey, error) {
+			return &models.APIKey{ID: id, Name: "ci", Active: true}, nil
+		}
+		var updated *models.APIKey
+		mockRepo.UpdateFunc = func(apiKey *models.APIKey) error {
+			updated = apiKey
+			return nil
+		}
+
+		resp, err := service.RevokeAPIKey(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("RevokeAPIKey() error = %v", err)
+		}
+		if resp.Active || updated == nil || updated.Active {
+			t.Errorf("expected key to be deactivated, got %+v", resp)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockRepo.GetByIDFunc = func(id uint) (*models.APIKey, error) {
+			return nil, repository.ErrAPIKeyNotFound
+		}
+		if _, err := service.RevokeAPIKey(context.Background(), 99); err == nil {
+			t.Error("expected error for missing key, got nil")
+		}
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		if _, err := service.RevokeAPIKey(context.Background(), 0); err == nil {
+			t.Error("expected error for invalid id, got nil")
+		}
+	})
+}
+
+func TestAPIKeyService_RotateAPIKey(t *testing.T) {
+	mockRepo := &MockAPIKeyRepository{}
+	service := NewAPIKeyService(mockRepo, testCursors, testConfig)
+
+	oldHash := util.HashAPIKey("old-key")
+	mockRepo.GetByIDFunc = func(id uint) (*models.APIKey, error) {
+		return &models.APIKey{ID: id, Name: "ci", Key: oldHash, Active: true}, nil
+	}
+	var stored *models.APIKey
+	mockRepo.UpdateKeyFunc = func(apiKey *models.APIKey) error {
+		stored = apiKey
+		return nil
+	}
+
+	t.Run("default grace period", func(t *testing.T) {
+		resp, err := service.RotateAPIKey(context.Background(), 1, nil)
+		if err != nil {
+			t.Fatalf("RotateAPIKey() error = %v", err)
+		}
+		if resp.Key == "" {
+			t.Fatal("expected the new plaintext key to be returned")
+		}
+		if stored.Key != util.HashAPIKey(resp.Key) || stored.PreviousKey != oldHash {
+			t.Errorf("expected new hash with old hash kept as previous key, got %+v", stored)
+		}
+		if resp.GraceUntil == nil || time.Until(*resp.GraceUntil) <= 59*time.Minute {
+			t.Errorf("expected a grace period of about an hour, got %v", resp.GraceUntil)
+		}
+	})
+
+	t.Run("explicit grace until", func(t *testing.T) {
+		graceUntil := time.Now().Add(48 * time.Hour)
+		resp, err := service.RotateAPIKey(context.Background(), 1, &models.RotateAPIKeyRequest{GraceUntil: &graceUntil})
+		if err != nil {
+			t.Fatalf("RotateAPIKey() error = %v", err)
+		}
+		if resp.GraceUntil == nil || !resp.GraceUntil.Equal(graceUntil) {
+			t.Errorf("expected grace until %v, got %v", graceUntil, resp.GraceUntil)
+		}
+	})
+
+	t.Run("grace until in the past", func(t *testing.T) {
+		graceUntil := time.Now().Add(-time.Minute)
+		_, err := service.RotateAPIKey(context.Background(), 1, &models.RotateAPIKeyRequest{GraceUntil: &graceUntil})
+		if !errors.Is(err, ErrInvalidGracePeriod) {
+			t.Errorf("expected ErrInvalidGracePeriod, got %v", err)
+		}
+	})
+
+	t.Run("explicit hard cutover", func(t *testing.T) {
+		resp, err := service.RotateAPIKey(context.Background(), 1, &models.RotateAPIKeyRequest{HardCutover: true})
+		if err != nil {
+			t.Fatalf("RotateAPIKey() error = %v", err)
+		}
+		if resp.GraceUntil != nil || stored.PreviousKey != "" {
+			t.Errorf("expected the previous key to be dropped, got %+v", stored)
+		}
+	})
+
+	t.Run("hard cutover without grace period", func(t *testing.T) {
+		service := NewAPIKeyService(mockRepo, testCursors, &config.Config{})
+		resp, err := service.RotateAPIKey(context.Background(), 1, nil)
+		if err != nil {
+			t.Fatalf("RotateAPIKey() error = %v", err)
+		}
+		if resp.GraceUntil != nil || stored.PreviousKey != "" {
+			t.Errorf("expected the previous key to be dropped, got %+v", stored)
+		}
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		if _, err := service.RotateAPIKey(context.Background(), 0, nil); err == nil {
+			t.Error("expected error for invalid id, got nil")
+		}
+	})
+}
+
+func TestAPIKeyService_EnsureAPIKey(t *testing.T) {
+	mockRepo := &MockAPIKeyRepository{}
+	service := NewAPIKeyService(mockRepo, testCursors, testConfig)
+
+	plainTextKey := "bootstrap-key-0123456789abcdefghij"
+
+	t.Run("creates missing key", func(t *testing.T) {
+		mockRepo.ExistsByKeyFunc = func(key string) bool { return false }
+		var created *models.APIKey
+		mockRepo.CreateFunc = func(apiKey *models.APIKey) error {
+			created = apiKey
+			return nil
+		}
+
+		ok, err := service.EnsureAPIKey(context.Background(), plainTextKey, "bootstrap", []string{models.ScopeAPIKeysAdmin})
+		if err != nil || !ok {
+			t.Fatalf("EnsureAPIKey() = %v, %v, want true, nil", ok, err)
+		}
+		if created.Key != util.HashAPIKey(plainTextKey) || !created.Active || !created.HasScopes(models.ScopeAPIKeysAdmin) {
+			t.Errorf("unexpected key created: %+v", created)
+		}
+	})
+
+	t.Run("existing key", func(t *testing.T) {
+		mockRepo.ExistsByKeyFunc = func(key string) bool { return true }
+		mockRepo.CreateFunc = func(apiKey *models.APIKey) error {
+			t.Error("Create should not be called for an existing key")
+			return nil
+		}
+
+		ok, err := service.EnsureAPIKey(context.Background(), plainTextKey, "bootstrap", nil)
+		if err != nil || ok {
+			t.Errorf("EnsureAPIKey() = %v, %v, want false, nil", ok, err)
+		}
+	})
+
+	t.Run("short key", func(t *testing.T) {
+		if _, err := service.EnsureAPIKey(context.Background(), "short", "bootstrap", nil); err == nil {
+			t.Error("expected error for short key, got nil")
+		}
+	})
+
+	t.Run("invalid scope", func(t *testing.T) {
+		_, err := service.EnsureAPIKey(context.Background(), plainTextKey, "bootstrap", []string{"root"})
+		if !errors.Is(err, ErrInvalidScope) {
+			t.Errorf("expected ErrInvalidScope, got %v", err)
+		}
+	})
+}
+
+// racingAPIKeyRepository holds every ExistsByKey caller until all of them have checked, so that they race to Create
+type racingAPIKeyRepository struct {
+	repository.APIKeyRepository
+	checked *sync.WaitGroup
+}
+
+func (r racingAPIKeyRepository) ExistsByKey(ctx context.Context, key string) bool {
+	exists := r.APIKeyRepository.ExistsByKey(ctx, key)
+	r.checked.Done()
+	r.checked.Wait()
+	return exists
+}
+
+func TestAPIKeyService_EnsureAPIKey_Concurrent(t *testing.T) {
+	const replicas = 2
+	var checked sync.WaitGroup
+	checked.Add(replicas)
+	repo := racingAPIKeyRepository{APIKeyRepository: memory.NewAPIKeyRepository(), checked: &checked}
+	service := NewAPIKeyService(repo, testCursors, testConfig)
+
+	type result struct {
+		created bool
+		err     error
+	}
+	results := make(chan result, replicas)
+	for i := 0; i < replicas; i++ {
+		go func() {
+			created, err := service.EnsureAPIKey(context.Background(), "bootstrap-key-0123456789abcdefghij", "bootstrap", nil)
+			results <- result{created, err}
+		}()
+	}
+
+	created := 0
+	for i := 0; i < replicas; i++ {
+		r := <-results
+		if r.err != nil {
+			t.Fatalf("EnsureAPIKey() error = %v", r.err)
+		}
+		if r.created {
+			created++
+		}
+	}
+	if created != 1 {
+		t.Errorf("expected exactly one call to create the key, got %d", created)
+	}
+}
+
 func TestAPIKeyService_ValidateAPIKey(t *testing.T) {
 	mockRepo := &MockAPIKeyRepository{}
-	service := NewAPIKeyService(mockRepo)
+	service := NewAPIKeyService(mockRepo, testCursors, testConfig)
 
 	plainTextKey := "valid-key"
 	hashedKey := util.HashAPIKey(plainTextKey)
@@ -229,10 +546,10 @@ func TestAPIKeyService_ValidateAPIKey(t *testing.T) {
 			if key == hashedKey {
 				return validKey, nil
 			}
-			return nil, errors.New("not found")
+			return nil, repository.ErrAPIKeyNotFound
 		}
 
-		apiKey, err := service.ValidateAPIKey(plainTextKey)
+		apiKey, err := service.ValidateAPIKey(context.Background(), plainTextKey)
 		if err != nil {
 			t.Fatalf("ValidateAPIKey() error = %v", err)
 		}
@@ -246,7 +563,7 @@ func TestAPIKeyService_ValidateAPIKey(t *testing.T) {
 		mockRepo.GetByKeyFunc = func(key string) (*models.APIKey, error) {
 			return inactiveKey, nil
 		}
-		_, err := service.ValidateAPIKey(plainTextKey)
+		_, err := service.ValidateAPIKey(context.Background(), plainTextKey)
 		if err == nil {
 			t.Error("expected error for inactive key, got nil")
 		}
@@ -258,14 +575,70 @@ func TestAPIKeyService_ValidateAPIKey(t *testing.T) {
 		mockRepo.GetByKeyFunc = func(key string) (*models.APIKey, error) {
 			return expiredKey, nil
 		}
-		_, err := service.ValidateAPIKey(plainTextKey)
+		_, err := service.ValidateAPIKey(context.Background(), plainTextKey)
 		if err == nil {
 			t.Error("expected error for expired key, got nil")
 		}
 	})
 
+	t.Run("unknown key", func(t *testing.T) {
+		mockRepo.GetByKeyFunc = func(key string) (*models.APIKey, error) {
+			return nil, repository.ErrAPIKeyNotFound
+		}
+		mockRepo.GetByPreviousKeyFunc = func(key string) (*models.APIKey, error) {
+			return nil, repository.ErrAPIKeyNotFound
+		}
+		if _, err := service.ValidateAPIKey(context.Background(), plainTextKey); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("expected ErrInvalidAPIKey, got %v", err)
+		}
+	})
+
+	t.Run("lookup failure", func(t *testing.T) {
+		mockRepo.GetByKeyFunc = func(key string) (*models.APIKey, error) {
+			return nil, errors.New("connection refused")
+		}
+		_, err := service.ValidateAPIKey(context.Background(), plainTextKey)
+		if err == nil || errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("expected a lookup error, got %v", err)
+		}
+	})
+
+	t.Run("previous key within grace period", func(t *testing.T) {
+		graceUntil := time.Now().Add(time.Hour)
+		rotatedKey := &models.APIKey{ID: 1, Key: "new-hash", PreviousKey: hashedKey, GraceUntil: &graceUntil, Active: true}
+		mockRepo.GetByKeyFunc = func(key string) (*models.APIKey, error) {
+			return nil, repository.ErrAPIKeyNotFound
+		}
+		mockRepo.GetByPreviousKeyFunc = func(key string) (*models.APIKey, error) {
+			return rotatedKey, nil
+		}
+
+		apiKey, err := service.ValidateAPIKey(context.Background(), plainTextKey)
+		if err != nil {
+			t.Fatalf("ValidateAPIKey() error = %v", err)
+		}
+		if apiKey.ID != 1 {
+			t.Errorf("expected key 1, got %d", apiKey.ID)
+		}
+	})
+
+	t.Run("previous key after grace period", func(t *testing.T) {
+		graceUntil := time.Now().Add(-time.Minute)
+		rotatedKey := &models.APIKey{ID: 1, Key: "new-hash", PreviousKey: hashedKey, GraceUntil: &graceUntil, Active: true}
+		mockRepo.GetByKeyFunc = func(key string) (*models.APIKey, error) {
+			return nil, repository.ErrAPIKeyNotFound
+		}
+		mockRepo.GetByPreviousKeyFunc = func(key string) (*models.APIKey, error) {
+			return rotatedKey, nil
+		}
+
+		if _, err := service.ValidateAPIKey(context.Background(), plainTextKey); err == nil {
+			t.Error("expected error for a previous key past its grace period, got nil")
+		}
+	})
+
 	t.Run("empty key", func(t *testing.T) {
-		_, err := service.ValidateAPIKey("")
+		_, err := service.ValidateAPIKey(context.Background(), "")
 		if err == nil {
 			t.Error("expected error for empty key, got nil")
 		}
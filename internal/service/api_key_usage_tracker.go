@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-grafana/internal/domain/models"
+	"go-grafana/internal/domain/repository"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// usageBufferSize is the number of usage events that can be queued before new ones are dropped
+	usageBufferSize = 4096
+	// usageBatchSize is the number of distinct keys that triggers a flush before the interval elapses
+	usageBatchSize = 500
+)
+
+// APIKeyUsageTracker records API key usage off the request path
+type APIKeyUsageTracker interface {
+	Track(apiKeyID uint, clientIP string)
+	Start()
+	Stop(ctx context.Context) error
+}
+
+// apiKeyUsageTracker implements APIKeyUsageTracker by aggregating usage in memory and
+// writing it to the database in batches
+type apiKeyUsageTracker struct {
+	apiKeyRepo    repository.APIKeyRepository
+	logger        *zap.Logger
+	flushInterval time.Duration
+
+	events   chan models.APIKeyUsage
+	done     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+	dropped  atomic.Int64
+}
+
+// NewAPIKeyUsageTracker creates a new instance of APIKeyUsageTracker
+func NewAPIKeyUsageTracker(apiKeyRepo repository.APIKeyRepository, flushInterval time.Duration, logger *zap.Logger) APIKeyUsageTracker {
+	return &apiKeyUsageTracker{
+		apiKeyRepo:    apiKeyRepo,
+		logger:        logger,
+		flushInterval: flushInterval,
+		events:        make(chan models.APIKeyUsage, usageBufferSize),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+}
+
+// Track queues a single use of an API key. It never blocks; events are dropped if the buffer is full.
+func (t *apiKeyUsageTracker) Track(apiKeyID uint, clientIP string) {
+	usage := models.APIKeyUsage{
+		APIKeyID:   apiKeyID,
+		Count:      1,
+		LastUsedAt: time.Now().UTC(),
+		LastUsedIP: clientIP,
+	}
+
+	select {
+	case t.events <- usage:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+// Start runs the flush loop in the background
+func (t *apiKeyUsageTracker) Start() {
+	go t.run()
+}
+
+// Stop flushes pending usage and waits for the flush loop to exit or the context to expire
+func (t *apiKeyUsageTracker) Stop(ctx context.Context) error {
+	t.stopOnce.Do(func() { close(t.done) })
+
+	select {
+	case <-t.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run aggregates usage events and flushes them on every tick, when the batch is full and on stop
+func (t *apiKeyUsageTracker) run() {
+	defer close(t.stopped)
+
+	ticker := time.NewTicker(t.flushInterval)
+	defer ticker.Stop()
+
+	pending := make(map[uint]*models.APIKeyUsage)
+	for {
+		select {
+		case usage := <-t.events:
+			mergeUsage(pending, usage)
+			if len(pending) >= usageBatchSize {
+				pending = t.flush(pending)
+			}
+		case <-ticker.C:
+			pending = t.flush(pending)
+		case <-t.done:
+			// Drain whatever is still buffered before the final flush
+			for {
+				select {
+				case usage := <-t.events:
+					mergeUsage(pending, usage)
+				default:
+					t.flush(pending)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush writes the pending usage to the database and returns an empty map for the next batch.
+// Usage that fails to be written is logged and discarded rather than retried.
+func (t *apiKeyUsageTracker) flush(pending map[uint]*models.APIKeyUsage) map[uint]*models.APIKeyUsage {
+	if dropped := t.dropped.Swap(0); dropped > 0 {
+		t.logger.Warn("Dropped API key usage events, buffer was full", zap.Int64("dropped", dropped))
+	}
+
+	if len(pending) == 0 {
+		return pending
+	}
+
+	usages := make([]models.APIKeyUsage, 0, len(pending))
+	for _, usage := range pending {
+		usages = append(usages, *usage)
+	}
+
+	// Usage outlives the requests it came from, so it is not tied to any request context
+	if err := t.apiKeyRepo.RecordUsage(context.Background(), usages); err != nil {
+		t.logger.Error("Failed to record API key usage", zap.Error(err), zap.Int("keys", len(usages)))
+	}
+
+	return make(map[uint]*models.APIKeyUsage)
+}
+
+// mergeUsage folds a usage event into the pending usage of its key, keeping the most recent client IP
+func mergeUsage(pending map[uint]*models.APIKeyUsage, usage models.APIKeyUsage) {
+	existing, ok := pending[usage.APIKeyID]
+	if !ok {
+		pending[usage.APIKeyID] = &usage
+		return
+	}
+
+	existing.Count += usage.Count
+	if !usage.LastUsedAt.Before(existing.LastUsedAt) {
+		existing.LastUsedAt = usage.LastUsedAt
+		existing.LastUsedIP = usage.LastUsedIP
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go-grafana/internal/domain/models"
+
+	"go.uber.org/zap"
+)
+
+func TestAPIKeyUsageTracker_FlushOnStop(t *testing.T) {
+	mockRepo := &MockAPIKeyRepository{}
+	var mu sync.Mutex
+	recorded := make(map[uint]models.APIKeyUsage)
+	mockRepo.RecordUsageFunc = func(usages []models.APIKeyUsage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, usage := range usages {
+			existing := recorded[usage.APIKeyID]
+			usage.Count += existing.Count
+			recorded[usage.APIKeyID] = usage
+		}
+		return nil
+	}
+
+	tracker := NewAPIKeyUsageTracker(mockRepo, time.Hour, zap.NewNop())
+	tracker.Start()
+
+	tracker.Track(1, "10.0.0.1")
+	tracker.Track(1, "10.0.0.2")
+	tracker.Track(2, "10.0.0.3")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := tracker.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if recorded[1].Count != 2 || recorded[1].LastUsedIP != "10.0.0.2" {
+		t.Errorf("unexpected usage for key 1: %+v", recorded[1])
+	}
+	if recorded[2].Count != 1 || recorded[2].LastUsedAt.IsZero() {
+		t.Errorf("unexpected usage for key 2: %+v", recorded[2])
+	}
+}
+
+func TestAPIKeyUsageTracker_FlushOnInterval(t *testing.T) {
+	mockRepo := &MockAPIKeyRepository{}
+	flushed := make(chan []models.APIKeyUsage, 1)
+	mockRepo.RecordUsageFunc = func(usages []models.APIKeyUsage) error {
+		flushed <- usages
+		return nil
+	}
+
+	tracker := NewAPIKeyUsageTracker(mockRepo, 10*time.Millisecond, zap.NewNop())
+	tracker.Start()
+	defer tracker.Stop(context.Background())
+
+	tracker.Track(7, "10.0.0.1")
+
+	select {
+	case usages := <-flushed:
+		if len(usages) != 1 || usages[0].APIKeyID != 7 || usages[0].Count != 1 {
+			t.Errorf("unexpected usage flushed: %+v", usages)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected usage to be flushed on the interval")
+	}
+}
+
+func TestAPIKeyUsageTracker_TrackNeverBlocks(t *testing.T) {
+	tracker := NewAPIKeyUsageTracker(&MockAPIKeyRepository{}, time.Hour, zap.NewNop())
+
+	// Without a running flush loop the buffer fills up and further events are dropped
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < usageBufferSize+10; i++ {
+			tracker.Track(1, "10.0.0.1")
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Track blocked on a full buffer")
+	}
+}
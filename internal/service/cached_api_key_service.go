@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go-grafana/internal/domain/models"
+	"go-grafana/internal/util"
+	"go-grafana/pkg/metrics"
+)
+
+// apiKeyCacheMaxEntries bounds the cache so that floods of random keys cannot grow it without limit
+const apiKeyCacheMaxEntries = 10000
+
+// apiKeyCacheEntry holds a cached validation result. A nil apiKey is a negative entry for an unknown or invalid key.
+type apiKeyCacheEntry struct {
+	apiKey    *models.APIKey
+	expiresAt time.Time
+}
+
+// cachedAPIKeyService decorates an APIKeyService with an in-process cache for ValidateAPIKey.
+// Entries are keyed by key hash and dropped whenever the key is changed through this instance;
+// changes made through other instances take effect once the TTL expires.
+type cachedAPIKeyService struct {
+	APIKeyService
+	ttl     time.Duration
+	metrics *metrics.PrometheusMetrics
+	now     func() time.Time
+
+	mu      sync.RWMutex
+	entries map[string]apiKeyCacheEntry
+	// generation is bumped on every invalidation so that lookups racing with it are not cached
+	generation uint64
+}
+
+// NewCachedAPIKeyService creates an APIKeyService that caches validation results for ttl
+func NewCachedAPIKeyService(next APIKeyService, ttl time.Duration, prometheusMetrics *metrics.PrometheusMetrics) APIKeyService {
+	return &cachedAPIKeyService{
+		APIKeyService: next,
+		ttl:           ttl,
+		metrics:       prometheusMetrics,
+		now:           time.Now,
+		entries:       make(map[string]apiKeyCacheEntry),
+	}
+}
+
+// ValidateAPIKey answers from the cache when possible and caches both valid keys and unknown ones.
+// Lookup failures other than an invalid key are never cached.
+func (s *cachedAPIKeyService) ValidateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	if key == "" {
+		return s.APIKeyService.ValidateAPIKey(ctx, key)
+	}
+
+	hashedKey := util.HashAPIKey(key)
+	now := s.now()
+
+	s.mu.RLock()
+	entry, ok := s.entries[hashedKey]
+	generation := s.generation
+	s.mu.RUnlock()
+
+	if ok && now.Before(entry.expiresAt) {
+		s.metrics.RecordAPIKeyCacheHit()
+		if entry.apiKey == nil {
+			return nil, ErrInvalidAPIKey
+		}
+		// Hand out a copy so callers cannot modify the cached key
+		apiKey := *entry.apiKey
+		return &apiKey, nil
+	}
+
+	s.metrics.RecordAPIKeyCacheMiss()
+	apiKey, err := s.APIKeyService.ValidateAPIKey(ctx, key)
+	if err != nil && !errors.Is(err, ErrInvalidAPIKey) {
+		return nil, err
+	}
+
+	s.store(hashedKey, apiKey, now, generation)
+	return apiKey, err
+}
+
+// UpdateAPIKey updates an API key and drops its cached validation results
+func (s *cachedAPIKeyService) UpdateAPIKey(ctx context.Context, id uint, req *models.UpdateAPIKeyRequest) (*models.APIKeyResponse, error) {
+	resp, err := s.APIKeyService.UpdateAPIKey(ctx, id, req)
+	if err == nil {
+		s.invalidateID(id)
+	}
+	return resp, err
+}
+
+// DeleteAPIKey deletes an API key and drops its cached validation results
+func (s *cachedAPIKeyService) DeleteAPIKey(ctx context.Context, id uint) error {
+	err := s.APIKeyService.DeleteAPIKey(ctx, id)
+	if err == nil {
+		s.invalidateID(id)
+	}
+	return err
+}
+
+// RevokeAPIKey revokes an API key and drops its cached validation results
+func (s *cachedAPIKeyService) RevokeAPIKey(ctx context.Context, id uint) (*models.APIKeyResponse, error) {
+	resp, err := s.APIKeyService.RevokeAPIKey(ctx, id)
+	if err == nil {
+		s.invalidateID(id)
+	}
+	return resp, err
+}
+
+// RotateAPIKey rotates an API key and drops its cached validation results
+func (s *cachedAPIKeyService) RotateAPIKey(ctx context.Context, id uint, req *models.RotateAPIKeyRequest) (*models.APIKeyResponse, error) {
+	resp, err := s.APIKeyService.RotateAPIKey(ctx, id, req)
+	if err == nil {
+		s.invalidateID(id)
+	}
+	return resp, err
+}
+
+// EnsureAPIKey seeds an API key and drops a negative entry cached for it
+func (s *cachedAPIKeyService) EnsureAPIKey(ctx context.Context, plainTextKey, name string, scopes []string) (bool, error) {
+	created, err := s.APIKeyService.EnsureAPIKey(ctx, plainTextKey, name, scopes)
+	if created {
+		s.mu.Lock()
+		delete(s.entries, util.HashAPIKey(plainTextKey))
+		s.generation++
+		s.mu.Unlock()
+	}
+	return created, err
+}
+
+// store caches a validation result unless an invalidation happened since the lookup started.
+// Valid keys are never cached past their expiry or, when matched by a previous key, past the end of the grace period.
+func (s *cachedAPIKeyService) store(hashedKey string, apiKey *models.APIKey, now time.Time, generation uint64) {
+	expiresAt := now.Add(s.ttl)
+	if apiKey != nil {
+		cached := *apiKey
+		apiKey = &cached
+
+		if apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(expiresAt) {
+			expiresAt = *apiKey.ExpiresAt
+		}
+		if apiKey.Key != hashedKey && apiKey.GraceUntil != nil && apiKey.GraceUntil.Before(expiresAt) {
+			expiresAt = *apiKey.GraceUntil
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if generation != s.generation {
+		return
+	}
+	if len(s.entries) >= apiKeyCacheMaxEntries {
+		s.evictExpired(now)
+	}
+	if len(s.entries) >= apiKeyCacheMaxEntries {
+		s.entries = make(map[string]apiKeyCacheEntry)
+	}
+	s.entries[hashedKey] = apiKeyCacheEntry{apiKey: apiKey, expiresAt: expiresAt}
+}
+
+// evictExpired removes expired entries. The caller must hold the write lock.
+func (s *cachedAPIKeyService) evictExpired(now time.Time) {
+	for hashedKey, entry := range s.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(s.entries, hashedKey)
+		}
+	}
+}
+
+// invalidateID removes every cached entry for the API key with the given ID,
+// which covers both its current and its previous key
+func (s *cachedAPIKeyService) invalidateID(id uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	for hashedKey, entry := range s.entries {
+		if entry.apiKey != nil && entry.apiKey.ID == id {
+			delete(s.entries, hashedKey)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-grafana/internal/domain/models"
+	"go-grafana/internal/domain/repository"
+	"go-grafana/internal/util"
+	"go-grafana/pkg/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+// newTestCachedAPIKeyService wires a cached service around a real service backed by the mock repository
+func newTestCachedAPIKeyService(mockRepo *MockAPIKeyRepository) (*cachedAPIKeyService, *prometheus.Registry) {
+	reg := prometheus.NewRegistry()
+	prometheusMetrics := metrics.NewPrometheusMetrics(zap.NewNop(), reg)
+	next := NewAPIKeyService(mockRepo, testCursors, testConfig)
+	return NewCachedAPIKeyService(next, time.Minute, prometheusMetrics).(*cachedAPIKeyService), reg
+}
+
+func TestCachedAPIKeyService_ValidateAPIKey(t *testing.T) {
+	plainTextKey := "cached-key"
+	hashedKey := util.HashAPIKey(plainTextKey)
+
+	t.Run("caches valid keys", func(t *testing.T) {
+		mockRepo := &MockAPIKeyRepository{}
+		service, reg := newTestCachedAPIKeyService(mockRepo)
+		lookups := 0
+		mockRepo.GetByKeyFunc = func(key string) (*models.APIKey, error) {
+			lookups++
+			return &models.APIKey{ID: 1, Key: hashedKey, Active: true}, nil
+		}
+
+		for i := 0; i < 3; i++ {
+			if _, err := service.ValidateAPIKey(context.Background(), plainTextKey); err != nil {
+				t.Fatalf("ValidateAPIKey() error = %v", err)
+			}
+		}
+		if lookups != 1 {
+			t.Errorf("expected 1 repository lookup, got %d", lookups)
+		}
+		if hits, _ := testutil.GatherAndCount(reg, "api_key_cache_hits_total"); hits != 1 {
+			t.Errorf("expected hit counter to be exported, got %d series", hits)
+		}
+	})
+
+	t.Run("caches unknown keys", func(t *testing.T) {
+		mockRepo := &MockAPIKeyRepository{}
+		service, _ := newTestCachedAPIKeyService(mockRepo)
+		lookups := 0
+		mockRepo.GetByKeyFunc = func(key string) (*models.APIKey, error) {
+			lookups++
+			return nil, repository.ErrAPIKeyNotFound
+		}
+		mockRepo.GetByPreviousKeyFunc = func(key string) (*models.APIKey, error) {
+			return nil, repository.ErrAPIKeyNotFound
+		}
+
+		for i := 0; i < 2; i++ {
+			if _, err := service.ValidateAPIKey(context.Background(), plainTextKey); !errors.Is(err, ErrInvalidAPIKey) {
+				t.Fatalf("expected ErrInvalidAPIKey, got %v", err)
+			}
+		}
+		if lookups != 1 {
+			t.Errorf("expected 1 repository lookup, got %d", lookups)
+		}
+	})
+
+	t.Run("does not cache lookup failures", func(t *testing.T) {
+		mockRepo := &MockAPIKeyRepository{}
+		service, _ := newTestCachedAPIKeyService(mockRepo)
+		lookups := 0
+		mockRepo.GetByKeyFunc = func(key string) (*models.APIKey, error) {
+			lookups++
+			return nil, errors.New("connection refused")
+		}
+
+		for i := 0; i < 2; i++ {
+			if _, err := service.ValidateAPIKey(context.Background(), plainTextKey); err == nil || errors.Is(err, ErrInvalidAPIKey) {
+				t.Fatalf("expected a lookup error, got %v", err)
+			}
+		}
+		if lookups != 2 {
+			t.Errorf("expected 2 repository lookups, got %d", lookups)
+		}
+	})
+
+	t.Run("expires after ttl", func(t *testing.T) {
+		mockRepo := &MockAPIKeyRepository{}
+		service, _ := newTestCachedAPIKeyService(mockRepo)
+		now := time.Now()
+		service.now = func() time.Time { return now }
+		lookups := 0
+		mockRepo.GetByKeyFunc = func(key string) (*models.APIKey, error) {
+			lookups++
+			return &models.APIKey{ID: 1, Key: hashedKey, Active: true}, nil
+		}
+
+		service.ValidateAPIKey(context.Background(), plainTextKey)
+		now = now.Add(2 * time.Minute)
+		service.ValidateAPIKey(context.Background(), plainTextKey)
+		if lookups != 2 {
+			t.Errorf("expected the entry to expire, got %d lookups", lookups)
+		}
+	})
+}
+
+func TestCachedAPIKeyService_Invalidation(t *testing.T) {
+	plainTextKey := "cached-key"
+	hashedKey := util.HashAPIKey(plainTextKey)
+
+	mockRepo := &MockAPIKeyRepository{}
+	service, _ := newTestCachedAPIKeyService(mockRepo)
+	stored := &models.APIKey{ID: 1, Name: "ci", Key: hashedKey, Active: true}
+	mockRepo.GetByKeyFunc = func(key string) (*models.APIKey, error) {
+		apiKey := *stored
+		return &apiKey, nil
+	}
+	mockRepo.GetByIDFunc = func(id uint) (*models.APIKey, error) {
+		apiKey := *stored
+		return &apiKey, nil
+	}
+	mockRepo.UpdateFunc = func(apiKey *models.APIKey) error {
+		*stored = *apiKey
+		return nil
+	}
+
+	if _, err := service.ValidateAPIKey(context.Background(), plainTextKey); err != nil {
+		t.Fatalf("ValidateAPIKey() error = %v", err)
+	}
+
+	if _, err := service.RevokeAPIKey(context.Background(), 1); err != nil {
+		t.Fatalf("RevokeAPIKey() error = %v", err)
+	}
+
+	if _, err := service.ValidateAPIKey(context.Background(), plainTextKey); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("expected revoked key to be rejected immediately, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrValidation is matched by every error caused by invalid input
+var ErrValidation = errors.New("validation failed")
+
+// FieldError describes why a single request field is invalid
+type FieldError struct {
+	Field   string `json:"field" example:"email"`
+	Message string `json:"message" example:"email is required"`
+}
+
+// ValidationError is returned when a request fails business validation.
+// It carries one entry per invalid field and matches ErrValidation.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// NewValidationError creates a validation error for a single field
+func NewValidationError(field, message string) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Message: message}}}
+}
+
+// Add records another invalid field
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// ErrOrNil returns the validation error if any field was recorded, or nil otherwise
+func (e *ValidationError) ErrOrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error joins the messages of all invalid fields
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Is reports whether target is ErrValidation
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// validationSentinel is a sentinel error that also matches ErrValidation
+type validationSentinel struct {
+	message string
+}
+
+// newValidationSentinel creates a sentinel error that is reported as a validation failure
+func newValidationSentinel(message string) error {
+	return &validationSentinel{message: message}
+}
+
+// Error returns the error message
+func (e *validationSentinel) Error() string {
+	return e.message
+}
+
+// Is reports whether target is ErrValidation
+func (e *validationSentinel) Is(target error) bool {
+	return target == ErrValidation
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestValidationError(t *testing.T) {
+	t.Run("collects fields", func(t *testing.T) {
+		validationErr := &ValidationError{}
+		if validationErr.ErrOrNil() != nil {
+			t.Fatal("expected nil for a validation error without fields")
+		}
+
+		validationErr.Add("email", "email is required")
+		validationErr.Add("age", "age must be between 1 and 120")
+
+		err := validationErr.ErrOrNil()
+		if err == nil {
+			t.Fatal("expected an error once fields were added")
+		}
+		if got, want := err.Error(), "email is required; age must be between 1 and 120"; got != want {
+			t.Errorf("Error() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("matches ErrValidation", func(t *testing.T) {
+		err := fmt.Errorf("wrapped: %w", NewValidationError("id", "invalid user ID"))
+		if !errors.Is(err, ErrValidation) {
+			t.Error("expected validation error to match ErrValidation")
+		}
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Fields[0].Field != "id" {
+			t.Errorf("expected errors.As to find the field, got %v", validationErr)
+		}
+	})
+}
+
+func TestValidationSentinels(t *testing.T) {
+	for _, sentinel := range []error{ErrInvalidListQuery, ErrInvalidScope, ErrInvalidGracePeriod} {
+		err := fmt.Errorf("%w: details", sentinel)
+		if !errors.Is(err, ErrValidation) {
+			t.Errorf("expected %v to match ErrValidation", sentinel)
+		}
+		if !errors.Is(err, sentinel) {
+			t.Errorf("expected %v to match itself", sentinel)
+		}
+	}
+
+	if errors.Is(ErrInvalidAPIKey, ErrValidation) {
+		t.Error("ErrInvalidAPIKey must not be reported as a validation failure")
+	}
+}
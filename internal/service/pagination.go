@@ -0,0 +1,56 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"go-grafana/internal/domain/models"
+	"go-grafana/internal/util"
+)
+
+// ErrInvalidListQuery is returned when list query parameters cannot be satisfied
+var ErrInvalidListQuery = newValidationSentinel("invalid list query")
+
+// pageSizeOrDefault clamps a requested page size to the allowed range
+func pageSizeOrDefault(size int) int {
+	if size <= 0 {
+		return models.DefaultPageSize
+	}
+	if size > models.MaxPageSize {
+		return models.MaxPageSize
+	}
+	return size
+}
+
+// decodeCursor turns an opaque cursor into a keyset position; an empty cursor means the first page
+func decodeCursor(cursors *util.CursorCodec, cursor string) (*models.Cursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	createdAt, id, err := cursors.Decode(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidListQuery, err)
+	}
+	return &models.Cursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// parseSort parses a "field,-field" sort expression against the allowed columns
+func parseSort(expr string, columns map[string]string) ([]models.SortField, error) {
+	var fields []models.SortField
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		desc := strings.HasPrefix(part, "-")
+		name := strings.TrimPrefix(strings.TrimPrefix(part, "-"), "+")
+		column, ok := columns[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown sort field %q", ErrInvalidListQuery, name)
+		}
+		fields = append(fields, models.SortField{Field: column, Desc: desc})
+	}
+	return fields, nil
+}
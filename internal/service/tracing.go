@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"go-grafana/internal/domain/models"
+	"go-grafana/internal/domain/repository"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the service spans
+const tracerName = "go-grafana/internal/service"
+
+// endSpan records the outcome of a service call and ends its span.
+// Errors caused by the request, such as validation failures, missing records, conflicts and
+// invalid API keys, are recorded but do not fail the span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if !isClientError(err) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
+
+// isClientError reports whether err is caused by the request rather than by the service
+func isClientError(err error) bool {
+	return errors.Is(err, ErrValidation) ||
+		errors.Is(err, ErrInvalidAPIKey) ||
+		errors.Is(err, repository.ErrNotFound) ||
+		errors.Is(err, repository.ErrConflict)
+}
+
+// tracedUserService decorates a UserService with a span around every call
+type tracedUserService struct {
+	next   UserService
+	tracer trace.Tracer
+}
+
+// NewTracedUserService creates a UserService that traces every call to next
+func NewTracedUserService(next UserService, tracerProvider trace.TracerProvider) UserService {
+	return &tracedUserService{
+		next:   next,
+		tracer: tracerProvider.Tracer(tracerName),
+	}
+}
+
+func (s *tracedUserService) CreateUser(ctx context.Context, req *models.CreateUserRequest) (user *models.UserResponse, err error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.CreateUser")
+	defer func() { endSpan(span, err) }()
+	return s.next.CreateUser(ctx, req)
+}
+
+func (s *tracedUserService) GetUserByID(ctx context.Context, id uint) (user *models.UserResponse, err error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.GetUserByID")
+	defer func() { endSpan(span, err) }()
+	return s.next.GetUserByID(ctx, id)
+}
+
+func (s *tracedUserService) ListUsers(ctx context.Context, req *models.ListUsersRequest) (users *models.UserListResponse, err error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.ListUsers")
+	defer func() { endSpan(span, err) }()
+	return s.next.ListUsers(ctx, req)
+}
+
+func (s *tracedUserService) UpdateUser(ctx context.Context, id uint, req *models.UpdateUserRequest) (user *models.UserResponse, err error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.UpdateUser")
+	defer func() { endSpan(span, err) }()
+	return s.next.UpdateUser(ctx, id, req)
+}
+
+func (s *tracedUserService) DeleteUser(ctx context.Context, id uint) (err error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.DeleteUser")
+	defer func() { endSpan(span, err) }()
+	return s.next.DeleteUser(ctx, id)
+}
+
+func (s *tracedUserService) GetUserCount(ctx context.Context) (count int64, err error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.GetUserCount")
+	defer func() { endSpan(span, err) }()
+	return s.next.GetUserCount(ctx)
+}
+
+// tracedAPIKeyService decorates an APIKeyService with a span around every call
+type tracedAPIKeyService struct {
+	next   APIKeyService
+	tracer trace.Tracer
+}
+
+// NewTracedAPIKeyService creates an APIKeyService that traces every call to next
+func NewTracedAPIKeyService(next APIKeyService, tracerProvider trace.TracerProvider) APIKeyService {
+	return &tracedAPIKeyService{
+		next:   next,
+		tracer: tracerProvider.Tracer(tracerName),
+	}
+}
+
+func (s *tracedAPIKeyService) CreateAPIKey(ctx context.Context, req *models.CreateAPIKeyRequest) (apiKey *models.APIKeyResponse, err error) {
+	ctx, span := s.tracer.Start(ctx, "APIKeyService.CreateAPIKey")
+	defer func() { endSpan(span, err) }()
+	return s.next.CreateAPIKey(ctx, req)
+}
+
+func (s *tracedAPIKeyService) GetAPIKeyByID(ctx context.Context, id uint) (apiKey *models.APIKeyResponse, err error) {
+	ctx, span := s.tracer.Start(ctx, "APIKeyService.GetAPIKeyByID")
+	defer func() { endSpan(span, err) }()
+	return s.next.GetAPIKeyByID(ctx, id)
+}
+
+func (s *tracedAPIKeyService) ListAPIKeys(ctx context.Context, req *models.ListAPIKeysRequest) (apiKeys *models.APIKeyListResponse, err error) {
+	ctx, span := s.tracer.Start(ctx, "APIKeyService.ListAPIKeys")
+	defer func() { endSpan(span, err) }()
+	return s.next.ListAPIKeys(ctx, req)
+}
+
+func (s *tracedAPIKeyService) UpdateAPIKey(ctx context.Context, id uint, req *models.UpdateAPIKeyRequest) (apiKey *models.APIKeyResponse, err error) {
+	ctx, span := s.tracer.Start(ctx, "APIKeyService.UpdateAPIKey")
+	defer func() { endSpan(span, err) }()
+	return s.next.UpdateAPIKey(ctx, id, req)
+}
+
+func (s *tracedAPIKeyService) DeleteAPIKey(ctx context.Context, id uint) (err error) {
+	ctx, span := s.tracer.Start(ctx, "APIKeyService.DeleteAPIKey")
+	defer func() { endSpan(span, err) }()
+	return s.next.DeleteAPIKey(ctx, id)
+}
+
+func (s *tracedAPIKeyService) RevokeAPIKey(ctx context.Context, id uint) (apiKey *models.APIKeyResponse, err error) {
+	ctx, span := s.tracer.Start(ctx, "APIKeyService.RevokeAPIKey")
+	defer func() { endSpan(span, err) }()
+	return s.next.RevokeAPIKey(ctx, id)
+}
+
+func (s *tracedAPIKeyService) RotateAPIKey(ctx context.Context, id uint, req *models.RotateAPIKeyRequest) (apiKey *models.APIKeyResponse, err error) {
+	ctx, span := s.tracer.Start(ctx, "APIKeyService.RotateAPIKey")
+	defer func() { endSpan(span, err) }()
+	return s.next.RotateAPIKey(ctx, id, req)
+}
+
+func (s *tracedAPIKeyService) EnsureAPIKey(ctx context.Context, plainTextKey, name string, scopes []string) (created bool, err error) {
+	ctx, span := s.tracer.Start(ctx, "APIKeyService.EnsureAPIKey")
+	defer func() { endSpan(span, err) }()
+	return s.next.EnsureAPIKey(ctx, plainTextKey, name, scopes)
+}
+
+func (s *tracedAPIKeyService) ValidateAPIKey(ctx context.Context, key string) (apiKey *models.APIKey, err error) {
+	ctx, span := s.tracer.Start(ctx, "APIKeyService.ValidateAPIKey")
+	defer func() { endSpan(span, err) }()
+	return s.next.ValidateAPIKey(ctx, key)
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-grafana/internal/domain/models"
+	"go-grafana/internal/domain/repository"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// stubUserService answers GetUserByID with a fixed error and reports the span it was called with
+type stubUserService struct {
+	UserService
+	err  error
+	span trace.SpanContext
+}
+
+func (s *stubUserService) GetUserByID(ctx context.Context, id uint) (*models.UserResponse, error) {
+	s.span = trace.SpanContextFromContext(ctx)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &models.UserResponse{ID: id}, nil
+}
+
+func TestTracedUserService(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus codes.Code
+	}{
+		{"success", nil, codes.Unset},
+		{"not found is expected", repository.ErrUserNotFound, codes.Unset},
+		{"unexpected error", errors.New("connection reset"), codes.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			stub := &stubUserService{err: tt.err}
+			service := NewTracedUserService(stub, tracerProvider)
+
+			if _, err := service.GetUserByID(context.Background(), 1); err != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			spans := recorder.Ended()
+			if len(spans) != 1 || spans[0].Name() != "UserService.GetUserByID" {
+				t.Fatalf("expected a UserService.GetUserByID span, got %v", spans)
+			}
+			if spans[0].SpanContext().SpanID() != stub.span.SpanID() {
+				t.Error("expected the wrapped service to run inside the span")
+			}
+			if spans[0].Status().Code != tt.wantStatus {
+				t.Errorf("expected status %v, got %v", tt.wantStatus, spans[0].Status())
+			}
+			if recorded := len(spans[0].Events()) > 0; recorded != (tt.err != nil) {
+				t.Errorf("expected the error to be recorded only when there is one, got %v", spans[0].Events())
+			}
+		})
+	}
+}
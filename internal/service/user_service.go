@@ -1,58 +1,91 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"go-grafana/internal/domain/models"
 	"go-grafana/internal/domain/repository"
+	"go-grafana/internal/util"
 	"go-grafana/pkg/metrics"
 )
 
 // UserService defines the interface for user business operations
 type UserService interface {
-	CreateUser(req *models.CreateUserRequest) (*models.UserResponse, error)
-	GetUserByID(id uint) (*models.UserResponse, error)
-	GetAllUsers() ([]models.UserResponse, error)
-	UpdateUser(id uint, req *models.UpdateUserRequest) (*models.UserResponse, error)
-	DeleteUser(id uint) error
-	GetUserCount() (int64, error)
+	CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.UserResponse, error)
+	GetUserByID(ctx context.Context, id uint) (*models.UserResponse, error)
+	ListUsers(ctx context.Context, req *models.ListUsersRequest) (*models.UserListResponse, error)
+	UpdateUser(ctx context.Context, id uint, req *models.UpdateUserRequest) (*models.UserResponse, error)
+	DeleteUser(ctx context.Context, id uint) error
+	GetUserCount(ctx context.Context) (int64, error)
+}
+
+// userSortColumns maps the sortable API field names to their database columns
+var userSortColumns = map[string]string{
+	"id":         "id",
+	"email":      "email",
+	"first_name": "first_name",
+	"last_name":  "last_name",
+	"age":        "age",
+	"active":     "active",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
 }
 
 // userService implements UserService interface
 type userService struct {
-	userRepo repository.UserRepository
-	metrics  *metrics.PrometheusMetrics
+	userRepo   repository.UserRepository
+	transactor repository.Transactor
+	cursors    *util.CursorCodec
+	metrics    *metrics.PrometheusMetrics
 }
 
 // NewUserService creates a new instance of UserService
-func NewUserService(userRepo repository.UserRepository, prometheusMetrics *metrics.PrometheusMetrics) UserService {
+func NewUserService(userRepo repository.UserRepository, transactor repository.Transactor, cursors *util.CursorCodec, prometheusMetrics *metrics.PrometheusMetrics) UserService {
 	return &userService{
-		userRepo: userRepo,
-		metrics:  prometheusMetrics,
+		userRepo:   userRepo,
+		transactor: transactor,
+		cursors:    cursors,
+		metrics:    prometheusMetrics,
 	}
 }
 
 // CreateUser creates a new user with validation
-func (s *userService) CreateUser(req *models.CreateUserRequest) (*models.UserResponse, error) {
+func (s *userService) CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.UserResponse, error) {
 	// Validate request
 	if err := s.validateCreateRequest(req); err != nil {
 		return nil, err
 	}
 
-	// Check if user with email already exists
-	existingUser, err := s.userRepo.GetByEmail(req.Email)
-	if err == nil && existingUser != nil {
-		return nil, errors.New("user with this email already exists")
-	}
-
 	// Create new user
 	user := &models.User{}
 	user.FromCreateRequest(req)
 
-	// Save to database
-	if err := s.userRepo.Create(user); err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
+	// Check the email and insert in one transaction; a concurrent insert of the same email
+	// still fails on the unique index and surfaces as a conflict
+	err := s.transactor.WithinTx(ctx, func(repos repository.Repositories) error {
+		existingUser, err := repos.Users.GetByEmail(ctx, req.Email)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("failed to check email: %w", err)
+		}
+		if existingUser != nil {
+			return repository.ErrUserEmailExists
+		}
+
+		// Save to database
+		if err := repos.Users.Create(ctx, user); err != nil {
+			// Losing a race on the unique email index is reported as is
+			if errors.Is(err, repository.ErrConflict) {
+				return err
+			}
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Record metrics
@@ -60,7 +93,7 @@ func (s *userService) CreateUser(req *models.CreateUserRequest) (*models.UserRes
 	s.metrics.RecordUserAge(user.Age)
 
 	// Update active users count
-	if count, err := s.userRepo.Count(); err == nil {
+	if count, err := s.userRepo.Count(ctx); err == nil {
 		s.metrics.SetActiveUsers(count)
 	}
 
@@ -68,12 +101,12 @@ func (s *userService) CreateUser(req *models.CreateUserRequest) (*models.UserRes
 }
 
 // GetUserByID retrieves a user by ID
-func (s *userService) GetUserByID(id uint) (*models.UserResponse, error) {
+func (s *userService) GetUserByID(ctx context.Context, id uint) (*models.UserResponse, error) {
 	if id == 0 {
-		return nil, errors.New("invalid user ID")
+		return nil, NewValidationError("id", "invalid user ID")
 	}
 
-	user, err := s.userRepo.GetByID(id)
+	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -81,9 +114,18 @@ func (s *userService) GetUserByID(id uint) (*models.UserResponse, error) {
 	return user.ToResponse(), nil
 }
 
-// GetAllUsers retrieves all users
-func (s *userService) GetAllUsers() ([]models.UserResponse, error) {
-	users, err := s.userRepo.GetAll()
+// ListUsers retrieves a filtered, sorted page of users
+func (s *userService) ListUsers(ctx context.Context, req *models.ListUsersRequest) (*models.UserListResponse, error) {
+	filter, err := s.buildUserFilter(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req != nil && req.Cursor != nil {
+		return s.listUsersAfter(ctx, filter, *req.Cursor)
+	}
+
+	users, total, err := s.userRepo.List(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get users: %w", err)
 	}
@@ -94,36 +136,94 @@ func (s *userService) GetAllUsers() ([]models.UserResponse, error) {
 		responses[i] = *user.ToResponse()
 	}
 
-	return responses, nil
+	return &models.UserListResponse{
+		Data: responses,
+		Pagination: models.PaginationMeta{
+			Total:  &total,
+			Limit:  filter.Limit,
+			Offset: filter.Offset,
+		},
+	}, nil
 }
 
-// UpdateUser updates an existing user
-func (s *userService) UpdateUser(id uint, req *models.UpdateUserRequest) (*models.UserResponse, error) {
-	// Validate request
-	if err := s.validateUpdateRequest(req); err != nil {
+// listUsersAfter retrieves the page of users following the cursor in (created_at, id) order
+func (s *userService) listUsersAfter(ctx context.Context, filter *models.UserFilter, cursor string) (*models.UserListResponse, error) {
+	after, err := decodeCursor(s.cursors, cursor)
+	if err != nil {
 		return nil, err
 	}
 
-	// Get existing user
-	user, err := s.userRepo.GetByID(id)
+	// Fetch one extra row to find out whether another page exists
+	limit := filter.Limit
+	filter.Limit = limit + 1
+	users, err := s.userRepo.ListAfter(ctx, filter, after)
 	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	var nextCursor string
+	if len(users) > limit {
+		users = users[:limit]
+		last := users[len(users)-1]
+		nextCursor = s.cursors.Encode(last.CreatedAt, last.ID)
+	}
+
+	responses := make([]models.UserResponse, len(users))
+	for i, user := range users {
+		responses[i] = *user.ToResponse()
+	}
+
+	return &models.UserListResponse{
+		Data: responses,
+		Pagination: models.PaginationMeta{
+			Limit:      limit,
+			NextCursor: nextCursor,
+		},
+	}, nil
+}
+
+// UpdateUser updates an existing user
+func (s *userService) UpdateUser(ctx context.Context, id uint, req *models.UpdateUserRequest) (*models.UserResponse, error) {
+	// Validate request
+	if err := s.validateUpdateRequest(req); err != nil {
 		return nil, err
 	}
 
-	// Check if email is being changed and if it conflicts with existing user
-	if user.Email != req.Email {
-		existingUser, err := s.userRepo.GetByEmail(req.Email)
-		if err == nil && existingUser != nil && existingUser.ID != id {
-			return nil, errors.New("user with this email already exists")
+	var user *models.User
+	err := s.transactor.WithinTx(ctx, func(repos repository.Repositories) error {
+		// Get existing user
+		var err error
+		user, err = repos.Users.GetByID(ctx, id)
+		if err != nil {
+			return err
 		}
-	}
 
-	// Update user data
-	user.FromUpdateRequest(req)
+		// Check if email is being changed and if it conflicts with existing user
+		if user.Email != req.Email {
+			existingUser, err := repos.Users.GetByEmail(ctx, req.Email)
+			if err != nil && !errors.Is(err, repository.ErrNotFound) {
+				return fmt.Errorf("failed to check email: %w", err)
+			}
+			if existingUser != nil && existingUser.ID != id {
+				return repository.ErrUserEmailExists
+			}
+		}
 
-	// Save to database
-	if err := s.userRepo.Update(user); err != nil {
-		return nil, fmt.Errorf("failed to update user: %w", err)
+		// Update user data
+		user.FromUpdateRequest(req)
+
+		// Save to database
+		if err := repos.Users.Update(ctx, user); err != nil {
+			// Losing a race on the unique email index is reported as is
+			if errors.Is(err, repository.ErrConflict) {
+				return err
+			}
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Record metrics
@@ -134,27 +234,32 @@ func (s *userService) UpdateUser(id uint, req *models.UpdateUserRequest) (*model
 }
 
 // DeleteUser removes a user from the system
-func (s *userService) DeleteUser(id uint) error {
+func (s *userService) DeleteUser(ctx context.Context, id uint) error {
 	if id == 0 {
-		return errors.New("invalid user ID")
+		return NewValidationError("id", "invalid user ID")
 	}
 
-	// Check if user exists
-	_, err := s.userRepo.GetByID(id)
+	err := s.transactor.WithinTx(ctx, func(repos repository.Repositories) error {
+		// Check if user exists
+		if _, err := repos.Users.GetByID(ctx, id); err != nil {
+			return err
+		}
+
+		// Delete user
+		if err := repos.Users.Delete(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	// Delete user
-	if err := s.userRepo.Delete(id); err != nil {
-		return fmt.Errorf("failed to delete user: %w", err)
-	}
-
 	// Record metrics
 	s.metrics.RecordUserDeletion()
 
 	// Update active users count
-	if count, err := s.userRepo.Count(); err == nil {
+	if count, err := s.userRepo.Count(ctx); err == nil {
 		s.metrics.SetActiveUsers(count)
 	}
 
@@ -162,60 +267,103 @@ func (s *userService) DeleteUser(id uint) error {
 }
 
 // GetUserCount returns the total number of users
-func (s *userService) GetUserCount() (int64, error) {
-	count, err := s.userRepo.Count()
+func (s *userService) GetUserCount(ctx context.Context) (int64, error) {
+	count, err := s.userRepo.Count(ctx)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get user count: %w", err)
 	}
 	return count, nil
 }
 
-// validateCreateRequest validates the create user request
-func (s *userService) validateCreateRequest(req *models.CreateUserRequest) error {
+// buildUserFilter validates the list request and converts it into a repository filter
+func (s *userService) buildUserFilter(req *models.ListUsersRequest) (*models.UserFilter, error) {
 	if req == nil {
-		return errors.New("request cannot be nil")
+		req = &models.ListUsersRequest{}
+	}
+
+	filter := &models.UserFilter{
+		Active:        req.Active,
+		EmailContains: strings.TrimSpace(req.Email),
+		MinAge:        req.MinAge,
+		MaxAge:        req.MaxAge,
+		CreatedAfter:  req.CreatedAfter,
+		CreatedBefore: req.CreatedBefore,
+	}
+
+	if req.Cursor != nil {
+		if req.Page > 0 || req.Offset > 0 || req.Sort != "" {
+			return nil, fmt.Errorf("%w: cursor cannot be combined with page, offset or sort", ErrInvalidListQuery)
+		}
 	}
 
-	if req.Email == "" {
-		return errors.New("email is required")
+	// limit/offset take precedence over page/page_size
+	if req.Limit > 0 || req.Offset > 0 {
+		filter.Limit = req.Limit
+		filter.Offset = req.Offset
+	} else {
+		filter.Limit = req.PageSize
+		if req.Page > 1 {
+			filter.Offset = (req.Page - 1) * pageSizeOrDefault(req.PageSize)
+		}
+	}
+	filter.Limit = pageSizeOrDefault(filter.Limit)
+	if filter.Offset < 0 {
+		return nil, fmt.Errorf("%w: offset cannot be negative", ErrInvalidListQuery)
 	}
 
-	if req.FirstName == "" {
-		return errors.New("first name is required")
+	if req.MinAge != nil && req.MaxAge != nil && *req.MinAge > *req.MaxAge {
+		return nil, fmt.Errorf("%w: min_age cannot be greater than max_age", ErrInvalidListQuery)
+	}
+	if req.CreatedAfter != nil && req.CreatedBefore != nil && !req.CreatedAfter.Before(*req.CreatedBefore) {
+		return nil, fmt.Errorf("%w: created_after must be before created_before", ErrInvalidListQuery)
 	}
 
-	if req.LastName == "" {
-		return errors.New("last name is required")
+	sort, err := parseSort(req.Sort, userSortColumns)
+	if err != nil {
+		return nil, err
 	}
+	filter.Sort = sort
+
+	return filter, nil
+}
 
-	if req.Age <= 0 || req.Age > 120 {
-		return errors.New("age must be between 1 and 120")
+// validateCreateRequest validates the create user request
+func (s *userService) validateCreateRequest(req *models.CreateUserRequest) error {
+	if req == nil {
+		return NewValidationError("", "request cannot be nil")
 	}
 
-	return nil
+	return validateUserFields(req.Email, req.FirstName, req.LastName, req.Age)
 }
 
 // validateUpdateRequest validates the update user request
 func (s *userService) validateUpdateRequest(req *models.UpdateUserRequest) error {
 	if req == nil {
-		return errors.New("request cannot be nil")
+		return NewValidationError("", "request cannot be nil")
 	}
 
-	if req.Email == "" {
-		return errors.New("email is required")
+	return validateUserFields(req.Email, req.FirstName, req.LastName, req.Age)
+}
+
+// validateUserFields checks the fields shared by the create and update requests and reports every invalid one
+func validateUserFields(email, firstName, lastName string, age int) error {
+	validationErr := &ValidationError{}
+
+	if email == "" {
+		validationErr.Add("email", "email is required")
 	}
 
-	if req.FirstName == "" {
-		return errors.New("first name is required")
+	if firstName == "" {
+		validationErr.Add("first_name", "first name is required")
 	}
 
-	if req.LastName == "" {
-		return errors.New("last name is required")
+	if lastName == "" {
+		validationErr.Add("last_name", "last name is required")
 	}
 
-	if req.Age <= 0 || req.Age > 120 {
-		return errors.New("age must be between 1 and 120")
+	if age <= 0 || age > 120 {
+		validationErr.Add("age", "age must be between 1 and 120")
 	}
 
-	return nil
+	return validationErr.ErrOrNil()
 }
@@ -1,10 +1,15 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"reflect"
 	"testing"
+	"time"
 
 	"go-grafana/internal/domain/models"
+	"go-grafana/internal/domain/repository"
+	"go-grafana/internal/domain/repository/memory"
 	"go-grafana/pkg/metrics"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -15,26 +20,54 @@ import (
 type MockUserRepository struct {
 	CreateFunc     func(user *models.User) error
 	GetByIDFunc    func(id uint) (*models.User, error)
-	GetAllFunc     func() ([]models.User, error)
+	ListFunc       func(filter *models.UserFilter) ([]models.User, int64, error)
+	ListAfterFunc  func(filter *models.UserFilter, after *models.Cursor) ([]models.User, error)
 	UpdateFunc     func(user *models.User) error
 	DeleteFunc     func(id uint) error
 	GetByEmailFunc func(email string) (*models.User, error)
 	CountFunc      func() (int64, error)
 }
 
-func (m *MockUserRepository) Create(user *models.User) error        { return m.CreateFunc(user) }
-func (m *MockUserRepository) GetByID(id uint) (*models.User, error) { return m.GetByIDFunc(id) }
-func (m *MockUserRepository) GetAll() ([]models.User, error)        { return m.GetAllFunc() }
-func (m *MockUserRepository) Update(user *models.User) error        { return m.UpdateFunc(user) }
-func (m *MockUserRepository) Delete(id uint) error                  { return m.DeleteFunc(id) }
-func (m *MockUserRepository) GetByEmail(email string) (*models.User, error) {
+func (m *MockUserRepository) Create(ctx context.Context, user *models.User) error {
+	return m.CreateFunc(user)
+}
+func (m *MockUserRepository) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	return m.GetByIDFunc(id)
+}
+func (m *MockUserRepository) Update(ctx context.Context, user *models.User) error {
+	return m.UpdateFunc(user)
+}
+func (m *MockUserRepository) Delete(ctx context.Context, id uint) error { return m.DeleteFunc(id) }
+func (m *MockUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	return m.GetByEmailFunc(email)
 }
-func (m *MockUserRepository) Count() (int64, error) { return m.CountFunc() }
+func (m *MockUserRepository) List(ctx context.Context, filter *models.UserFilter) ([]models.User, int64, error) {
+	return m.ListFunc(filter)
+}
+func (m *MockUserRepository) ListAfter(ctx context.Context, filter *models.UserFilter, after *models.Cursor) ([]models.User, error) {
+	return m.ListAfterFunc(filter, after)
+}
+func (m *MockUserRepository) Count(ctx context.Context) (int64, error) { return m.CountFunc() }
+
+// MockTransactor is a mock implementation of Transactor that runs the unit of work against mock repositories
+type MockTransactor struct {
+	Repos      repository.Repositories
+	RolledBack bool
+}
+
+func newMockTransactor(userRepo *MockUserRepository) *MockTransactor {
+	return &MockTransactor{Repos: repository.Repositories{Users: userRepo}}
+}
+
+func (m *MockTransactor) WithinTx(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	err := fn(m.Repos)
+	m.RolledBack = err != nil
+	return err
+}
 
 func TestNewUserService(t *testing.T) {
 	mockRepo := &MockUserRepository{}
-	service := NewUserService(mockRepo, metrics.NewPrometheusMetrics(zap.NewNop(), prometheus.NewRegistry()))
+	service := NewUserService(mockRepo, newMockTransactor(mockRepo), testCursors, metrics.NewPrometheusMetrics(zap.NewNop(), prometheus.NewRegistry()))
 	if service == nil {
 		t.Error("NewUserService() returned nil")
 	}
@@ -42,12 +75,12 @@ func TestNewUserService(t *testing.T) {
 
 func TestUserService_CreateUser(t *testing.T) {
 	mockRepo := &MockUserRepository{}
-	service := NewUserService(mockRepo, metrics.NewPrometheusMetrics(zap.NewNop(), prometheus.NewRegistry()))
+	service := NewUserService(mockRepo, newMockTransactor(mockRepo), testCursors, metrics.NewPrometheusMetrics(zap.NewNop(), prometheus.NewRegistry()))
 
 	t.Run("success", func(t *testing.T) {
 		req := &models.CreateUserRequest{Email: "test@example.com", FirstName: "Test", LastName: "User", Age: 30}
 		mockRepo.GetByEmailFunc = func(email string) (*models.User, error) {
-			return nil, errors.New("not found")
+			return nil, repository.ErrUserNotFound
 		}
 		mockRepo.CreateFunc = func(user *models.User) error {
 			user.ID = 1
@@ -57,7 +90,7 @@ func TestUserService_CreateUser(t *testing.T) {
 			return 1, nil
 		}
 
-		resp, err := service.CreateUser(req)
+		resp, err := service.CreateUser(context.Background(), req)
 		if err != nil {
 			t.Fatalf("CreateUser() error = %v", err)
 		}
@@ -71,24 +104,49 @@ func TestUserService_CreateUser(t *testing.T) {
 		mockRepo.GetByEmailFunc = func(email string) (*models.User, error) {
 			return &models.User{ID: 1, Email: email}, nil
 		}
-		_, err := service.CreateUser(req)
-		if err == nil {
-			t.Error("expected an error for existing email, got nil")
+		_, err := service.CreateUser(context.Background(), req)
+		if !errors.Is(err, repository.ErrConflict) {
+			t.Errorf("expected a conflict error for existing email, got %v", err)
+		}
+	})
+
+	t.Run("concurrent insert with the same email", func(t *testing.T) {
+		transactor := newMockTransactor(mockRepo)
+		service := NewUserService(mockRepo, transactor, testCursors, metrics.NewPrometheusMetrics(zap.NewNop(), prometheus.NewRegistry()))
+		req := &models.CreateUserRequest{Email: "test@example.com", FirstName: "Test", LastName: "User", Age: 30}
+		mockRepo.GetByEmailFunc = func(email string) (*models.User, error) {
+			return nil, repository.ErrUserNotFound
+		}
+		mockRepo.CreateFunc = func(user *models.User) error {
+			return repository.ErrUserEmailExists
+		}
+
+		_, err := service.CreateUser(context.Background(), req)
+		if err != repository.ErrUserEmailExists {
+			t.Errorf("expected %v, got %v", repository.ErrUserEmailExists, err)
+		}
+		if !transactor.RolledBack {
+			t.Error("expected the transaction to be rolled back")
 		}
 	})
 
 	t.Run("invalid request", func(t *testing.T) {
 		req := &models.CreateUserRequest{Email: ""} // Invalid
-		_, err := service.CreateUser(req)
-		if err == nil {
-			t.Error("expected an error for invalid request, got nil")
+		_, err := service.CreateUser(context.Background(), req)
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("expected a validation error for invalid request, got %v", err)
+		}
+		// Every invalid field is reported, not just the first one
+		if len(validationErr.Fields) != 4 {
+			t.Errorf("expected 4 invalid fields, got %+v", validationErr.Fields)
 		}
 	})
 }
 
 func TestUserService_GetUserByID(t *testing.T) {
 	mockRepo := &MockUserRepository{}
-	service := NewUserService(mockRepo, metrics.NewPrometheusMetrics(zap.NewNop(), prometheus.NewRegistry()))
+	service := NewUserService(mockRepo, newMockTransactor(mockRepo), testCursors, metrics.NewPrometheusMetrics(zap.NewNop(), prometheus.NewRegistry()))
 
 	t.Run("success", func(t *testing.T) {
 		expectedUser := &models.User{ID: 1}
@@ -96,9 +154,9 @@ func TestUserService_GetUserByID(t *testing.T) {
 			if id == 1 {
 				return expectedUser, nil
 			}
-			return nil, errors.New("not found")
+			return nil, repository.ErrUserNotFound
 		}
-		user, err := service.GetUserByID(1)
+		user, err := service.GetUserByID(context.Background(), 1)
 		if err != nil {
 			t.Fatalf("GetUserByID() error = %v", err)
 		}
@@ -109,18 +167,25 @@ func TestUserService_GetUserByID(t *testing.T) {
 
 	t.Run("not found", func(t *testing.T) {
 		mockRepo.GetByIDFunc = func(id uint) (*models.User, error) {
-			return nil, errors.New("not found")
+			return nil, repository.ErrUserNotFound
 		}
-		_, err := service.GetUserByID(99)
-		if err == nil {
-			t.Error("expected error for user not found, got nil")
+		_, err := service.GetUserByID(context.Background(), 99)
+		if !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("expected a not found error, got %v", err)
+		}
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		_, err := service.GetUserByID(context.Background(), 0)
+		if !errors.Is(err, ErrValidation) {
+			t.Errorf("expected a validation error for invalid id, got %v", err)
 		}
 	})
 }
 
 func TestUserService_UpdateUser(t *testing.T) {
 	mockRepo := &MockUserRepository{}
-	service := NewUserService(mockRepo, metrics.NewPrometheusMetrics(zap.NewNop(), prometheus.NewRegistry()))
+	service := NewUserService(mockRepo, newMockTransactor(mockRepo), testCursors, metrics.NewPrometheusMetrics(zap.NewNop(), prometheus.NewRegistry()))
 
 	t.Run("success", func(t *testing.T) {
 		req := &models.UpdateUserRequest{Email: "new@example.com", FirstName: "New", LastName: "Name", Age: 40}
@@ -129,13 +194,13 @@ func TestUserService_UpdateUser(t *testing.T) {
 			return existingUser, nil
 		}
 		mockRepo.GetByEmailFunc = func(email string) (*models.User, error) {
-			return nil, errors.New("not found") // No conflict
+			return nil, repository.ErrUserNotFound // No conflict
 		}
 		mockRepo.UpdateFunc = func(user *models.User) error {
 			return nil
 		}
 
-		resp, err := service.UpdateUser(1, req)
+		resp, err := service.UpdateUser(context.Background(), 1, req)
 		if err != nil {
 			t.Fatalf("UpdateUser() error = %v", err)
 		}
@@ -152,7 +217,7 @@ func TestUserService_UpdateUser(t *testing.T) {
 		mockRepo.GetByEmailFunc = func(email string) (*models.User, error) {
 			return &models.User{ID: 2, Email: "conflict@example.com"}, nil // Other user has this email
 		}
-		_, err := service.UpdateUser(1, req)
+		_, err := service.UpdateUser(context.Background(), 1, req)
 		if err == nil {
 			t.Error("expected error for email conflict, got nil")
 		}
@@ -161,7 +226,7 @@ func TestUserService_UpdateUser(t *testing.T) {
 
 func TestUserService_DeleteUser(t *testing.T) {
 	mockRepo := &MockUserRepository{}
-	service := NewUserService(mockRepo, metrics.NewPrometheusMetrics(zap.NewNop(), prometheus.NewRegistry()))
+	service := NewUserService(mockRepo, newMockTransactor(mockRepo), testCursors, metrics.NewPrometheusMetrics(zap.NewNop(), prometheus.NewRegistry()))
 
 	t.Run("success", func(t *testing.T) {
 		mockRepo.GetByIDFunc = func(id uint) (*models.User, error) {
@@ -174,7 +239,7 @@ func TestUserService_DeleteUser(t *testing.T) {
 			return 0, nil
 		}
 
-		err := service.DeleteUser(1)
+		err := service.DeleteUser(context.Background(), 1)
 		if err != nil {
 			t.Fatalf("DeleteUser() error = %v", err)
 		}
@@ -182,11 +247,172 @@ func TestUserService_DeleteUser(t *testing.T) {
 
 	t.Run("not found", func(t *testing.T) {
 		mockRepo.GetByIDFunc = func(id uint) (*models.User, error) {
-			return nil, errors.New("not found")
+			return nil, repository.ErrUserNotFound
 		}
-		err := service.DeleteUser(1)
+		err := service.DeleteUser(context.Background(), 1)
 		if err == nil {
 			t.Error("expected error for user not found, got nil")
 		}
 	})
 }
+
+func TestUserService_MemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	service := NewUserService(store.Users(), store, testCursors, metrics.NewPrometheusMetrics(zap.NewNop(), prometheus.NewRegistry()))
+
+	created, err := service.CreateUser(ctx, &models.CreateUserRequest{Email: "jane@example.com", FirstName: "Jane", LastName: "Doe", Age: 30})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if _, err := service.CreateUser(ctx, &models.CreateUserRequest{Email: "jane@example.com", FirstName: "Jane", LastName: "Roe", Age: 31}); err != repository.ErrUserEmailExists {
+		t.Errorf("expected %v, got %v", repository.ErrUserEmailExists, err)
+	}
+
+	updated, err := service.UpdateUser(ctx, created.ID, &models.UpdateUserRequest{Email: "jane.doe@example.com", FirstName: "Jane", LastName: "Doe", Age: 31})
+	if err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	if updated.Email != "jane.doe@example.com" || updated.Age != 31 {
+		t.Errorf("unexpected user after update %+v", updated)
+	}
+
+	page, err := service.ListUsers(ctx, &models.ListUsersRequest{})
+	if err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+	if len(page.Data) != 1 || *page.Pagination.Total != 1 {
+		t.Errorf("unexpected page %+v", page)
+	}
+
+	if err := service.DeleteUser(ctx, created.ID); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if _, err := service.GetUserByID(ctx, created.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected the user to be gone, got %v", err)
+	}
+	if err := service.DeleteUser(ctx, created.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected deleting twice to report not found, got %v", err)
+	}
+}
+
+func TestUserService_ListUsers(t *testing.T) {
+	mockRepo := &MockUserRepository{}
+	service := NewUserService(mockRepo, newMockTransactor(mockRepo), testCursors, metrics.NewPrometheusMetrics(zap.NewNop(), prometheus.NewRegistry()))
+
+	t.Run("defaults", func(t *testing.T) {
+		mockRepo.ListFunc = func(filter *models.UserFilter) ([]models.User, int64, error) {
+			if filter.Limit != models.DefaultPageSize || filter.Offset != 0 {
+				t.Errorf("expected default paging, got limit %d offset %d", filter.Limit, filter.Offset)
+			}
+			return []models.User{{ID: 1}, {ID: 2}}, 2, nil
+		}
+
+		resp, err := service.ListUsers(context.Background(), &models.ListUsersRequest{})
+		if err != nil {
+			t.Fatalf("ListUsers() error = %v", err)
+		}
+		if len(resp.Data) != 2 || *resp.Pagination.Total != 2 {
+			t.Errorf("unexpected response: %+v", resp)
+		}
+	})
+
+	t.Run("page and sort", func(t *testing.T) {
+		mockRepo.ListFunc = func(filter *models.UserFilter) ([]models.User, int64, error) {
+			if filter.Limit != 10 || filter.Offset != 20 {
+				t.Errorf("expected limit 10 offset 20, got limit %d offset %d", filter.Limit, filter.Offset)
+			}
+			expected := []models.SortField{{Field: "age", Desc: true}, {Field: "last_name"}}
+			if !reflect.DeepEqual(filter.Sort, expected) {
+				t.Errorf("expected sort %+v, got %+v", expected, filter.Sort)
+			}
+			return nil, 0, nil
+		}
+
+		_, err := service.ListUsers(context.Background(), &models.ListUsersRequest{Page: 3, PageSize: 10, Sort: "-age, last_name"})
+		if err != nil {
+			t.Fatalf("ListUsers() error = %v", err)
+		}
+	})
+
+	t.Run("limit and offset take precedence", func(t *testing.T) {
+		mockRepo.ListFunc = func(filter *models.UserFilter) ([]models.User, int64, error) {
+			if filter.Limit != 5 || filter.Offset != 7 {
+				t.Errorf("expected limit 5 offset 7, got limit %d offset %d", filter.Limit, filter.Offset)
+			}
+			return nil, 0, nil
+		}
+
+		_, err := service.ListUsers(context.Background(), &models.ListUsersRequest{Page: 3, PageSize: 10, Limit: 5, Offset: 7})
+		if err != nil {
+			t.Fatalf("ListUsers() error = %v", err)
+		}
+	})
+
+	t.Run("unknown sort field", func(t *testing.T) {
+		_, err := service.ListUsers(context.Background(), &models.ListUsersRequest{Sort: "password"})
+		if !errors.Is(err, ErrInvalidListQuery) {
+			t.Errorf("expected ErrInvalidListQuery, got %v", err)
+		}
+	})
+
+	t.Run("inverted age range", func(t *testing.T) {
+		minAge, maxAge := 50, 20
+		_, err := service.ListUsers(context.Background(), &models.ListUsersRequest{MinAge: &minAge, MaxAge: &maxAge})
+		if !errors.Is(err, ErrInvalidListQuery) {
+			t.Errorf("expected ErrInvalidListQuery, got %v", err)
+		}
+	})
+
+	t.Run("cursor", func(t *testing.T) {
+		createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		empty := ""
+		mockRepo.ListAfterFunc = func(filter *models.UserFilter, after *models.Cursor) ([]models.User, error) {
+			if after != nil {
+				t.Errorf("expected first page to have no cursor, got %+v", after)
+			}
+			return []models.User{{ID: 1, CreatedAt: createdAt}, {ID: 2, CreatedAt: createdAt}}, nil
+		}
+
+		resp, err := service.ListUsers(context.Background(), &models.ListUsersRequest{Limit: 1, Cursor: &empty})
+		if err != nil {
+			t.Fatalf("ListUsers() error = %v", err)
+		}
+		if len(resp.Data) != 1 || resp.Pagination.NextCursor == "" || resp.Pagination.Total != nil {
+			t.Fatalf("unexpected keyset response: %+v", resp)
+		}
+
+		mockRepo.ListAfterFunc = func(filter *models.UserFilter, after *models.Cursor) ([]models.User, error) {
+			if after == nil || after.ID != 1 {
+				t.Errorf("expected cursor after user 1, got %+v", after)
+			}
+			return nil, nil
+		}
+		next := resp.Pagination.NextCursor
+		resp, err = service.ListUsers(context.Background(), &models.ListUsersRequest{Limit: 1, Cursor: &next})
+		if err != nil {
+			t.Fatalf("ListUsers() error = %v", err)
+		}
+		if resp.Pagination.NextCursor != "" {
+			t.Errorf("expected last page to have no next cursor, got %q", resp.Pagination.NextCursor)
+		}
+	})
+
+	t.Run("cursor with sort", func(t *testing.T) {
+		empty := ""
+		_, err := service.ListUsers(context.Background(), &models.ListUsersRequest{Cursor: &empty, Sort: "age"})
+		if !errors.Is(err, ErrInvalidListQuery) {
+			t.Errorf("expected ErrInvalidListQuery, got %v", err)
+		}
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		mockRepo.ListFunc = func(filter *models.UserFilter) ([]models.User, int64, error) {
+			return nil, 0, errors.New("db down")
+		}
+		_, err := service.ListUsers(context.Background(), &models.ListUsersRequest{})
+		if err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package util
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCursorCodec(t *testing.T) {
+	codec := NewCursorCodec([]byte("test-secret"))
+	createdAt := time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC)
+
+	t.Run("round trip", func(t *testing.T) {
+		token := codec.Encode(createdAt, 42)
+
+		decodedAt, id, err := codec.Decode(token)
+		if err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		if !decodedAt.Equal(createdAt) {
+			t.Errorf("expected created_at %v, got %v", createdAt, decodedAt)
+		}
+		if id != 42 {
+			t.Errorf("expected id 42, got %d", id)
+		}
+	})
+
+	t.Run("rejects tokens signed with another secret", func(t *testing.T) {
+		token := NewCursorCodec([]byte("other-secret")).Encode(createdAt, 42)
+		if _, _, err := codec.Decode(token); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("expected ErrInvalidCursor, got %v", err)
+		}
+	})
+
+	t.Run("rejects tampered payload", func(t *testing.T) {
+		_, signature, _ := strings.Cut(codec.Encode(createdAt, 42), ".")
+		payload, _, _ := strings.Cut(codec.Encode(createdAt, 43), ".")
+		tampered := payload + "." + signature
+		if _, _, err := codec.Decode(tampered); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("expected ErrInvalidCursor, got %v", err)
+		}
+	})
+
+	t.Run("rejects garbage", func(t *testing.T) {
+		for _, token := range []string{"", "abc", "abc.def", "!!!.???"} {
+			if _, _, err := codec.Decode(token); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("Decode(%q): expected ErrInvalidCursor, got %v", token, err)
+			}
+		}
+	})
+}
+
+func TestNewRandomCursorCodec(t *testing.T) {
+	codec1, err := NewRandomCursorCodec()
+	if err != nil {
+		t.Fatalf("NewRandomCursorCodec() error = %v", err)
+	}
+	codec2, err := NewRandomCursorCodec()
+	if err != nil {
+		t.Fatalf("NewRandomCursorCodec() error = %v", err)
+	}
+
+	token := codec1.Encode(time.Now(), 1)
+	if _, _, err := codec2.Decode(token); err == nil {
+		t.Error("expected codecs with different random secrets to reject each other's tokens")
+	}
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"fmt"
+
+	"go-grafana/internal/config"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// NewDB creates the database connection for the configured driver.
+// Every statement is traced as a child span of the span in its context, and statement
+// durations, errors and connection pool statistics are registered with reg.
+func NewDB(cfg *config.Config, logger *zap.Logger, tracerProvider trace.TracerProvider, reg prometheus.Registerer) (*gorm.DB, error) {
+	var db *gorm.DB
+	var system attribute.KeyValue
+	var name string
+	var err error
+	switch cfg.Database.Driver {
+	case config.DriverPostgres:
+		db, err = NewPostgresDB(cfg, logger)
+		system = semconv.DBSystemPostgreSQL
+		name = cfg.Database.DBName
+	case config.DriverSQLite:
+		db, err = NewSQLiteDB(cfg, logger)
+		system = semconv.DBSystemSqlite
+		name = cfg.Database.SQLitePath
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Database.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Use(newTracing(tracerProvider, system)); err != nil {
+		return nil, fmt.Errorf("failed to register tracing: %w", err)
+	}
+	if err := useMetrics(db, reg, name); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// useStatementTimeout bounds every statement by the configured timeout on top of its request context
+func useStatementTimeout(db *gorm.DB, cfg *config.Config) error {
+	if cfg.Database.StatementTimeout <= 0 {
+		return nil
+	}
+
+	if err := db.Use(newStatementTimeout(cfg.Database.StatementTimeout)); err != nil {
+		return fmt.Errorf("failed to register statement timeout: %w", err)
+	}
+	return nil
+}
+
+// CloseDB closes the database connection
+func CloseDB(db *gorm.DB, logger *zap.Logger) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get underlying SQL database: %w", err)
+	}
+
+	if err := sqlDB.Close(); err != nil {
+		return fmt.Errorf("failed to close database connection: %w", err)
+	}
+
+	logger.Info("Database connection closed successfully")
+	return nil
+}
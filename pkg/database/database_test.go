@@ -0,0 +1,35 @@
+package database
+
+import (
+	"testing"
+
+	"go-grafana/internal/config"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+)
+
+func TestNewDB(t *testing.T) {
+	t.Run("sqlite", func(t *testing.T) {
+		cfg := &config.Config{Database: config.DatabaseConfig{Driver: config.DriverSQLite, SQLitePath: ":memory:"}}
+		db, err := NewDB(cfg, zap.NewNop(), noop.NewTracerProvider(), prometheus.NewRegistry())
+		if err != nil {
+			t.Fatalf("NewDB() error = %v", err)
+		}
+		defer CloseDB(db, zap.NewNop())
+
+		for _, table := range []string{"users", "api_keys"} {
+			if !db.Migrator().HasTable(table) {
+				t.Errorf("expected table %s to be created", table)
+			}
+		}
+	})
+
+	t.Run("unsupported driver", func(t *testing.T) {
+		cfg := &config.Config{Database: config.DatabaseConfig{Driver: "mysql"}}
+		if _, err := NewDB(cfg, zap.NewNop(), noop.NewTracerProvider(), prometheus.NewRegistry()); err == nil {
+			t.Error("expected an error for an unsupported driver")
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+// metricsStartKey stores the start time of a statement between callbacks
+const metricsStartKey = "metrics:start"
+
+// queryMetrics is a GORM plugin that records the duration and errors of every statement by operation and table
+type queryMetrics struct {
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+}
+
+// newQueryMetrics creates the plugin and registers its metrics
+func newQueryMetrics(reg prometheus.Registerer) (*queryMetrics, error) {
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Duration of database statements",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation", "table"})
+
+	errorsTotal := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Total number of database statements that failed",
+	}, []string{"operation", "table"})
+
+	if err := errors.Join(reg.Register(duration), reg.Register(errorsTotal)); err != nil {
+		return nil, err
+	}
+
+	return &queryMetrics{
+		duration: duration,
+		errors:   errorsTotal,
+	}, nil
+}
+
+// Name returns the plugin name
+func (p *queryMetrics) Name() string {
+	return "metrics"
+}
+
+// Initialize wraps the create, query, update, delete, row and raw callbacks
+func (p *queryMetrics) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("metrics:start", p.start),
+		callbacks.Create().After("gorm:create").Register("metrics:end", p.end("create")),
+		callbacks.Query().Before("gorm:query").Register("metrics:start", p.start),
+		callbacks.Query().After("gorm:query").Register("metrics:end", p.end("query")),
+		callbacks.Update().Before("gorm:update").Register("metrics:start", p.start),
+		callbacks.Update().After("gorm:update").Register("metrics:end", p.end("update")),
+		callbacks.Delete().Before("gorm:delete").Register("metrics:start", p.start),
+		callbacks.Delete().After("gorm:delete").Register("metrics:end", p.end("delete")),
+		callbacks.Row().Before("gorm:row").Register("metrics:start", p.start),
+		callbacks.Row().After("gorm:row").Register("metrics:end", p.end("row")),
+		callbacks.Raw().Before("gorm:raw").Register("metrics:start", p.start),
+		callbacks.Raw().After("gorm:raw").Register("metrics:end", p.end("raw")),
+	)
+}
+
+// start records when the statement began
+func (p *queryMetrics) start(db *gorm.DB) {
+	db.InstanceSet(metricsStartKey, time.Now())
+}
+
+// end returns a callback that observes the duration of a statement and counts it if it failed
+func (p *queryMetrics) end(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(metricsStartKey)
+		if !ok {
+			return
+		}
+
+		// Raw statements have no table
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+
+		p.duration.WithLabelValues(operation, table).Observe(time.Since(value.(time.Time)).Seconds())
+
+		// A missing record is an expected outcome rather than a failure
+		if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			p.errors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
+
+// useMetrics registers the query metrics plugin and exports the connection pool statistics
+// as the go_sql_* metrics, labelled with the database name
+func useMetrics(db *gorm.DB, reg prometheus.Registerer, name string) error {
+	plugin, err := newQueryMetrics(reg)
+	if err != nil {
+		return fmt.Errorf("failed to register query metrics: %w", err)
+	}
+	if err := db.Use(plugin); err != nil {
+		return fmt.Errorf("failed to register query metrics: %w", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get underlying SQL database: %w", err)
+	}
+	if err := reg.Register(collectors.NewDBStatsCollector(sqlDB, name)); err != nil {
+		return fmt.Errorf("failed to register connection pool metrics: %w", err)
+	}
+	return nil
+}
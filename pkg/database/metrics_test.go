@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"go-grafana/internal/config"
+	"go-grafana/internal/domain/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	cfg := &config.Config{Database: config.DatabaseConfig{Driver: config.DriverSQLite, SQLitePath: ":memory:"}}
+	db, err := NewDB(cfg, zap.NewNop(), noop.NewTracerProvider(), reg)
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	defer CloseDB(db, zap.NewNop())
+
+	ctx := context.Background()
+	user := &models.User{Email: "metrics@example.com", FirstName: "Test", LastName: "User", Age: 30}
+	if err := db.WithContext(ctx).Create(user).Error; err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	var found models.User
+	if err := db.WithContext(ctx).Where(map[string]interface{}{"id": user.ID + 100}).First(&found).Error; err == nil {
+		t.Fatal("expected the lookup of a missing user to fail")
+	}
+	if err := db.WithContext(ctx).Create(&models.User{Email: user.Email, FirstName: "Dup", LastName: "User", Age: 30}).Error; err == nil {
+		t.Fatal("expected the duplicate email to be rejected")
+	}
+
+	plugin := db.Config.Plugins["metrics"].(*queryMetrics)
+	if count := testutil.CollectAndCount(plugin.duration); count != 2 {
+		t.Errorf("expected durations for create and query on users, got %d series", count)
+	}
+	// The missing record is not an error; the duplicate is
+	if got := testutil.ToFloat64(plugin.errors.WithLabelValues("create", "users")); got != 1 {
+		t.Errorf("expected 1 create error, got %v", got)
+	}
+	if count := testutil.CollectAndCount(plugin.errors); count != 1 {
+		t.Errorf("expected only the create error to be counted, got %d series", count)
+	}
+
+	for _, name := range []string{"go_sql_open_connections", "go_sql_in_use_connections", "go_sql_idle_connections", "go_sql_wait_count_total", "go_sql_wait_duration_seconds_total"} {
+		if count, err := testutil.GatherAndCount(reg, name); err != nil || count != 1 {
+			t.Errorf("expected the %s pool metric, got %d (%v)", name, count, err)
+		}
+	}
+}
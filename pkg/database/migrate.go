@@ -0,0 +1,346 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// migrationLockID is the Postgres advisory lock key held while migrating, so that replicas
+// starting at the same time apply migrations one after another instead of concurrently
+const migrationLockID int64 = 7_351_948_221_063
+
+// migrationFilePattern matches migration file names such as 0003_add_api_key_scopes.up.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// migrationNamePattern matches names accepted by CreateMigration
+var migrationNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Migration is a versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	// Unknown is set for versions recorded in the database without a migration file
+	Unknown bool
+}
+
+// Migrator applies and rolls back versioned SQL migrations, recording them in the schema_migrations table
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	logger     *zap.Logger
+}
+
+// NewMigrator creates a migrator for the migrations in fsys
+func NewMigrator(db *sql.DB, fsys fs.FS, logger *zap.Logger) (*Migrator, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		logger:     logger,
+	}, nil
+}
+
+// LoadMigrations reads the migration files in fsys, ordered by version.
+// Every version needs an up file; the down file is optional.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, migration.Name, match[2])
+		}
+
+		contents, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		if match[3] == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Up applies every pending migration in version order and returns how many were applied
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		appliedAt, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := appliedAt[migration.Version]; ok {
+				continue
+			}
+
+			m.logger.Info("Applying migration", zap.Int64("version", migration.Version), zap.String("name", migration.Name))
+			if err := m.run(ctx, conn, migration.Up, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+				return err
+			}); err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the most recently applied migrations, at most steps of them, and returns how many were rolled back
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	rolledBack := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		appliedAt, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && rolledBack < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := appliedAt[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+			}
+
+			m.logger.Info("Rolling back migration", zap.Int64("version", migration.Version), zap.String("name", migration.Name))
+			if err := m.run(ctx, conn, migration.Down, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+				return err
+			}); err != nil {
+				return fmt.Errorf("failed to roll back migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			rolledBack++
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// Status lists every known migration with the time it was applied, followed by applied versions
+// that have no migration file
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+	defer conn.Close()
+
+	appliedAt, err := m.appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if at, ok := appliedAt[migration.Version]; ok {
+			status.AppliedAt = &at
+			delete(appliedAt, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	unknown := make([]MigrationStatus, 0, len(appliedAt))
+	for version, at := range appliedAt {
+		at := at
+		unknown = append(unknown, MigrationStatus{Version: version, AppliedAt: &at, Unknown: true})
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].Version < unknown[j].Version })
+
+	return append(statuses, unknown...), nil
+}
+
+// Pending returns the known migrations that have not been applied yet. Unlike Status it does not
+// create the schema_migrations table, so it is safe to call from health checks.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+	defer conn.Close()
+
+	appliedAt, err := queryAppliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := appliedAt[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// withLock runs fn on a single connection while holding the migration advisory lock.
+// The lock is tied to the session, so it is released even if the process dies mid-migration.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+	defer conn.Close()
+
+	m.logger.Debug("Waiting for migration lock")
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Unlock even if ctx has been cancelled, otherwise the pooled connection keeps the lock
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+			m.logger.Error("Failed to release migration lock", zap.Error(err))
+		}
+	}()
+
+	return fn(conn)
+}
+
+// run executes a migration script and the bookkeeping statement in one transaction
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, script string, record func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	if err := record(tx); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+
+	return tx.Commit()
+}
+
+// appliedVersions returns the applied migration versions and when they were applied
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	return queryAppliedVersions(ctx, conn)
+}
+
+// queryAppliedVersions reads the schema_migrations table, which must exist
+func queryAppliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	appliedAt := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		appliedAt[version] = at
+	}
+	return appliedAt, rows.Err()
+}
+
+// ensureMigrationsTable creates the schema_migrations table if it does not exist yet
+func ensureMigrationsTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// CreateMigration writes empty up and down files for a new migration in dir, numbered after the
+// highest existing version, and returns their paths
+func CreateMigration(dir, name string) ([]string, error) {
+	if !migrationNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid migration name %q: use lowercase letters, digits and underscores", name)
+	}
+
+	migrations, err := LoadMigrations(os.DirFS(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	var version int64 = 1
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	paths := make([]string, 0, 2)
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%04d_%s.%s.sql", version, name, direction))
+		contents := fmt.Sprintf("-- %04d_%s (%s)\n", version, name, direction)
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write migration: %w", err)
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"go-grafana/pkg/database/migrations"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("orders by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0010_add_index.up.sql":     {Data: []byte("CREATE INDEX")},
+			"0002_add_column.up.sql":    {Data: []byte("ALTER TABLE")},
+			"0002_add_column.down.sql":  {Data: []byte("ALTER TABLE DROP")},
+			"0001_create_tables.up.sql": {Data: []byte("CREATE TABLE")},
+			"README.md":                 {Data: []byte("ignored")},
+		}
+
+		loaded, err := LoadMigrations(fsys)
+		if err != nil {
+			t.Fatalf("LoadMigrations() error = %v", err)
+		}
+		if len(loaded) != 3 {
+			t.Fatalf("expected 3 migrations, got %d", len(loaded))
+		}
+		for i, want := range []int64{1, 2, 10} {
+			if loaded[i].Version != want {
+				t.Errorf("migration %d: got version %d, want %d", i, loaded[i].Version, want)
+			}
+		}
+		if loaded[1].Name != "add_column" || loaded[1].Down != "ALTER TABLE DROP" {
+			t.Errorf("unexpected migration: %+v", loaded[1])
+		}
+	})
+
+	t.Run("missing up file", func(t *testing.T) {
+		fsys := fstest.MapFS{"0001_create_tables.down.sql": {Data: []byte("DROP TABLE")}}
+		if _, err := LoadMigrations(fsys); err == nil {
+			t.Error("expected an error for a migration without an up file")
+		}
+	})
+
+	t.Run("duplicate version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0001_create_tables.up.sql": {Data: []byte("CREATE TABLE")},
+			"0001_add_column.up.sql":    {Data: []byte("ALTER TABLE")},
+		}
+		if _, err := LoadMigrations(fsys); err == nil {
+			t.Error("expected an error for two migrations with the same version")
+		}
+	})
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	loaded, err := LoadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatalf("LoadMigrations() error = %v", err)
+	}
+	if len(loaded) == 0 {
+		t.Fatal("expected embedded migrations")
+	}
+
+	for i, migration := range loaded {
+		if migration.Version != int64(i+1) {
+			t.Errorf("expected contiguous versions, got %d at position %d", migration.Version, i)
+		}
+		if strings.TrimSpace(migration.Down) == "" {
+			t.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+		}
+	}
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "0001_create_tables.up.sql"), []byte("CREATE TABLE"), 0o644)
+
+	paths, err := CreateMigration(dir, "add_users_phone")
+	if err != nil {
+		t.Fatalf("CreateMigration() error = %v", err)
+	}
+
+	want := []string{
+		filepath.Join(dir, "0002_add_users_phone.up.sql"),
+		filepath.Join(dir, "0002_add_users_phone.down.sql"),
+	}
+	for i, path := range want {
+		if paths[i] != path {
+			t.Errorf("got path %s, want %s", paths[i], path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s to exist: %v", path, err)
+		}
+	}
+
+	if _, err := CreateMigration(dir, "Add Users Phone"); err == nil {
+		t.Error("expected an error for an invalid migration name")
+	}
+}
@@ -0,0 +1,28 @@
+-- Baseline schema. IF NOT EXISTS keeps this a no-op on databases created by GORM AutoMigrate.
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    email text NOT NULL,
+    first_name text NOT NULL,
+    last_name text NOT NULL,
+    age bigint NOT NULL,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    key text NOT NULL,
+    description text,
+    active boolean DEFAULT true,
+    expires_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key ON api_keys (key);
+CREATE INDEX IF NOT EXISTS idx_api_keys_deleted_at ON api_keys (deleted_at);
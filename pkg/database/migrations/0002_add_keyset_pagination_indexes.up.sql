@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_users_created_at_id ON users (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_api_keys_created_at_id ON api_keys (created_at, id);
@@ -0,0 +1,5 @@
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS scopes text NOT NULL DEFAULT '';
+
+-- Keys created before scopes existed had full access; keep it so that upgrading does not lock them out.
+-- This runs in the migration that adds the column because keys created afterwards may have no scopes on purpose.
+UPDATE api_keys SET scopes = 'users:read users:write api-keys:admin' WHERE scopes = '';
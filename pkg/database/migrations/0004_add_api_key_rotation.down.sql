@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_api_keys_previous_key;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS grace_until;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS previous_key;
@@ -0,0 +1,3 @@
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS previous_key text;
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS grace_until timestamptz;
+CREATE INDEX IF NOT EXISTS idx_api_keys_previous_key ON api_keys (previous_key);
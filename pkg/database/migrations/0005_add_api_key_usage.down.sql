@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_api_keys_last_used_at;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS usage_count;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS last_used_ip;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS last_used_at;
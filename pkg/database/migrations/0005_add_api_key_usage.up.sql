@@ -0,0 +1,4 @@
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS last_used_at timestamptz;
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS last_used_ip varchar(45);
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS usage_count bigint NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_api_keys_last_used_at ON api_keys (last_used_at);
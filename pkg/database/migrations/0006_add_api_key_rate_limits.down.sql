@@ -0,0 +1,2 @@
+ALTER TABLE api_keys DROP COLUMN IF EXISTS rate_limit_burst;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS rate_limit_rps;
@@ -0,0 +1,2 @@
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS rate_limit_rps decimal;
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS rate_limit_burst bigint;
@@ -2,11 +2,12 @@ package database
 
 import (
 	"fmt"
-	"time"
 
 	"go-grafana/internal/config"
 	"go-grafana/internal/domain/models"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -14,12 +15,26 @@ import (
 
 // NewPostgresDB creates a new PostgreSQL database connection
 func NewPostgresDB(cfg *config.Config, logger *zap.Logger) (*gorm.DB, error) {
+	// The password is set on the parsed config rather than in the DSN, so that errors quoting the DSN cannot leak it
+	connConfig, err := pgx.ParseConfig(cfg.GetDSN())
+	if err != nil {
+		return nil, fmt.Errorf("invalid database settings: %w", err)
+	}
+	if password := cfg.Database.Password.Reveal(); password != "" {
+		connConfig.Password = password
+	}
+
 	// Create database connection
-	db, err := gorm.Open(postgres.Open(cfg.GetDSN()), &gorm.Config{})
+	// TranslateError turns unique constraint violations into gorm.ErrDuplicatedKey
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: stdlib.OpenDB(*connConfig)}), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	if err := useStatementTimeout(db, cfg); err != nil {
+		return nil, err
+	}
+
 	// Get underlying SQL database
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -27,9 +42,10 @@ func NewPostgresDB(cfg *config.Config, logger *zap.Logger) (*gorm.DB, error) {
 	}
 
 	// Configure connection pool
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	sqlDB.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.Database.ConnMaxIdleTime)
 
 	// Test connection
 	if err := sqlDB.Ping(); err != nil {
@@ -42,15 +58,17 @@ func NewPostgresDB(cfg *config.Config, logger *zap.Logger) (*gorm.DB, error) {
 		zap.String("database", cfg.Database.DBName),
 	)
 
-	// Auto migrate models
-	if err := autoMigrate(db, logger); err != nil {
-		return nil, fmt.Errorf("failed to auto migrate database: %w", err)
+	// Auto migrate models in development; otherwise the schema is managed by versioned migrations
+	if cfg.Database.AutoMigrate {
+		if err := autoMigrate(db, logger); err != nil {
+			return nil, fmt.Errorf("failed to auto migrate database: %w", err)
+		}
 	}
 
 	return db, nil
 }
 
-// autoMigrate performs database migrations
+// autoMigrate creates and updates tables from the models with GORM AutoMigrate
 func autoMigrate(db *gorm.DB, logger *zap.Logger) error {
 	logger.Info("Starting database migration")
 
@@ -66,18 +84,3 @@ func autoMigrate(db *gorm.DB, logger *zap.Logger) error {
 	logger.Info("Database migration completed successfully")
 	return nil
 }
-
-// CloseDB closes the database connection
-func CloseDB(db *gorm.DB, logger *zap.Logger) error {
-	sqlDB, err := db.DB()
-	if err != nil {
-		return fmt.Errorf("failed to get underlying SQL database: %w", err)
-	}
-
-	if err := sqlDB.Close(); err != nil {
-		return fmt.Errorf("failed to close database connection: %w", err)
-	}
-
-	logger.Info("Database connection closed successfully")
-	return nil
-}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"go-grafana/internal/config"
+
+	"github.com/glebarez/sqlite"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// NewSQLiteDB creates a SQLite database connection for local development and tests.
+// The versioned migrations are written for Postgres, so the schema is always created with AutoMigrate.
+func NewSQLiteDB(cfg *config.Config, logger *zap.Logger) (*gorm.DB, error) {
+	// Wait for locks instead of failing with SQLITE_BUSY
+	dsn := cfg.Database.SQLitePath + "?_pragma=busy_timeout(5000)"
+
+	// Timestamps are stored as text, so keep them in UTC for cursor comparisons to work
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		TranslateError: true,
+		NowFunc:        func() time.Time { return time.Now().UTC() },
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	if err := useStatementTimeout(db, cfg); err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get underlying SQL database: %w", err)
+	}
+
+	// SQLite has a single writer, and every connection to ":memory:" opens a separate database
+	sqlDB.SetMaxOpenConns(1)
+
+	logger.Info("Database connection established successfully",
+		zap.String("driver", config.DriverSQLite),
+		zap.String("path", cfg.Database.SQLitePath),
+	)
+
+	if err := autoMigrate(db, logger); err != nil {
+		return nil, fmt.Errorf("failed to auto migrate database: %w", err)
+	}
+
+	return db, nil
+}
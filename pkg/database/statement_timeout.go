@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// statementTimeoutCancelKey stores the cancel function of the statement context between callbacks
+const statementTimeoutCancelKey = "statement_timeout:cancel"
+
+// statementTimeout is a GORM plugin that bounds every statement by a timeout on top of the
+// context it was issued with, so a slow query is cancelled even if the request is still waiting
+type statementTimeout struct {
+	timeout time.Duration
+}
+
+// newStatementTimeout creates the plugin for the given timeout
+func newStatementTimeout(timeout time.Duration) *statementTimeout {
+	return &statementTimeout{timeout: timeout}
+}
+
+// Name returns the plugin name
+func (p *statementTimeout) Name() string {
+	return "statement_timeout"
+}
+
+// Initialize wraps the create, query, update, delete and raw callbacks.
+// The timeout starts right before the statement itself rather than before the transaction GORM opens around
+// writes, because cancelling a transaction's context rolls it back.
+func (p *statementTimeout) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("statement_timeout:start", p.start),
+		callbacks.Create().After("gorm:create").Register("statement_timeout:stop", p.stop),
+		callbacks.Query().Before("gorm:query").Register("statement_timeout:start", p.start),
+		callbacks.Query().After("gorm:query").Register("statement_timeout:stop", p.stop),
+		callbacks.Update().Before("gorm:update").Register("statement_timeout:start", p.start),
+		callbacks.Update().After("gorm:update").Register("statement_timeout:stop", p.stop),
+		callbacks.Delete().Before("gorm:delete").Register("statement_timeout:start", p.start),
+		callbacks.Delete().After("gorm:delete").Register("statement_timeout:stop", p.stop),
+		callbacks.Raw().Before("gorm:raw").Register("statement_timeout:start", p.start),
+		callbacks.Raw().After("gorm:raw").Register("statement_timeout:stop", p.stop),
+	)
+}
+
+// start replaces the statement context with one that expires after the timeout
+func (p *statementTimeout) start(db *gorm.DB) {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	db.Statement.Context = ctx
+	db.InstanceSet(statementTimeoutCancelKey, cancel)
+}
+
+// stop releases the timer of the statement context once the statement has run
+func (p *statementTimeout) stop(db *gorm.DB) {
+	if cancel, ok := db.InstanceGet(statementTimeoutCancelKey); ok {
+		cancel.(context.CancelFunc)()
+	}
+}
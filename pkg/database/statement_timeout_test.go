@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type timeoutTestRow struct {
+	ID   uint
+	Name string
+}
+
+func newDryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=test"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to open dry run database: %v", err)
+	}
+	return db
+}
+
+func TestStatementTimeout(t *testing.T) {
+	db := newDryRunDB(t)
+	if err := db.Use(newStatementTimeout(time.Minute)); err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+
+	var deadlines []time.Time
+	var contexts []context.Context
+	capture := func(db *gorm.DB) {
+		deadline, ok := db.Statement.Context.Deadline()
+		if !ok {
+			t.Errorf("expected the statement context to have a deadline")
+		}
+		deadlines = append(deadlines, deadline)
+		contexts = append(contexts, db.Statement.Context)
+	}
+	callbacks := db.Callback()
+	callbacks.Query().After("statement_timeout:start").Before("gorm:query").Register("test:capture", capture)
+	callbacks.Create().After("statement_timeout:start").Before("gorm:create").Register("test:capture", capture)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	var row timeoutTestRow
+	db.WithContext(ctx).First(&row)
+	db.WithContext(ctx).Create(&timeoutTestRow{Name: "test"})
+
+	if len(deadlines) != 2 {
+		t.Fatalf("expected 2 captured statements, got %d", len(deadlines))
+	}
+	for i, deadline := range deadlines {
+		if deadline.Before(start.Add(time.Minute)) || deadline.After(time.Now().Add(time.Minute)) {
+			t.Errorf("statement %d: unexpected deadline %v", i, deadline)
+		}
+		if contexts[i].Err() == nil {
+			t.Errorf("statement %d: expected the statement context to be released after the statement", i)
+		}
+	}
+	if ctx.Err() != nil {
+		t.Error("expected the request context to be left untouched")
+	}
+}
+
+func TestStatementTimeoutHonoursRequestCancellation(t *testing.T) {
+	db := newDryRunDB(t)
+	if err := db.Use(newStatementTimeout(time.Minute)); err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+
+	var statementErr error
+	db.Callback().Query().After("statement_timeout:start").Before("gorm:query").Register("test:capture", func(db *gorm.DB) {
+		statementErr = db.Statement.Context.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var row timeoutTestRow
+	db.WithContext(ctx).First(&row)
+
+	if statementErr != context.Canceled {
+		t.Errorf("expected the statement context to be cancelled with the request, got %v", statementErr)
+	}
+}
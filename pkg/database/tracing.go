@@ -0,0 +1,95 @@
+package database
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// tracingSpanKey stores the span of a statement between callbacks
+const tracingSpanKey = "tracing:span"
+
+// tracing is a GORM plugin that records every statement as a client span, a child of the span in the
+// statement context. Only the SQL with its placeholders is recorded, never the bound values.
+type tracing struct {
+	tracer trace.Tracer
+	system attribute.KeyValue
+}
+
+// newTracing creates the plugin for the given tracer provider and database engine
+func newTracing(tracerProvider trace.TracerProvider, system attribute.KeyValue) *tracing {
+	return &tracing{
+		tracer: tracerProvider.Tracer("go-grafana/pkg/database"),
+		system: system,
+	}
+}
+
+// Name returns the plugin name
+func (p *tracing) Name() string {
+	return "tracing"
+}
+
+// Initialize wraps the create, query, update, delete, row and raw callbacks
+func (p *tracing) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("tracing:start", p.start("create")),
+		callbacks.Create().After("gorm:create").Register("tracing:end", p.end),
+		callbacks.Query().Before("gorm:query").Register("tracing:start", p.start("query")),
+		callbacks.Query().After("gorm:query").Register("tracing:end", p.end),
+		callbacks.Update().Before("gorm:update").Register("tracing:start", p.start("update")),
+		callbacks.Update().After("gorm:update").Register("tracing:end", p.end),
+		callbacks.Delete().Before("gorm:delete").Register("tracing:start", p.start("delete")),
+		callbacks.Delete().After("gorm:delete").Register("tracing:end", p.end),
+		callbacks.Row().Before("gorm:row").Register("tracing:start", p.start("row")),
+		callbacks.Row().After("gorm:row").Register("tracing:end", p.end),
+		callbacks.Raw().Before("gorm:raw").Register("tracing:start", p.start("raw")),
+		callbacks.Raw().After("gorm:raw").Register("tracing:end", p.end),
+	)
+}
+
+// start returns a callback that starts the span of a statement and makes it the statement context
+func (p *tracing) start(operation string) func(db *gorm.DB) {
+	name := "gorm." + operation
+	return func(db *gorm.DB) {
+		if db.Statement.Context == nil {
+			return
+		}
+
+		ctx, span := p.tracer.Start(db.Statement.Context, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(p.system),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(tracingSpanKey, span)
+	}
+}
+
+// end records the statement, affected rows and error on the span and ends it
+func (p *tracing) end(db *gorm.DB) {
+	value, ok := db.InstanceGet(tracingSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	attributes := []attribute.KeyValue{
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	}
+	if db.Statement.Table != "" {
+		attributes = append(attributes, semconv.DBCollectionName(db.Statement.Table))
+	}
+	span.SetAttributes(attributes...)
+
+	// A missing record is an expected outcome rather than a failure
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
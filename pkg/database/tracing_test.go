@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go-grafana/internal/config"
+	"go-grafana/internal/domain/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	cfg := &config.Config{Database: config.DatabaseConfig{Driver: config.DriverSQLite, SQLitePath: ":memory:"}}
+	db, err := NewDB(cfg, zap.NewNop(), tracerProvider, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	defer CloseDB(db, zap.NewNop())
+
+	ctx, parent := tracerProvider.Tracer("test").Start(context.Background(), "request")
+	user := &models.User{Email: "secret@example.com", FirstName: "Test", LastName: "User", Age: 30}
+	if err := db.WithContext(ctx).Create(user).Error; err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	var found models.User
+	if err := db.WithContext(ctx).Where(map[string]interface{}{"id": user.ID + 100}).First(&found).Error; err == nil {
+		t.Fatal("expected the lookup of a missing user to fail")
+	}
+	parent.End()
+
+	var statements []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() != "request" {
+			statements = append(statements, span)
+		}
+	}
+	if len(statements) != 2 || statements[0].Name() != "gorm.create" || statements[1].Name() != "gorm.query" {
+		t.Fatalf("expected a create and a query span, got %v", statements)
+	}
+
+	for _, span := range statements {
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() || span.SpanKind() != trace.SpanKindClient {
+			t.Errorf("%s: expected a client span under the request span", span.Name())
+		}
+
+		attributes := make(map[string]string)
+		for _, attribute := range span.Attributes() {
+			attributes[string(attribute.Key)] = attribute.Value.Emit()
+		}
+		if attributes["db.system"] != "sqlite" || attributes["db.collection.name"] != "users" {
+			t.Errorf("%s: unexpected attributes %v", span.Name(), attributes)
+		}
+		if query := attributes["db.query.text"]; query == "" || strings.Contains(query, "secret@example.com") {
+			t.Errorf("%s: expected the statement without its values, got %q", span.Name(), query)
+		}
+	}
+
+	// Not finding a record is not an error
+	if status := statements[1].Status(); status.Code.String() == "Error" {
+		t.Errorf("expected a missing record not to mark the span as failed, got %v", status)
+	}
+}
@@ -14,6 +14,9 @@ type PrometheusMetrics struct {
 	userUpdateTotal   prometheus.Counter
 	activeUsersGauge  prometheus.Gauge
 	userAgeHistogram  prometheus.Histogram
+	// API key validation cache metrics
+	apiKeyCacheHitsTotal   prometheus.Counter
+	apiKeyCacheMissesTotal prometheus.Counter
 }
 
 // NewPrometheusMetrics creates a new Prometheus metrics instance
@@ -45,12 +48,24 @@ func NewPrometheusMetrics(logger *zap.Logger, reg prometheus.Registerer) *Promet
 		Buckets: prometheus.LinearBuckets(0, 10, 13), // 0-120 years in 10-year buckets
 	})
 
+	apiKeyCacheHitsTotal := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "api_key_cache_hits_total",
+		Help: "Total number of API key validations answered from the cache",
+	})
+
+	apiKeyCacheMissesTotal := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "api_key_cache_misses_total",
+		Help: "Total number of API key validations that had to query the database",
+	})
+
 	// Register the metrics
 	reg.MustRegister(userCreationTotal)
 	reg.MustRegister(userDeletionTotal)
 	reg.MustRegister(userUpdateTotal)
 	reg.MustRegister(activeUsersGauge)
 	reg.MustRegister(userAgeHistogram)
+	reg.MustRegister(apiKeyCacheHitsTotal)
+	reg.MustRegister(apiKeyCacheMissesTotal)
 
 	logger.Info("Prometheus metrics initialized")
 
@@ -61,6 +76,9 @@ func NewPrometheusMetrics(logger *zap.Logger, reg prometheus.Registerer) *Promet
 		userUpdateTotal:   userUpdateTotal,
 		activeUsersGauge:  activeUsersGauge,
 		userAgeHistogram:  userAgeHistogram,
+
+		apiKeyCacheHitsTotal:   apiKeyCacheHitsTotal,
+		apiKeyCacheMissesTotal: apiKeyCacheMissesTotal,
 	}
 }
 
@@ -93,3 +111,13 @@ func (m *PrometheusMetrics) RecordUserAge(age int) {
 	m.userAgeHistogram.Observe(float64(age))
 	m.logger.Debug("User age metric recorded", zap.Int("age", age))
 }
+
+// RecordAPIKeyCacheHit increments the API key cache hit counter
+func (m *PrometheusMetrics) RecordAPIKeyCacheHit() {
+	m.apiKeyCacheHitsTotal.Inc()
+}
+
+// RecordAPIKeyCacheMiss increments the API key cache miss counter
+func (m *PrometheusMetrics) RecordAPIKeyCacheMiss() {
+	m.apiKeyCacheMissesTotal.Inc()
+}
@@ -19,11 +19,20 @@ func TestPrometheusMetrics(t *testing.T) {
 	metrics.RecordUserUpdate()
 	metrics.SetActiveUsers(42)
 	metrics.RecordUserAge(30)
+	metrics.RecordAPIKeyCacheHit()
+	metrics.RecordAPIKeyCacheHit()
+	metrics.RecordAPIKeyCacheMiss()
 
 	expected := `
 		# HELP active_users_total Total number of active users
 		# TYPE active_users_total gauge
 		active_users_total 42
+		# HELP api_key_cache_hits_total Total number of API key validations answered from the cache
+		# TYPE api_key_cache_hits_total counter
+		api_key_cache_hits_total 2
+		# HELP api_key_cache_misses_total Total number of API key validations that had to query the database
+		# TYPE api_key_cache_misses_total counter
+		api_key_cache_misses_total 1
 		# HELP user_age_distribution Distribution of user ages
 		# TYPE user_age_distribution histogram
 		user_age_distribution_bucket{le="0"} 0
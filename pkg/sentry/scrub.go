@@ -0,0 +1,94 @@
+package sentry
+
+import (
+	"encoding/json"
+
+	"go-grafana/internal/redact"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// scrubber removes secrets from Sentry events and breadcrumbs before they leave the process
+type scrubber struct {
+	redactor *redact.Redactor
+}
+
+// event scrubs the message, exceptions, request, tags, extra data, contexts and breadcrumbs of the event
+func (s scrubber) event(event *sentry.Event) {
+	event.Message = redact.String(event.Message)
+	for i := range event.Exception {
+		event.Exception[i].Value = redact.String(event.Exception[i].Value)
+	}
+	if event.Request != nil {
+		s.request(event.Request)
+	}
+	for name, value := range event.Tags {
+		event.Tags[name] = s.redactor.Value(name, value)
+	}
+	s.values(event.Extra)
+	for _, values := range event.Contexts {
+		s.values(values)
+	}
+	for _, breadcrumb := range event.Breadcrumbs {
+		s.breadcrumb(breadcrumb)
+	}
+}
+
+// request masks the sensitive headers and cookies of the request, and the secrets in its URL and body
+func (s scrubber) request(request *sentry.Request) {
+	request.URL = redact.String(request.URL)
+	request.QueryString = redact.String(request.QueryString)
+	if request.Cookies != "" {
+		request.Cookies = s.redactor.Value("cookie", request.Cookies)
+	}
+	for name, value := range request.Headers {
+		request.Headers[name] = s.redactor.Value(name, value)
+	}
+	request.Data = s.body(request.Data)
+}
+
+// body scrubs a request body, by key when it is a JSON document
+func (s scrubber) body(data string) string {
+	var document any
+	if err := json.Unmarshal([]byte(data), &document); err != nil {
+		return redact.String(data)
+	}
+	scrubbed, err := json.Marshal(s.value("", document))
+	if err != nil {
+		return redact.String(data)
+	}
+	return string(scrubbed)
+}
+
+// breadcrumb scrubs the message and data of the breadcrumb, which carry the log entries preceding an event
+func (s scrubber) breadcrumb(breadcrumb *sentry.Breadcrumb) {
+	breadcrumb.Message = redact.String(breadcrumb.Message)
+	s.values(breadcrumb.Data)
+}
+
+// values scrubs a map in place
+func (s scrubber) values(values map[string]any) {
+	for key, value := range values {
+		values[key] = s.value(key, value)
+	}
+}
+
+// value returns the value stored under key with its secrets masked, scrubbing nested maps and lists in place
+func (s scrubber) value(key string, value any) any {
+	if key != "" && s.redactor.Sensitive(key) {
+		return redact.Mask
+	}
+	switch value := value.(type) {
+	case string:
+		return redact.String(value)
+	case error:
+		return redact.String(value.Error())
+	case map[string]any:
+		s.values(value)
+	case []any:
+		for i := range value {
+			value[i] = s.value("", value[i])
+		}
+	}
+	return value
+}
@@ -0,0 +1,56 @@
+package sentry
+
+import (
+	"strings"
+	"testing"
+
+	"go-grafana/internal/redact"
+
+	"github.com/getsentry/sentry-go"
+)
+
+func TestScrubber_Event(t *testing.T) {
+	s := scrubber{redactor: redact.New([]string{"x-api-key", "authorization", "cookie", "password"})}
+	event := &sentry.Event{
+		Message:   "rejected sk-0123456789abcdef",
+		Exception: []sentry.Exception{{Value: "dial postgres://app:hunter2@db/app"}},
+		Request: &sentry.Request{
+			URL:         "http://localhost:8080/api/v1/users",
+			QueryString: "key=sk-0123456789abcdef",
+			Cookies:     "session=abc",
+			Headers:     map[string]string{"X-Api-Key": "sk-0123456789abcdef", "Content-Type": "application/json"},
+			Data:        `{"name":"ops","password":"hunter2","nested":[{"key":"sk-0123456789abcdef"}]}`,
+		},
+		Extra: map[string]any{"password": "hunter2", "count": 3},
+		Breadcrumbs: []*sentry.Breadcrumb{{
+			Message: "HTTP Request",
+			Data:    map[string]any{"X-API-Key": "sk-0123456789abcdef", "path": "/api/v1/users"},
+		}},
+	}
+
+	s.event(event)
+
+	for name, got := range map[string]string{
+		"message":    event.Message,
+		"exception":  event.Exception[0].Value,
+		"query":      event.Request.QueryString,
+		"cookies":    event.Request.Cookies,
+		"header":     event.Request.Headers["X-Api-Key"],
+		"body":       event.Request.Data,
+		"extra":      event.Extra["password"].(string),
+		"breadcrumb": event.Breadcrumbs[0].Data["X-API-Key"].(string),
+	} {
+		if strings.Contains(got, "0123456789abcdef") || strings.Contains(got, "hunter2") || strings.Contains(got, "abc") {
+			t.Errorf("%s: expected the secret to be masked, got %q", name, got)
+		}
+	}
+	if event.Request.Headers["Content-Type"] != "application/json" || event.Breadcrumbs[0].Data["path"] != "/api/v1/users" {
+		t.Error("expected other values to be kept")
+	}
+	if !strings.Contains(event.Request.Data, `"name":"ops"`) {
+		t.Errorf("expected the body to stay JSON, got %s", event.Request.Data)
+	}
+	if event.Extra["count"] != 3 {
+		t.Errorf("expected non-string values to be kept, got %v", event.Extra["count"])
+	}
+}
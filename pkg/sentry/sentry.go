@@ -2,13 +2,29 @@ package sentry
 
 import (
 	"log"
+	"math"
+	"math/rand/v2"
+	"sync/atomic"
 	"time"
 
 	"go-grafana/internal/config"
+	"go-grafana/internal/redact"
 
 	"github.com/getsentry/sentry-go"
 )
 
+// sampleRate holds the bits of the fraction of error events sent, so that it can change at runtime
+var sampleRate atomic.Uint64
+
+func init() {
+	SetSampleRate(1)
+}
+
+// SetSampleRate changes the fraction of error events sent to Sentry
+func SetSampleRate(rate float64) {
+	sampleRate.Store(math.Float64bits(rate))
+}
+
 // InitSentry initializes the Sentry client
 func InitSentry(cfg *config.Config) {
 	if cfg.Sentry.DSN == "" {
@@ -16,14 +32,31 @@ func InitSentry(cfg *config.Config) {
 		return
 	}
 
+	SetSampleRate(cfg.Sentry.SampleRate)
+	scrubber := scrubber{redactor: redact.New(cfg.Logging.RedactFields)}
 	err := sentry.Init(sentry.ClientOptions{
-		Dsn: cfg.Sentry.DSN,
+		Dsn: cfg.Sentry.DSN.Reveal(),
 		// Set tracesSampleRate to 1.0 to capture 100%
 		// of transactions for performance monitoring.
 		// We recommend adjusting this value in production.
 		TracesSampleRate: 0.1,
 		EnableTracing:    true,
 		AttachStacktrace: true,
+		// Error events are sampled in BeforeSend rather than through SampleRate, which is fixed at init
+		BeforeSend: func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			if event = sampleEvent(event, hint); event != nil {
+				scrubber.event(event)
+			}
+			return event
+		},
+		BeforeSendTransaction: func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+			scrubber.event(event)
+			return event
+		},
+		BeforeBreadcrumb: func(breadcrumb *sentry.Breadcrumb, _ *sentry.BreadcrumbHint) *sentry.Breadcrumb {
+			scrubber.breadcrumb(breadcrumb)
+			return breadcrumb
+		},
 	})
 	if err != nil {
 		log.Fatalf("sentry.Init: %s", err)
@@ -34,3 +67,11 @@ func InitSentry(cfg *config.Config) {
 
 	log.Println("Sentry initialized successfully")
 }
+
+// sampleEvent drops error events at random to keep the current sample rate
+func sampleEvent(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+	if rand.Float64() >= math.Float64frombits(sampleRate.Load()) {
+		return nil
+	}
+	return event
+}
@@ -0,0 +1,117 @@
+// Package tracing sets up OpenTelemetry tracing and W3C trace context propagation
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go-grafana/internal/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// NewTracerProvider creates the tracer provider for the configured exporter and installs it, along with
+// the W3C trace context and baggage propagators, as the global default.
+// Buffered spans are flushed when the application stops.
+func NewTracerProvider(lifecycle fx.Lifecycle, cfg *config.Config, logger *zap.Logger) (trace.TracerProvider, error) {
+	// Propagate incoming trace context even when spans are not exported
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	exporter, closeExporter, err := newExporter(cfg.Tracing)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		logger.Info("Tracing disabled")
+		tracerProvider := noop.NewTracerProvider()
+		otel.SetTracerProvider(tracerProvider)
+		return tracerProvider, nil
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg.Tracing.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+	)
+	otel.SetTracerProvider(tracerProvider)
+
+	lifecycle.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			if err := tracerProvider.Shutdown(ctx); err != nil {
+				return fmt.Errorf("failed to flush spans: %w", err)
+			}
+			return closeExporter()
+		},
+	})
+
+	logger.Info("Tracing enabled",
+		zap.String("exporter", cfg.Tracing.Exporter),
+		zap.Float64("sample_ratio", cfg.Tracing.SampleRatio),
+	)
+	return tracerProvider, nil
+}
+
+// newExporter creates the span exporter selected by cfg and a function that releases its output.
+// It returns a nil exporter when tracing is disabled.
+func newExporter(cfg config.TracingConfig) (sdktrace.SpanExporter, func() error, error) {
+	noClose := func() error { return nil }
+
+	switch cfg.Exporter {
+	case "", config.TraceExporterNone:
+		return nil, noClose, nil
+	case config.TraceExporterOTLP:
+		exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+		return exporter, noClose, nil
+	case config.TraceExporterStdout:
+		var out io.Writer = os.Stdout
+		closeOut := noClose
+		if cfg.File != "" {
+			file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to open trace file: %w", err)
+			}
+			out, closeOut = file, file.Close
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(out))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create stdout trace exporter: %w", err)
+		}
+		return exporter, closeOut, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported trace exporter %q", cfg.Exporter)
+	}
+}
+
+// LogFields returns the trace and span IDs of the span in ctx as zap fields, or nothing if there is no span
+func LogFields(ctx context.Context) []zap.Field {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return nil
+	}
+	return []zap.Field{
+		zap.String("trace_id", spanContext.TraceID().String()),
+		zap.String("span_id", spanContext.SpanID().String()),
+	}
+}
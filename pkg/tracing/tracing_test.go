@@ -0,0 +1,82 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-grafana/internal/config"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/fx/fxtest"
+	"go.uber.org/zap"
+)
+
+func TestNewTracerProvider(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		cfg := &config.Config{Tracing: config.TracingConfig{Exporter: config.TraceExporterNone}}
+		tracerProvider, err := NewTracerProvider(fxtest.NewLifecycle(t), cfg, zap.NewNop())
+		if err != nil {
+			t.Fatalf("NewTracerProvider() error = %v", err)
+		}
+		_, span := tracerProvider.Tracer("test").Start(context.Background(), "span")
+		if span.IsRecording() {
+			t.Error("expected spans not to be recorded when tracing is disabled")
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "traces.json")
+		cfg := &config.Config{Tracing: config.TracingConfig{
+			Exporter:    config.TraceExporterStdout,
+			ServiceName: "test-service",
+			File:        path,
+			SampleRatio: 1,
+		}}
+		lifecycle := fxtest.NewLifecycle(t)
+		tracerProvider, err := NewTracerProvider(lifecycle, cfg, zap.NewNop())
+		if err != nil {
+			t.Fatalf("NewTracerProvider() error = %v", err)
+		}
+
+		_, span := tracerProvider.Tracer("test").Start(context.Background(), "exported-span")
+		span.End()
+		// Stopping flushes the batched spans
+		lifecycle.RequireStart().RequireStop()
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read trace file: %v", err)
+		}
+		if !strings.Contains(string(data), "exported-span") || !strings.Contains(string(data), "test-service") {
+			t.Errorf("expected the span and service name in the trace file, got %s", data)
+		}
+	})
+
+	t.Run("unsupported exporter", func(t *testing.T) {
+		cfg := &config.Config{Tracing: config.TracingConfig{Exporter: "zipkin"}}
+		if _, err := NewTracerProvider(fxtest.NewLifecycle(t), cfg, zap.NewNop()); err == nil {
+			t.Error("expected an error for an unsupported exporter")
+		}
+	})
+}
+
+func TestLogFields(t *testing.T) {
+	if fields := LogFields(context.Background()); len(fields) != 0 {
+		t.Errorf("expected no fields without a span, got %v", fields)
+	}
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	fields := LogFields(ctx)
+	if len(fields) != 2 || fields[0].String != traceID.String() || fields[1].String != spanID.String() {
+		t.Errorf("unexpected fields %v", fields)
+	}
+}